@@ -0,0 +1,301 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/example/protobuf-compat/internal/wire"
+	"github.com/example/protobuf-compat/pkg/wireanalyze"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func init() {
+	register(&command{
+		name:    "analyze",
+		summary: "show the wire-format structure of a payload without a schema",
+		run:     runAnalyze,
+		formats: []string{"json", "ndjson", "yaml", "protoscope"},
+	})
+}
+
+func runAnalyze(args []string) error {
+	fs := newFlagSet("analyze")
+	stream := fs.String("stream", "", "analyze a raw binary file incrementally (\"-\" for stdin) instead of a payload argument")
+	maxValue := fs.Int("max-value", wire.DefaultMaxValue, "with -stream, largest length-delimited value to load")
+	loadSets := addDescriptorSetFlag(fs)
+	batch := fs.String("batch", "", "analyze every line of a file (\"-\" for stdin) as a separate encoded payload")
+	maxDepth := fs.Int("max-depth", wire.DefaultMaxDepth, "give up on payloads nesting messages or groups deeper than this")
+	redact := fs.Bool("redact", false, "replace string and bytes contents with same-length placeholders before printing, for sharing output")
+	protoscope := fs.Bool("protoscope", outputFormat == "protoscope", "print the payload in protoscope text syntax, which protoscope can assemble back into the same bytes")
+	sizes := fs.Bool("sizes", false, "print a breakdown of the payload bytes by field path instead of its structure")
+	grpc := fs.Bool("grpc", false, "treat payloads as gRPC messages with 5-byte frame headers (detected automatically otherwise)")
+	addFixedFlag(fs)
+	pf := addPayloadFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := loadSets(); err != nil {
+		return err
+	}
+	a := &analysis{opts: wireanalyze.Options{MaxDepth: *maxDepth, GRPC: *grpc, Redact: *redact}}
+	a.printer = func(fields []wire.Field, size int) { printFields(fields, 0) }
+	ndjson := outputFormat == "ndjson"
+	structured := outputFormat == "json" || outputFormat == "yaml" || ndjson
+	switch {
+	case *sizes && *protoscope:
+		return withExitCode(exitUsage, errors.New("-sizes and -protoscope are exclusive"))
+	case structured && (*sizes || *stream != ""):
+		return withExitCode(exitUsage, fmt.Errorf("-format %s cannot be combined with -sizes or -stream", outputFormat))
+	case structured && !ndjson && *batch != "":
+		return withExitCode(exitUsage, fmt.Errorf("-format %s cannot be combined with -batch; -format ndjson can", outputFormat))
+	case structured:
+		a.structured = true
+	case *sizes:
+		a.printer = printSizes
+	case *protoscope:
+		a.printer = func(fields []wire.Field, size int) { fmt.Print(wire.Protoscope(fields)) }
+		a.comment = "# "
+	}
+	if *stream != "" {
+		if *redact || *grpc {
+			return withExitCode(exitUsage, errors.New("-redact and -grpc cannot be combined with -stream"))
+		}
+		return parseFailure(analyzeStream(*stream, *maxValue, *maxDepth))
+	}
+	files, err := pf.files(fs.Args())
+	if err != nil {
+		return err
+	}
+	switch {
+	case *batch != "" && files != nil:
+		return withExitCode(exitUsage, errors.New("-batch and payload files are exclusive"))
+	case *batch != "" && ndjson:
+		each := func(fn payloadFunc) error { return readCorpusLines(rootContext, *batch, pf.decode, fn) }
+		return parseFailure(streamBatch(each, "parsed cleanly", a.batchValue))
+	case *batch != "":
+		each := func(fn payloadFunc) error { return readCorpusLines(rootContext, *batch, pf.decode, fn) }
+		return parseFailure(runBatch(each, "parsed cleanly", a.batchPayload))
+	case files != nil && ndjson:
+		each := func(fn payloadFunc) error { return pf.readFiles(rootContext, files, fn) }
+		return parseFailure(streamBatch(each, "parsed cleanly", a.batchValue))
+	case files != nil && structured:
+		return withExitCode(exitUsage, fmt.Errorf("-format %s cannot be combined with several payload files; -format ndjson can", outputFormat))
+	case files != nil:
+		each := func(fn payloadFunc) error { return pf.readFiles(rootContext, files, fn) }
+		return parseFailure(runBatch(each, "parsed cleanly", a.batchPayload))
+	case fs.NArg() > 1:
+		return usageError("protocompat analyze [payload | FILE... | 'GLOB'] | -stream FILE | -batch FILE")
+	}
+	data, err := pf.read(fs.Args())
+	if err != nil {
+		return err
+	}
+	if !*protoscope && !structured {
+		fmt.Printf("Total length: %d bytes\n\n", len(data))
+	}
+	return parseFailure(a.payload(data))
+}
+
+// analysis holds the settings for analyzing payloads.
+type analysis struct {
+	opts    wireanalyze.Options
+	printer func(fields []wire.Field, size int)
+	comment string // prefix for lines that are not printer output
+	// structured analyses print a JSON or YAML document per message, in
+	// place of printer, and nothing may come between them.
+	structured bool
+}
+
+// payload prints the analysis of data. gRPC-framed payloads are split into
+// their messages, each analyzed on its own.
+func (a *analysis) payload(data []byte) error {
+	if !a.opts.GRPC && !wire.IsGRPCFramed(data) {
+		return a.message(data)
+	}
+	frames, err := wire.SplitGRPC(data)
+	if err != nil {
+		return err
+	}
+	for i, fr := range frames {
+		if a.structured {
+			if err := a.message(fr.Data); err != nil {
+				return fmt.Errorf("gRPC frame at byte %d: %w", fr.Offset, err)
+			}
+			continue
+		}
+		if i > 0 {
+			fmt.Println()
+		}
+		compressed := ""
+		if fr.Compressed {
+			compressed = ", gzip-compressed"
+		}
+		fmt.Printf("%sgRPC frame at byte %d: %d-byte message%s\n", a.comment, fr.Offset, len(fr.Data), compressed)
+		if err := a.message(fr.Data); err != nil {
+			return fmt.Errorf("gRPC frame at byte %d: %w", fr.Offset, err)
+		}
+	}
+	return nil
+}
+
+// batchPayload is payload for runBatch, classing failures as parse
+// failures.
+func (a *analysis) batchPayload(data []byte) error {
+	return parseFailure(a.payload(data))
+}
+
+// batchValue is batchPayload for streamBatch, returning the fields of the
+// payload, or of each of its gRPC frames, rather than printing them.
+func (a *analysis) batchValue(data []byte) (any, error) {
+	r, err := a.opts.Analyze(data)
+	if r.Frames == nil {
+		return r.Fields, parseFailure(err)
+	}
+	var out [][]wireanalyze.Field
+	for _, fr := range r.Frames {
+		out = append(out, fr.Fields)
+	}
+	return out, parseFailure(err)
+}
+
+func (a *analysis) message(data []byte) error {
+	if a.structured {
+		fields, err := a.opts.Fields(data)
+		printStructured(outputFormat, fields)
+		return err
+	}
+	fields, err := a.fields(data)
+	a.printer(fields, len(data))
+	return err
+}
+
+// fields parses data, redacted if asked to. Fields parsed before an error
+// are returned with it.
+func (a *analysis) fields(data []byte) ([]wire.Field, error) {
+	if a.opts.Redact {
+		data = wire.Redact(data)
+	}
+	return wire.ParseOptions{MaxDepth: a.opts.MaxDepth}.Parse(data)
+}
+
+// printSizes prints the bytes taken by each field path, largest first.
+func printSizes(fields []wire.Field, total int) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "FIELD\tCOUNT\tBYTES\tOVERHEAD\tSHARE\t")
+	for _, s := range wire.Sizes(fields) {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%.1f%%\t\n", wire.FormatPath(s.Path),
+			s.Occurrences, s.Bytes, s.Overhead, 100*float64(s.Bytes)/float64(total))
+	}
+	tw.Flush()
+}
+
+// analyzeStream prints top-level fields as they are read from path, keeping
+// memory bounded by maxValue regardless of the file size.
+func analyzeStream(path string, maxValue, maxDepth int) error {
+	r := io.Reader(os.Stdin)
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var count, size int
+	err := wire.ParseOptions{MaxDepth: maxDepth}.ParseStream(rootContext, r, maxValue, func(f wire.Field) error {
+		printFields([]wire.Field{f}, 0)
+		count++
+		size = f.End
+		return nil
+	})
+	fmt.Printf("\n%d top-level fields, %d bytes\n", count, size)
+	return err
+}
+
+// printFields prints one line per field, indenting nested messages.
+// Repeated fields that look like map entries are printed once, as a map.
+func printFields(fields []wire.Field, depth int) {
+	occurrences := make(map[protowire.Number][]wire.Field)
+	for _, f := range fields {
+		occurrences[f.Number] = append(occurrences[f.Number], f)
+	}
+	printed := make(map[protowire.Number]bool)
+	indent := strings.Repeat("  ", depth)
+	for _, f := range fields {
+		if entries, ok := wire.DetectMap(occurrences[f.Number]); ok {
+			if !printed[f.Number] {
+				printed[f.Number] = true
+				fmt.Printf("%s[%d] field %d (map, %d entries):\n", indent, f.Offset, f.Number, len(entries))
+				for _, e := range entries {
+					printMapEntry(e, depth+1)
+				}
+			}
+			continue
+		}
+		printField(fmt.Sprintf("%s[%d] field %d (%s): ", indent, f.Offset, f.Number, wire.TypeName(f.Type)), f, depth)
+	}
+}
+
+func printMapEntry(e wire.MapEntry, depth int) {
+	key := "(default key)"
+	if e.Key != nil {
+		key = describeValue(*e.Key)
+	}
+	prefix := fmt.Sprintf("%s%s => ", strings.Repeat("  ", depth), key)
+	if e.Value == nil {
+		fmt.Println(prefix + "(default value)")
+		return
+	}
+	printField(prefix, *e.Value, depth)
+}
+
+// printField prints prefix followed by f's value and any annotations, then
+// f's nested fields one level deeper than depth.
+func printField(prefix string, f wire.Field, depth int) {
+	fmt.Print(prefix + describeValue(f))
+	if wk, ok := wire.DetectWellKnown(f); ok {
+		// The canonical rendering says all there is to say about the
+		// nested fields.
+		fmt.Printf("  ⟶ %s %s\n", wk.Type, wk.Text)
+		return
+	}
+	if guess, ok := wire.FieldTime(f); ok {
+		fmt.Printf("  ⏱ %s", guess.Format())
+	}
+	if guess, ok := wire.GuessBytes(f); ok {
+		fmt.Printf("  ≈ %s", guess)
+	}
+	fmt.Println()
+	if len(f.Nested) > 0 && (f.Type == protowire.StartGroupType || !wire.IsText(f.Value)) {
+		printFields(f.Nested, depth+1)
+	}
+}
+
+// describeValue renders the most likely interpretation of a field's value.
+func describeValue(f wire.Field) string {
+	switch f.Type {
+	case protowire.VarintType:
+		return fmt.Sprint(f.Varint)
+	case protowire.Fixed32Type, protowire.Fixed64Type:
+		return formatFixed(f)
+	case protowire.StartGroupType:
+		return fmt.Sprintf("group, %d fields", len(f.Nested))
+	}
+	switch {
+	case f.Value == nil && f.End > f.ValueOffset:
+		return fmt.Sprintf("%d bytes, not loaded", f.End-f.ValueOffset)
+	case len(f.Value) == 0:
+		return `""`
+	case wire.IsText(f.Value):
+		return fmt.Sprintf("%q", f.Value)
+	case len(f.Nested) > 0:
+		return fmt.Sprintf("message, %d bytes", len(f.Value))
+	default:
+		return fmt.Sprintf("%d bytes %X", len(f.Value), f.Value)
+	}
+}
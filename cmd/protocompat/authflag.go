@@ -0,0 +1,21 @@
+package main
+
+import (
+	"flag"
+	"strings"
+
+	"github.com/example/protobuf-compat/internal/auth"
+)
+
+// addAuthFlag registers the -auth flag of commands that talk to remote
+// services and returns a function building the selected provider.
+func addAuthFlag(fs *flag.FlagSet) func() (auth.Provider, error) {
+	spec := fs.String("auth", "", "request authentication: "+strings.Join(auth.Names(), ", ")+
+		" (e.g. bearer-file:/path, header:X-Consul-Token=T, exec:/path/to/helper)")
+	return func() (auth.Provider, error) {
+		if *spec == "" {
+			return nil, nil
+		}
+		return auth.New(*spec)
+	}
+}
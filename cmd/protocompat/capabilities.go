@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"strings"
+
+	"github.com/example/protobuf-compat/internal/auth"
+	"github.com/example/protobuf-compat/internal/kv"
+	"github.com/example/protobuf-compat/internal/payload"
+	"github.com/example/protobuf-compat/internal/schema"
+	"github.com/example/protobuf-compat/pkg/compat"
+)
+
+func init() {
+	register(&command{
+		name:    "capabilities",
+		summary: "describe what this build supports (use -format json for tooling)",
+		run:     runCapabilities,
+		formats: []string{"json", "yaml"},
+	})
+}
+
+// version is set at link time: -ldflags "-X main.version=v1.2.3".
+var version = "dev"
+
+// capabilitiesVersion is bumped whenever the JSON layout below changes
+// incompatibly, so tooling can tell layouts apart.
+const capabilitiesVersion = 1
+
+// analysisFeatures names the schema-less analysis features of this build.
+var analysisFeatures = []string{
+	"timestamp-heuristics",
+	"well-known-types",
+	"proto-inference",
+	"descriptor-validation",
+	"streaming",
+	"length-delimited-streams",
+	"multi-schema-identify",
+	"corpus-statistics",
+	"size-profile",
+	"any-resolution",
+	"protoscope-output",
+	"depth-limit",
+	"grpc-framing",
+	"entropy-analysis",
+}
+
+type capabilities struct {
+	FormatVersion int                 `json:"format_version"`
+	Version       string              `json:"version"`
+	GoVersion     string              `json:"go_version"`
+	Revision      string              `json:"revision,omitempty"`
+	Commands      []commandInfo       `json:"commands"`
+	Inputs        []string            `json:"input_encodings"`
+	Adapters      map[string][]string `json:"adapters"`
+	Auth          []string            `json:"auth_providers"`
+	Schemas       []schemaInfo        `json:"schemas"`
+	Features      []string            `json:"analysis_features"`
+	CompatRules   []string            `json:"compat_rules"`
+	ExitCodes     map[string]int      `json:"exit_codes"`
+	Plugins       []pluginInfo        `json:"plugins"`
+}
+
+type pluginInfo struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+type commandInfo struct {
+	Name    string   `json:"name"`
+	Summary string   `json:"summary"`
+	Formats []string `json:"output_formats"`
+}
+
+type schemaInfo struct {
+	Name    string `json:"name"`
+	Message string `json:"message"`
+}
+
+func collectCapabilities() capabilities {
+	c := capabilities{
+		FormatVersion: capabilitiesVersion,
+		Version:       version,
+		Adapters: map[string][]string{
+			"kv":       kv.Backends,
+			"k8s":      {"configmap", "secret", "custom-resource", "pod-logs"},
+			"file":     {"raw", "length-delimited"},
+			"grpc":     {"reflection"},
+			"registry": {"confluent"},
+			"schema":   {"linked", "descriptor-set", "proto", "reflection"},
+		},
+		Auth:        auth.Names(),
+		Features:    analysisFeatures,
+		CompatRules: compat.Rules,
+		ExitCodes:   make(map[string]int),
+	}
+	for code, name := range exitCodeNames {
+		c.ExitCodes[name] = code
+	}
+	c.ExitCodes["interrupted"] = exitInterrupted
+	if info, ok := debug.ReadBuildInfo(); ok {
+		c.GoVersion = info.GoVersion
+		for _, s := range info.Settings {
+			if s.Key == "vcs.revision" {
+				c.Revision = s.Value
+			}
+		}
+	}
+	for _, name := range sortedCommandNames() {
+		c.Commands = append(c.Commands, commandInfo{
+			Name:    name,
+			Summary: commands[name].summary,
+			Formats: append([]string{"text"}, commands[name].formats...),
+		})
+	}
+	found := plugins()
+	c.Plugins = []pluginInfo{}
+	for _, name := range sortedKeys(found) {
+		c.Plugins = append(c.Plugins, pluginInfo{Name: name, Path: found[name]})
+	}
+	for _, e := range payload.Encodings {
+		c.Inputs = append(c.Inputs, string(e))
+	}
+	for _, name := range schema.Aliases() {
+		mt, err := schema.Lookup(name)
+		if err != nil {
+			continue
+		}
+		c.Schemas = append(c.Schemas, schemaInfo{Name: name, Message: string(mt.Descriptor().FullName())})
+	}
+	return c
+}
+
+func runCapabilities(args []string) error {
+	fs := newFlagSet("capabilities")
+	asJSON := fs.Bool("json", false, "print machine-readable JSON, like the global -format json")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	c := collectCapabilities()
+	if *asJSON {
+		return printJSON(c)
+	}
+	if outputFormat != "text" {
+		return printStructured(outputFormat, c)
+	}
+
+	fmt.Printf("protocompat %s (%s)\n\n", c.Version, c.GoVersion)
+	fmt.Println("Commands:")
+	for _, cmd := range c.Commands {
+		fmt.Printf("  %-12s %s\n", cmd.Name, cmd.Summary)
+	}
+	fmt.Printf("\nInput encodings: %s\n", strings.Join(c.Inputs, ", "))
+	adapters := make([]string, 0, len(c.Adapters))
+	for name := range c.Adapters {
+		adapters = append(adapters, name)
+	}
+	sort.Strings(adapters)
+	fmt.Println("Adapters:")
+	for _, name := range adapters {
+		fmt.Printf("  %-5s %s\n", name, strings.Join(c.Adapters[name], ", "))
+	}
+	fmt.Printf("Auth providers: %s\n", strings.Join(c.Auth, ", "))
+	fmt.Println("Schemas:")
+	for _, s := range c.Schemas {
+		fmt.Printf("  %-4s %s\n", s.Name, s.Message)
+	}
+	fmt.Printf("Analysis features: %s\n", strings.Join(c.Features, ", "))
+	fmt.Printf("Compat rules: %s\n", strings.Join(c.CompatRules, ", "))
+	codes := make([]string, len(exitCodeNames))
+	for code, name := range exitCodeNames {
+		codes[code] = fmt.Sprintf("%d %s", code, name)
+	}
+	codes = append(codes, fmt.Sprintf("%d interrupted", exitInterrupted))
+	fmt.Printf("Exit codes: %s\n", strings.Join(codes, ", "))
+	if len(c.Plugins) > 0 {
+		fmt.Println("Plugins:")
+		for _, p := range c.Plugins {
+			fmt.Printf("  %-12s %s\n", p.Name, p.Path)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,495 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/example/protobuf-compat/internal/source"
+	"github.com/example/protobuf-compat/pkg/compat"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func init() {
+	register(&command{
+		name:    "compat",
+		summary: "check schema versions for breaking changes (compat check OLD NEW, compat fuzz OLD NEW, compat matrix V1 V2 ..., compat simulate A B, compat record|replay)",
+		run:     runCompat,
+		formats: []string{"json", "yaml"},
+	})
+}
+
+// compatCommands are the subcommands of compat.
+var compatCommands = map[string]func(args []string) error{
+	"check":    runCompatCheck,
+	"fuzz":     runCompatFuzz,
+	"matrix":   runCompatMatrix,
+	"record":   runCompatRecord,
+	"replay":   runCompatReplay,
+	"simulate": runCompatSimulate,
+}
+
+func runCompat(args []string) error {
+	names := make([]string, 0, len(compatCommands))
+	for name := range compatCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	usage := usageError(fmt.Sprintf("protocompat compat {%s} [flags] ...", strings.Join(names, "|")))
+	if len(args) == 0 {
+		return usage
+	}
+	run, ok := compatCommands[args[0]]
+	if !ok {
+		return usage
+	}
+	if outputFormat != "text" && args[0] != "check" && args[0] != "simulate" {
+		return withExitCode(exitUsage, fmt.Errorf("-format %s is only supported by compat check and compat simulate", outputFormat))
+	}
+	return run(args[1:])
+}
+
+// severityMarks prefixes findings in text output.
+var severityMarks = map[compat.Severity]string{
+	compat.Info:     "ℹ️ ",
+	compat.Warning:  "⚠️ ",
+	compat.Breaking: "❌",
+}
+
+// compatFormats are the report formats of compat check.
+var compatFormats = []string{"text", "json", "yaml", "sarif", "markdown", "html"}
+
+// compatJSON is the layout of compat check -format json.
+type compatJSON struct {
+	Old      string           `json:"old"`
+	New      string           `json:"new"`
+	Policy   string           `json:"policy"`
+	Findings []compat.Finding `json:"findings"`
+	Breaking int              `json:"breaking"`
+	Warnings int              `json:"warnings"`
+	Info     int              `json:"info"`
+	// Suppressed counts the findings acknowledged by a baseline file.
+	Suppressed int `json:"suppressed,omitempty"`
+	// Bump is the semantic version bump the findings call for.
+	Bump string `json:"bump"`
+}
+
+func runCompatCheck(args []string) error {
+	fs := newFlagSet("compat check")
+	format := fs.String("format", outputFormat, "report format: "+strings.Join(compatFormats, ", "))
+	failOn := fs.String("fail-on", "breaking", "exit non-zero on findings of this severity or above: info, warning, breaking or none")
+	policyName := fs.String("policy", "FULL", "readers that must keep working: "+strings.Join(compat.PolicyNames, ", "))
+	category := fs.String("category", "", "report only rules of this category: wire, json for changes that break only protojson, api for services and field options such as deprecation, or custom (default: all)")
+	renamesFile := fs.String("renames", "", "file of \"OLD NEW\" lines naming messages and enums renamed on purpose")
+	baselineFile := fs.String("baseline", "", "file of acknowledged \"RULE PATH\" findings to suppress")
+	writeBaseline := fs.String("write-baseline", "", "write the findings to this baseline file instead of reporting them")
+	docs := fs.Bool("docs", false, "also report comments and annotations of messages and fields that were removed ("+compat.DocumentationRemoved+"); comments need descriptor sets built with --include_source_info")
+	vf := addVersionFlags(fs)
+	registryURL := fs.String("registry", "", "URL of a Confluent-compatible schema registry; check NEW against the versions of -subject under the subject's compatibility mode, unless -policy is set")
+	subject := fs.String("subject", "", "schema registry subject holding the earlier versions, e.g. orders-value")
+	semver := fs.String("semver", "", "current and proposed schema versions as CURRENT..NEXT, e.g. 1.4.2..1.5.0; fail if NEXT is too small a bump for the changes")
+	override := addSeverityFlag(fs)
+	wf := addWatchFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if wf.enabled() {
+		return wf.run(fs, func() error { return runCompatCheck(args) })
+	}
+	if *docs && !slices.Contains(compat.Rules, compat.DocumentationRemoved) {
+		compat.Register(compat.DocumentationRemoved, compat.DocumentationRule)
+	}
+	var current, next compat.Version
+	if *semver != "" {
+		from, to, ok := strings.Cut(*semver, "..")
+		if !ok {
+			return withExitCode(exitUsage, fmt.Errorf("bad -semver %q: want CURRENT..NEXT", *semver))
+		}
+		var err error
+		if current, err = compat.ParseVersion(from); err != nil {
+			return err
+		}
+		if next, err = compat.ParseVersion(to); err != nil {
+			return err
+		}
+	}
+	var renames compat.Renames
+	if *renamesFile != "" {
+		var err error
+		if renames, err = compat.ReadRenames(*renamesFile); err != nil {
+			return err
+		}
+	}
+	var baseline compat.Baseline
+	if *baselineFile != "" {
+		var err error
+		if baseline, err = compat.ReadBaseline(*baselineFile); err != nil {
+			return err
+		}
+	}
+	switch compat.Category(*category) {
+	case "", compat.Wire, compat.JSONOnly, compat.API, compat.Custom:
+	default:
+		return withExitCode(exitUsage, fmt.Errorf("unknown category %q (want %s, %s, %s or %s)", *category, compat.Wire, compat.JSONOnly, compat.API, compat.Custom))
+	}
+	threshold := compat.Severity(-1)
+	if *failOn != "none" {
+		var err error
+		if threshold, err = compat.ParseSeverity(*failOn); err != nil {
+			return err
+		}
+	}
+	policy, err := compat.ParsePolicy(*policyName)
+	if err != nil {
+		return err
+	}
+	var names []string
+	var versions [][]protoreflect.FileDescriptor
+	if *registryURL != "" {
+		if *subject == "" || fs.NArg() != 1 {
+			return usageError("protocompat compat check -registry URL -subject SUBJECT [flags] NEW")
+		}
+		var mode string
+		if versions, names, mode, err = vf.registryHistory(*registryURL, *subject); err != nil {
+			return err
+		}
+		if len(versions) == 0 {
+			fmt.Printf("✅ subject %s has no registered versions to check against\n", *subject)
+			return nil
+		}
+		policySet := false
+		fs.Visit(func(f *flag.Flag) { policySet = policySet || f.Name == "policy" })
+		if !policySet {
+			if policy, err = compat.ParsePolicy(mode); err != nil {
+				return fmt.Errorf("subject %s: %w", *subject, err)
+			}
+		}
+	} else if fs.NArg() < 2 {
+		return usageError("protocompat compat check [flags] OLD... NEW (schema names, descriptor sets, .proto files or grpc:// servers, oldest first)")
+	}
+	for _, name := range fs.Args() {
+		files, err := vf.load(name)
+		if err != nil {
+			return err
+		}
+		names = append(names, name)
+		versions = append(versions, files)
+	}
+
+	// Earlier versions only matter to transitive policies; the others check
+	// NEW against the version before it.
+	newName := names[len(names)-1]
+	against := policy.Against(len(names))
+	reports := compat.CheckHistory(versions, policy, renames)
+	all := &compat.Report{}
+	suppressed := make([]int, len(reports))
+	for i, report := range reports {
+		if *category != "" {
+			report = report.InCategory(compat.Category(*category))
+			reports[i] = report
+		}
+		if err := override(report); err != nil {
+			return err
+		}
+		suppressed[i] = baseline.Suppress(report)
+		all.Findings = append(all.Findings, report.Findings...)
+	}
+	if *writeBaseline != "" {
+		err := writeFileAtomic(*writeBaseline, func(w io.Writer) error {
+			return compat.WriteBaseline(w, all)
+		})
+		if err != nil {
+			return err
+		}
+		logger.Info("wrote baseline", "file", *writeBaseline, "findings", len(all.Findings))
+		return nil
+	}
+
+	switch *format {
+	case "text":
+		fmt.Printf("Policy %s\n\n", policy)
+		for i, report := range reports {
+			printCompatReport(report, names[against[i]], newName, threshold)
+			if suppressed[i] > 0 {
+				fmt.Printf("ℹ️  %d acknowledged findings suppressed by %s\n", suppressed[i], *baselineFile)
+			}
+			if i < len(reports)-1 {
+				fmt.Println()
+			}
+		}
+		switch bump := all.Bump(); {
+		case *semver == "":
+			fmt.Printf("\nSuggested release: %s version bump\n", bump)
+		case bump.Verify(current, next) == nil:
+			fmt.Printf("\n✅ %s..%s is a large enough bump for %s changes\n", current, next, bump)
+		}
+	case "json", "yaml":
+		for i, report := range reports {
+			if err = printStructured(*format, compatJSON{
+				Old:        names[against[i]],
+				New:        newName,
+				Policy:     policy.String(),
+				Findings:   append([]compat.Finding{}, report.Findings...),
+				Breaking:   report.Count(compat.Breaking),
+				Warnings:   report.Count(compat.Warning),
+				Info:       report.Count(compat.Info),
+				Suppressed: suppressed[i],
+				Bump:       report.Bump().String(),
+			}); err != nil {
+				break
+			}
+		}
+	case "sarif":
+		err = all.WriteSARIF(os.Stdout, "protocompat", version)
+	case "markdown":
+		for i, report := range reports {
+			if i > 0 {
+				fmt.Println()
+			}
+			if err = report.WriteMarkdown(os.Stdout, names[against[i]], newName); err != nil {
+				break
+			}
+		}
+	case "html":
+		comparisons := make([]compat.Comparison, len(reports))
+		for i, report := range reports {
+			comparisons[i] = compat.Comparison{
+				Old:      names[against[i]],
+				New:      newName,
+				OldFiles: versions[against[i]],
+				NewFiles: versions[len(versions)-1],
+				Report:   report,
+			}
+		}
+		err = compat.WriteHTML(os.Stdout, fmt.Sprintf("Compatibility of %s under policy %s", newName, policy), comparisons...)
+	default:
+		return withExitCode(exitUsage, fmt.Errorf("unknown format %q (want %s)", *format, strings.Join(compatFormats, ", ")))
+	}
+	if err != nil {
+		return err
+	}
+	if *semver != "" {
+		if err := all.Bump().Verify(current, next); err != nil {
+			return incompatible(err)
+		}
+	}
+	if threshold < 0 {
+		return nil
+	}
+	if n := all.AtLeast(threshold); n > 0 {
+		return incompatible(fmt.Errorf("%d findings at or above %s under policy %s for %s", n, threshold, policy, newName))
+	}
+	return nil
+}
+
+// addSeverityFlag registers the repeatable -severity flag and returns a
+// function applying the overrides to a report.
+func addSeverityFlag(fs *flag.FlagSet) func(*compat.Report) error {
+	var overrides [][2]string
+	fs.Func("severity", "RULE=LEVEL: report rules matching RULE (e.g. JSON_*) as info, warning, breaking, or off; repeatable", func(s string) error {
+		rule, level, ok := strings.Cut(s, "=")
+		if !ok {
+			return errors.New("want RULE=LEVEL")
+		}
+		overrides = append(overrides, [2]string{rule, level})
+		return nil
+	})
+	return func(report *compat.Report) error {
+		for _, o := range overrides {
+			rule, level := o[0], o[1]
+			if level == "off" {
+				if err := report.Drop(rule); err != nil {
+					return err
+				}
+				continue
+			}
+			s, err := compat.ParseSeverity(level)
+			if err != nil {
+				return err
+			}
+			if err := report.SetSeverity(rule, s); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func printCompatReport(report *compat.Report, old, new string, threshold compat.Severity) {
+	fmt.Printf("Comparing %s with %s\n\n", old, new)
+	for _, f := range report.Findings {
+		fmt.Printf("%s %s\n", severityMarks[f.Severity], f)
+	}
+	if len(report.Findings) > 0 {
+		fmt.Println()
+	}
+	fmt.Printf("%d breaking, %d warnings, %d info\n",
+		report.Count(compat.Breaking), report.Count(compat.Warning), report.Count(compat.Info))
+	switch {
+	case threshold < 0:
+	case report.AtLeast(threshold) > 0:
+	case threshold == compat.Breaking:
+		fmt.Println("✅ no breaking changes")
+	default:
+		fmt.Printf("✅ no findings at or above %s\n", threshold)
+	}
+}
+
+// compatVerdicts names matrix cells by the worst finding affecting them.
+var compatVerdicts = map[compat.Severity]string{
+	-1:              "ok",
+	compat.Info:     "ok",
+	compat.Warning:  "lossy",
+	compat.Breaking: "BREAKS",
+}
+
+func runCompatMatrix(args []string) error {
+	fs := newFlagSet("compat matrix")
+	vf := addVersionFlags(fs)
+	override := addSeverityFlag(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() < 2 {
+		return usageError("protocompat compat matrix [flags] V1 V2 ... (oldest first; schema names, descriptor sets, .proto files or grpc:// servers)")
+	}
+	names := fs.Args()
+	versions := make([][]protoreflect.FileDescriptor, len(names))
+	for i, name := range names {
+		var err error
+		if versions[i], err = vf.load(name); err != nil {
+			return err
+		}
+	}
+
+	// worst[p][c] is the worst finding for consumers of version c reading
+	// payloads of version p. Each pair is checked once: older readers are
+	// affected by forward findings, newer ones by backward findings.
+	worst := make([][]compat.Severity, len(names))
+	for i := range worst {
+		worst[i] = make([]compat.Severity, len(names))
+	}
+	for i := range names {
+		for j := i + 1; j < len(names); j++ {
+			report := compat.Compare(versions[i], versions[j], nil)
+			if err := override(report); err != nil {
+				return err
+			}
+			worst[i][j] = report.Affecting(compat.Backward).Worst()
+			worst[j][i] = report.Affecting(compat.Forward).Worst()
+		}
+	}
+
+	fmt.Println("Payloads written by each version (rows) as read by each version (columns):")
+	fmt.Println()
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "producer \\ consumer\t%s\t\n", strings.Join(names, "\t"))
+	for p, name := range names {
+		cells := make([]string, len(names))
+		for c := range names {
+			cells[c] = "-"
+			if c != p {
+				cells[c] = compatVerdicts[worst[p][c]]
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%s\t\n", name, strings.Join(cells, "\t"))
+	}
+	tw.Flush()
+
+	fmt.Println()
+	for p, name := range names {
+		oldest := p
+		for oldest > 0 && worst[p][oldest-1] < compat.Breaking {
+			oldest--
+		}
+		switch {
+		case p == 0:
+		case oldest == p:
+			fmt.Printf("❌ %s payloads break every older consumer\n", name)
+		default:
+			fmt.Printf("✅ %s payloads are read by consumers as old as %s\n", name, names[oldest])
+		}
+	}
+	return nil
+}
+
+func runCompatFuzz(args []string) error {
+	fs := newFlagSet("compat fuzz")
+	n := fs.Int("n", 1000, "number of random messages")
+	seed := fs.Uint64("seed", 1, "random seed; the same seed generates the same messages")
+	message := fs.String("message", "", "message to fuzz, by name within its package or in full (default: the message of a schema name given as NEW)")
+	discard := fs.Bool("discard-unknown", false, "have the old version drop unknown fields, as readers that do not preserve them do")
+	show := fs.Int("show", 5, "number of failures to print")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return usageError("protocompat compat fuzz [flags] OLD NEW (schema names, descriptor sets, .proto files or grpc:// servers)")
+	}
+	name, err := messageName(*message, fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	var mds [2]protoreflect.MessageDescriptor
+	for i := range mds {
+		if mds[i], err = findMessage(fs.Arg(i), name); err != nil {
+			return err
+		}
+	}
+
+	failures := compat.FuzzRoundTrip(mds[0], mds[1], *n, *seed, *discard)
+	fmt.Printf("Round-tripped %d random %s messages from %s through %s\n\n", *n, name, fs.Arg(1), fs.Arg(0))
+	for i, f := range failures {
+		if i == *show {
+			fmt.Printf("... and %d more\n\n", len(failures)-i)
+			break
+		}
+		fmt.Printf("❌ message %d (%d bytes: %s)\n", f.Index, len(f.Payload), truncate(hex.EncodeToString(f.Payload), 64))
+		if f.Err != nil {
+			fmt.Printf("   %v\n", f.Err)
+		}
+		for _, c := range f.Changes {
+			fmt.Printf("   %s\n", c)
+		}
+		fmt.Println()
+	}
+	if len(failures) > 0 {
+		return incompatible(fmt.Errorf("%d of %d messages changed in the round trip (seed %d)", len(failures), *n, *seed))
+	}
+	fmt.Println("✅ every message came back intact")
+	return nil
+}
+
+// messageName returns the message named by a -message flag, defaulting to
+// the message of version when that is a schema name.
+func messageName(flagValue, version string) (string, error) {
+	if flagValue != "" {
+		return flagValue, nil
+	}
+	mt, err := lookupSchema(version)
+	if err != nil {
+		return "", fmt.Errorf("-message is required: %w", err)
+	}
+	md := mt.Descriptor()
+	return strings.TrimPrefix(string(md.FullName()), string(md.ParentFile().Package())+"."), nil
+}
+
+// findMessage returns the message called name in a schema version.
+func findMessage(version, name string) (protoreflect.MessageDescriptor, error) {
+	files, err := loadSource(version, source.Options{})
+	if err != nil {
+		return nil, err
+	}
+	md, err := compat.FindMessage(files, name)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", version, err)
+	}
+	return md, nil
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/example/protobuf-compat/internal/schema"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func init() {
+	register(&command{
+		name:    "completion",
+		summary: "print a bash, zsh or fish completion script",
+		run:     runCompletion,
+	})
+}
+
+// completionScripts are the completion scripts by shell. They ask
+// "protocompat completion __complete" for the candidates of the word being
+// completed, given the words before it, and fall back to file names.
+var completionScripts = map[string]string{
+	"bash": `# bash completion for protocompat; load with
+#   source <(protocompat completion bash)
+_protocompat() {
+    local IFS=$'\n'
+    COMPREPLY=($(protocompat completion __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+}
+complete -o default -F _protocompat protocompat
+`,
+	"zsh": `#compdef protocompat
+# zsh completion for protocompat; load with
+#   source <(protocompat completion zsh)
+_protocompat() {
+    local -a candidates
+    candidates=("${(@f)$(protocompat completion __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+    if [[ -n ${candidates[1]} ]]; then
+        compadd -a candidates
+    else
+        _files
+    fi
+}
+compdef _protocompat protocompat
+`,
+	"fish": `# fish completion for protocompat; load with
+#   protocompat completion fish | source
+function __protocompat_complete
+    set -l words (commandline -opc) (commandline -ct)
+    protocompat completion __complete $words[2..-1] 2>/dev/null
+end
+complete -c protocompat -a '(__protocompat_complete)'
+`,
+}
+
+func runCompletion(args []string) error {
+	if len(args) > 0 && args[0] == "__complete" {
+		for _, c := range complete(args[1:]) {
+			fmt.Println(c)
+		}
+		return nil
+	}
+	fs := newFlagSet("completion")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	script, ok := completionScripts[fs.Arg(0)]
+	if fs.NArg() != 1 || !ok {
+		return usageError("protocompat completion bash|zsh|fish")
+	}
+	fmt.Print(script)
+	return nil
+}
+
+// complete returns the candidates for the last of words, the command-line
+// words after "protocompat", among command names, flag names and values of
+// flags naming formats or message types. Nothing means file names.
+func complete(words []string) []string {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	n := len(words) - 1
+	cur, prev := words[n], ""
+	if n > 0 {
+		prev = words[n-1]
+	}
+
+	// Global flags come before the command.
+	i := 0
+	for i < n && strings.HasPrefix(words[i], "-") {
+		if name := flagName(words[i]); (name == "format" || name == "config" || name == "log-format" || name == "out" || name == "time-format" || name == "tz") && !strings.Contains(words[i], "=") {
+			i++
+		}
+		i++
+	}
+	switch {
+	case i > n && flagName(prev) == "format":
+		return matching(cur, outputFormats)
+	case i > n:
+		return nil
+	case i == n && strings.HasPrefix(cur, "-"):
+		return matching(cur, []string{"-config", "-format", "-log-format", "-out", "-quiet", "-time-format", "-tz", "-verbose"})
+	case i == n:
+		return matching(cur, append(append(sortedCommandNames(), sortedKeys(plugins())...), "help"))
+	}
+
+	path := words[i : i+1]
+	switch name := words[i]; {
+	case name == "compat" && i+1 == n:
+		return matching(cur, sortedKeys(compatCommands))
+	case name == "compat":
+		path = words[i : i+2]
+	case name == "types" && i+1 == n:
+		return matching(cur, []string{"list"})
+	case name == "types":
+		path = words[i : i+2]
+	case name == "completion" && i+1 == n:
+		return matching(cur, sortedKeys(completionScripts))
+	}
+	fs := commandFlags(path)
+	if fs == nil {
+		return nil
+	}
+	if f := fs.Lookup(flagName(prev)); f != nil && !strings.Contains(prev, "=") && !isBoolFlag(f) {
+		return matching(cur, flagValues(path, f.Name, words[i:n]))
+	}
+	if strings.HasPrefix(cur, "-") {
+		var names []string
+		fs.VisitAll(func(f *flag.Flag) { names = append(names, "-"+f.Name) })
+		return matching(cur, names)
+	}
+	return nil
+}
+
+// commandFlags returns the flag set of a command, such as ["decode"] or
+// ["compat", "check"], by running it with -h and its output discarded.
+func commandFlags(path []string) *flag.FlagSet {
+	cmd, ok := commands[path[0]]
+	if !ok || cmd.name == "completion" {
+		return nil
+	}
+	var fs *flag.FlagSet
+	flagSetCreated = func(created *flag.FlagSet) {
+		created.SetOutput(io.Discard)
+		fs = created
+	}
+	defer func() { flagSetCreated = nil }()
+	cfg = nil // a broken configuration file must not break completion
+	stdout, stderr := os.Stdout, os.Stderr
+	if null, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0); err == nil {
+		os.Stdout, os.Stderr = null, null
+		defer null.Close()
+	}
+	args := append(slices.Clone(path[1:]), "-h")
+	cmd.run(args)
+	os.Stdout, os.Stderr = stdout, stderr
+	return fs
+}
+
+// flagValues returns the candidate values of flag name of the command at
+// path, given the command's words before the one completed. Flags naming
+// schemas take the aliases and the message types linked in, in the
+// command's descriptor sets or, for compat subcommands, in the descriptor
+// sets compared.
+func flagValues(path []string, name string, words []string) []string {
+	switch name {
+	case "format":
+		if len(path) > 1 && path[1] == "check" {
+			return compatFormats
+		}
+		return outputFormats
+	case "schema", "schemas", "diff", "message", "type":
+	default:
+		return nil
+	}
+	var extra []string
+	for i, w := range words {
+		switch {
+		case flagName(w) == "descriptor-set":
+			_, paths, ok := strings.Cut(w, "=")
+			if !ok && i+1 < len(words) {
+				paths = words[i+1]
+			}
+			for _, p := range strings.Split(paths, ",") {
+				schema.LoadDescriptorSet(strings.TrimSpace(p))
+			}
+		case path[0] == "compat" && !strings.HasPrefix(w, "-"):
+			if files, err := schema.ReadDescriptorSet(w); err == nil {
+				files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+					extra = appendMessageNames(extra, fd.Messages())
+					return true
+				})
+			}
+		}
+	}
+	return append(append(schema.Aliases(), extra...), schema.MessageNames()...)
+}
+
+func appendMessageNames(names []string, msgs protoreflect.MessageDescriptors) []string {
+	for i := 0; i < msgs.Len(); i++ {
+		md := msgs.Get(i)
+		if !md.IsMapEntry() {
+			names = append(names, string(md.FullName()))
+		}
+		names = appendMessageNames(names, md.Messages())
+	}
+	return names
+}
+
+// flagName returns the name of the flag in word, as in -name, --name or
+// -name=value, or "" if word is not a flag.
+func flagName(word string) string {
+	if !strings.HasPrefix(word, "-") {
+		return ""
+	}
+	name, _, _ := strings.Cut(strings.TrimLeft(word, "-"), "=")
+	return name
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// matching returns the candidates starting with prefix, sorted and
+// without duplicates.
+func matching(prefix string, candidates []string) []string {
+	var out []string
+	for _, c := range candidates {
+		if strings.HasPrefix(c, prefix) {
+			out = append(out, c)
+		}
+	}
+	sort.Strings(out)
+	return slices.Compact(out)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	return slices.Sorted(maps.Keys(m))
+}
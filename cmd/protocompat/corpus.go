@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/example/protobuf-compat/internal/payload"
+)
+
+// payloadFunc is called with every payload of a batch, or the error
+// reading it.
+type payloadFunc func(name string, data []byte, err error) error
+
+// readCorpus calls fn for every payload of a corpus. path is either a
+// directory, each regular file of which holds one payload, or a file (or
+// "-" for stdin) holding one encoded payload per line; blank lines are
+// skipped. Payloads that fail to decode are passed to fn with their error
+// so that callers can count and report them; an error returned by fn, or
+// ctx being done, stops the walk.
+func readCorpus(ctx context.Context, path string, enc payload.Encoding, fn payloadFunc) error {
+	if path != "-" {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return readCorpusDir(ctx, path, enc, fn)
+		}
+	}
+
+	return readCorpusLines(ctx, path, func(text string) ([]byte, error) {
+		return payload.Decode(text, enc)
+	}, fn)
+}
+
+// readCorpusLines calls fn for every non-blank line of the named file, or of
+// stdin when path is "-", after decoding it with decode, until ctx is done.
+func readCorpusLines(ctx context.Context, path string, decode func(text string) ([]byte, error), fn payloadFunc) error {
+	var r io.Reader = os.Stdin
+	var size int64
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+		if fi, err := f.Stat(); err == nil && fi.Mode().IsRegular() {
+			size = fi.Size()
+		}
+	}
+	p := startProgress(size, true)
+	defer p.finish()
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), maxLogLine)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		data, err := decode(text)
+		if err := fn(fmt.Sprintf("%s:%d", path, line), data, err); err != nil {
+			return err
+		}
+		p.step(int64(len(sc.Bytes())+1), err != nil)
+	}
+	return sc.Err()
+}
+
+func readCorpusDir(ctx context.Context, dir string, enc payload.Encoding, fn payloadFunc) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	entries = slices.DeleteFunc(entries, func(e os.DirEntry) bool { return !e.Type().IsRegular() })
+	p := startProgress(int64(len(entries)), false)
+	defer p.finish()
+	for _, e := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		name := filepath.Join(dir, e.Name())
+		data, err := payload.ReadFile(name, enc)
+		if err := fn(name, data, err); err != nil {
+			return err
+		}
+		p.step(int64(len(data)), err != nil)
+	}
+	return nil
+}
+
+// runBatch calls process with every payload each reads, printing its
+// results under the payload's name, then a summary of the payloads that
+// were processed cleanly, in the words of clean, and those that failed. It
+// fails if any payload did, in the class of the first failure if it has
+// one. Readers passed as each report progress; runBatch counts the
+// payloads that fail to process. Interrupted batches are summed up too.
+func runBatch(each func(fn payloadFunc) error, clean string, process func(data []byte) error) error {
+	var ok, failed int
+	var first error
+	err := each(func(name string, data []byte, err error) error {
+		if err != nil {
+			fmt.Printf("=== %s ===\n❌ %v\n\n", name, err)
+		} else {
+			fmt.Printf("=== %s (%d bytes) ===\n", name, len(data))
+			if err = process(data); err != nil {
+				fmt.Printf("❌ %v\n", err)
+				activeProgress.fail()
+			}
+			fmt.Println()
+		}
+		if err != nil {
+			failed++
+			first = cmp.Or(first, err)
+		} else {
+			ok++
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+	fmt.Printf("%d payloads: %d %s, %d failed\n", ok+failed, ok, clean, failed)
+	return cmp.Or(err, batchError(ok, failed, first))
+}
+
+// batchRecord is the line -format ndjson prints for a payload of a batch.
+type batchRecord struct {
+	Payload string `json:"payload"`
+	Bytes   int    `json:"bytes"`
+	Result  any    `json:"result,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// streamBatch is runBatch for -format ndjson: it prints a batchRecord for
+// every payload as soon as process returns its result, and logs the
+// summary rather than printing it, so that every line of the output is a
+// record.
+func streamBatch(each func(fn payloadFunc) error, clean string, process func(data []byte) (any, error)) error {
+	var ok, failed int
+	var first error
+	err := each(func(name string, data []byte, err error) error {
+		rec := batchRecord{Payload: name, Bytes: len(data)}
+		if err == nil {
+			if rec.Result, err = process(data); err != nil {
+				activeProgress.fail()
+			}
+		}
+		if err != nil {
+			rec.Error = err.Error()
+			failed++
+			first = cmp.Or(first, err)
+		} else {
+			ok++
+		}
+		return printNDJSON(rec)
+	})
+	if err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+	logger.Info(fmt.Sprintf("%d payloads: %d %s, %d failed", ok+failed, ok, clean, failed))
+	return cmp.Or(err, batchError(ok, failed, first))
+}
+
+// batchError is the error of a batch of which failed payloads failed, the
+// first with first.
+func batchError(ok, failed int, first error) error {
+	if failed == 0 {
+		return nil
+	}
+	err := fmt.Errorf("%d of %d payloads failed", failed, ok+failed)
+	if code := exitCode(first); code != exitFailure {
+		err = withExitCode(code, err)
+	}
+	return err
+}
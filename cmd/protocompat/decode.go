@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/example/protobuf-compat/internal/schema"
+	"github.com/example/protobuf-compat/internal/wire"
+	"github.com/example/protobuf-compat/pkg/wireanalyze"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func init() {
+	register(&command{
+		name:    "decode",
+		summary: "decode a payload with a linked-in schema or a type from a descriptor set",
+		run:     runDecode,
+		formats: []string{"json", "ndjson", "yaml", "protoscope"},
+	})
+}
+
+func runDecode(args []string) error {
+	fs := newFlagSet("decode")
+	sf := addSchemaFlags(fs)
+	asJSON := fs.Bool("json", false, "print the decoded message as JSON instead of a typed field listing")
+	pf := addPayloadFlags(fs)
+	wf := addWatchFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if wf.enabled() {
+		if pf.stdin(fs.Args()) {
+			return withExitCode(exitUsage, errors.New("-watch needs the payload as an argument, with -hex or -base64, or in a file"))
+		}
+		return wf.run(fs, func() error { return runDecode(args) })
+	}
+	files, err := pf.files(fs.Args())
+	if err != nil {
+		return err
+	}
+	if files == nil && fs.NArg() > 1 {
+		return usageError("protocompat decode [-schema NAME] [-type MESSAGE] [-descriptor-set FILE] [-diff NAME] [payload | FILE... | 'GLOB']")
+	}
+	if err := sf.resolve(); err != nil {
+		return err
+	}
+	if files != nil {
+		if outputFormat == "ndjson" {
+			if sf.diffType != nil {
+				return withExitCode(exitUsage, errors.New("-format ndjson cannot be combined with -diff"))
+			}
+			each := func(fn payloadFunc) error { return pf.readFiles(rootContext, files, fn) }
+			return streamBatch(each, "decoded", func(data []byte) (any, error) { return decodedValue(sf, data) })
+		}
+		if outputFormat != "text" {
+			return withExitCode(exitUsage, fmt.Errorf("-format %s cannot be combined with several payload files", outputFormat))
+		}
+		each := func(fn payloadFunc) error { return pf.readFiles(rootContext, files, fn) }
+		return runBatch(each, "decoded", func(data []byte) error { return printDecodedText(sf, *asJSON, data) })
+	}
+	data, err := pf.read(fs.Args())
+	if err != nil {
+		return err
+	}
+	if outputFormat != "text" {
+		return printDecodedAs(outputFormat, sf, data)
+	}
+	return printDecodedText(sf, *asJSON, data)
+}
+
+// printDecodedText prints data decoded with the selected schemas as a
+// typed field listing or, with asJSON, as JSON.
+func printDecodedText(sf *schemaFlags, asJSON bool, data []byte) error {
+	if asJSON || sf.msgType == nil || sf.diffType != nil {
+		return sf.printDecoded(data)
+	}
+	msg, err := sf.decode(sf.msgType, data)
+	if err != nil {
+		return schemaMismatch(err)
+	}
+	m := msg.ProtoReflect()
+	fmt.Printf("%s\n", m.Descriptor().FullName())
+	printMessage(m, 1)
+	return nil
+}
+
+// printDecodedAs prints data decoded with the selected schema as protojson
+// or YAML, or without a schema its wire-format structure in the format.
+func printDecodedAs(format string, sf *schemaFlags, data []byte) error {
+	if sf.diffType != nil {
+		return withExitCode(exitUsage, fmt.Errorf("-format %s cannot be combined with -diff", format))
+	}
+	if format == "protoscope" {
+		fields, err := wire.Parse(data)
+		if err != nil {
+			return parseFailure(err)
+		}
+		fmt.Print(wire.Protoscope(fields))
+		return nil
+	}
+	v, err := decodedValue(sf, data)
+	if err != nil {
+		return err
+	}
+	return printStructured(format, v)
+}
+
+// decodedValue returns data decoded with the selected schema as protojson,
+// with timestamps in the -time-format, or without a schema its wire-format
+// structure, for printing as JSON or YAML.
+func decodedValue(sf *schemaFlags, data []byte) (any, error) {
+	if sf.msgType == nil {
+		fields, err := wireanalyze.Options{}.Fields(data)
+		if err != nil {
+			return nil, parseFailure(err)
+		}
+		return fields, nil
+	}
+	msg, err := sf.decode(sf.msgType, data)
+	if err != nil {
+		return nil, schemaMismatch(err)
+	}
+	out, err := schema.MarshalJSON(msg)
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(out), nil
+}
+
+// printMessage lists the populated fields of m in field-number order with
+// their numbers and types, descending into singular nested messages.
+func printMessage(m protoreflect.Message, depth int) {
+	indent := strings.Repeat("  ", depth)
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if !m.Has(fd) {
+			continue
+		}
+		v := m.Get(fd)
+		// Timestamps are rendered as times, by FormatValue.
+		if fd.Message() != nil && !fd.IsList() && !fd.IsMap() && fd.Message().FullName() != "google.protobuf.Timestamp" {
+			fmt.Printf("%s%d %s (%s):\n", indent, fd.Number(), fd.Name(), fieldTypeName(fd))
+			printMessage(v.Message(), depth+1)
+			continue
+		}
+		fmt.Printf("%s%d %s (%s): %s\n", indent, fd.Number(), fd.Name(), fieldTypeName(fd), schema.FormatValue(fd, v))
+	}
+	if raw := m.GetUnknown(); len(raw) > 0 {
+		fmt.Printf("%s⚠️  %d bytes of unknown fields: %X\n", indent, len(raw), raw)
+	}
+}
+
+// fieldTypeName renders a field's type as it would appear in a .proto file.
+func fieldTypeName(fd protoreflect.FieldDescriptor) string {
+	if fd.IsMap() {
+		return fmt.Sprintf("map<%s, %s>", fieldTypeName(fd.MapKey()), fieldTypeName(fd.MapValue()))
+	}
+	name := fd.Kind().String()
+	switch {
+	case fd.Message() != nil:
+		name = string(fd.Message().FullName())
+	case fd.Enum() != nil:
+		name = string(fd.Enum().FullName())
+	}
+	if fd.IsList() {
+		return "repeated " + name
+	}
+	return name
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/example/protobuf-compat/internal/wire"
+)
+
+func init() {
+	register(&command{
+		name:    "delimited",
+		summary: "decode a stream of varint length-prefixed messages",
+		run:     runDelimited,
+	})
+}
+
+func runDelimited(args []string) error {
+	fs := newFlagSet("delimited")
+	maxSize := fs.Int("max-size", 64<<20, "largest message accepted")
+	sf := addSchemaFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return usageError("protocompat delimited [flags] FILE (\"-\" for stdin)")
+	}
+	if err := sf.resolve(); err != nil {
+		return err
+	}
+
+	r := io.Reader(os.Stdin)
+	if path := fs.Arg(0); path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	count := 0
+	err := wire.ReadDelimited(rootContext, r, *maxSize, func(fr wire.Frame) error {
+		fmt.Printf("message %d at offset %d (%d bytes)\n", fr.Index, fr.Offset, len(fr.Data))
+		sf.printDecoded(fr.Data)
+		fmt.Println()
+		count++
+		return nil
+	})
+	fmt.Printf("%d messages\n", count)
+	if err != nil {
+		return parseFailure(err)
+	}
+	return sf.strictError()
+}
@@ -4,22 +4,37 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/example/protobuf-compat/pkg/compat"
 	v1 "github.com/example/protobuf-compat/proto/v1"
 	v2 "github.com/example/protobuf-compat/proto/v2"
-	"google.golang.org/protobuf/encoding/protojson"
-	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
-func main() {
-	fmt.Println("=== Protobuf Backward Compatibility Demo ===\n")
+func init() {
+	register(&command{
+		name:    "demo",
+		summary: "walk through v1 and v2 payloads read by the other version",
+		run:     runDemo,
+	})
+}
+
+func runDemo(args []string) error {
+	fs := newFlagSet("demo")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return usageError("protocompat demo")
+	}
+
+	fmt.Print("=== Protobuf Backward Compatibility Demo ===\n\n")
 
 	// Create timestamps for our test data
 	startTime := timestamppb.New(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
 	stopTime := timestamppb.New(time.Date(2024, 1, 1, 13, 0, 0, 0, time.UTC))
 
 	fmt.Println("--- SCENARIO 1: Old Producer (v1) → New Consumer (v2) ---")
-	fmt.Println("(Forward Compatibility: new field gets default value)\n")
+	fmt.Print("(Forward Compatibility: new field gets default value)\n\n")
 
 	// Create v1 message (old producer)
 	v1Msg := &v1.InfrastructureExecution{
@@ -36,25 +51,15 @@ func main() {
 	fmt.Printf("  instance_ids: %v\n", v1Msg.InstanceIds)
 	fmt.Println()
 
-	// Marshal v1 to binary
-	v1Binary, err := proto.Marshal(v1Msg)
+	// Marshal v1 to binary and JSON, and read both with v2 (new consumer)
+	toV2, err := compat.SimulateExchange(v1Msg, (&v2.InfrastructureExecution{}).ProtoReflect().Type())
 	if err != nil {
-		panic(err)
+		return err
 	}
-	fmt.Printf("V1 Binary size: %d bytes\n", len(v1Binary))
+	fmt.Printf("V1 Binary size: %d bytes\n", len(toV2.Binary))
+	fmt.Printf("V1 JSON:\n%s\n\n", string(toV2.JSON))
 
-	// Marshal v1 to JSON
-	v1JSON, err := protojson.Marshal(v1Msg)
-	if err != nil {
-		panic(err)
-	}
-	fmt.Printf("V1 JSON:\n%s\n\n", string(v1JSON))
-
-	// Unmarshal binary into v2 (new consumer)
-	v2FromBinary := &v2.InfrastructureExecution{}
-	if err := proto.Unmarshal(v1Binary, v2FromBinary); err != nil {
-		panic(err)
-	}
+	v2FromBinary := toV2.FromBinary.(*v2.InfrastructureExecution)
 
 	fmt.Println("✅ V2 Message from Binary (new consumer reading old data):")
 	fmt.Printf("  execution_id: %s\n", v2FromBinary.ExecutionId)
@@ -63,11 +68,7 @@ func main() {
 	fmt.Printf("  message: \"%s\" (new field gets default/empty value)\n", v2FromBinary.Message)
 	fmt.Println()
 
-	// Unmarshal JSON into v2 (new consumer)
-	v2FromJSON := &v2.InfrastructureExecution{}
-	if err := protojson.Unmarshal(v1JSON, v2FromJSON); err != nil {
-		panic(err)
-	}
+	v2FromJSON := toV2.FromJSON.(*v2.InfrastructureExecution)
 
 	fmt.Println("✅ V2 Message from JSON (new consumer reading old data):")
 	fmt.Printf("  execution_id: %s\n", v2FromJSON.ExecutionId)
@@ -77,7 +78,7 @@ func main() {
 	fmt.Println()
 
 	fmt.Println("--- SCENARIO 2: New Producer (v2) → Old Consumer (v1) ---")
-	fmt.Println("(Backward Compatibility: old consumer ignores new field)\n")
+	fmt.Print("(Backward Compatibility: old consumer ignores new field)\n\n")
 
 	// Create v2 message (new producer) with the new field populated
 	v2Msg := &v2.InfrastructureExecution{
@@ -96,25 +97,16 @@ func main() {
 	fmt.Printf("  message: \"%s\" (new field)\n", v2Msg.Message)
 	fmt.Println()
 
-	// Marshal v2 to binary
-	v2Binary, err := proto.Marshal(v2Msg)
+	// Marshal v2 to binary and JSON, and read both with v1 (old consumer).
+	// JSON readers discard the new 'message' key (same behavior as binary)
+	toV1, err := compat.SimulateExchange(v2Msg, (&v1.InfrastructureExecution{}).ProtoReflect().Type())
 	if err != nil {
-		panic(err)
+		return err
 	}
-	fmt.Printf("V2 Binary size: %d bytes\n", len(v2Binary))
+	fmt.Printf("V2 Binary size: %d bytes\n", len(toV1.Binary))
+	fmt.Printf("V2 JSON:\n%s\n\n", string(toV1.JSON))
 
-	// Marshal v2 to JSON
-	v2JSON, err := protojson.Marshal(v2Msg)
-	if err != nil {
-		panic(err)
-	}
-	fmt.Printf("V2 JSON:\n%s\n\n", string(v2JSON))
-
-	// Unmarshal binary into v1 (old consumer)
-	v1FromBinary := &v1.InfrastructureExecution{}
-	if err := proto.Unmarshal(v2Binary, v1FromBinary); err != nil {
-		panic(err)
-	}
+	v1FromBinary := toV1.FromBinary.(*v1.InfrastructureExecution)
 
 	fmt.Println("✅ V1 Message from Binary (old consumer ignores new field):")
 	fmt.Printf("  execution_id: %s\n", v1FromBinary.ExecutionId)
@@ -123,13 +115,7 @@ func main() {
 	fmt.Printf("  (message field not present in v1 schema - safely ignored)\n")
 	fmt.Println()
 
-	// Unmarshal JSON into v1 (old consumer)
-	// Use DiscardUnknown to ignore the new 'message' field (same behavior as binary)
-	v1FromJSON := &v1.InfrastructureExecution{}
-	unmarshalOpts := protojson.UnmarshalOptions{`DiscardUnknown`: true}
-	if err := unmarshalOpts.Unmarshal(v2JSON, v1FromJSON); err != nil {
-		panic(err)
-	}
+	v1FromJSON := toV1.FromJSON.(*v1.InfrastructureExecution)
 
 	fmt.Println("✅ V1 Message from JSON (old consumer ignores new field):")
 	fmt.Printf("  execution_id: %s\n", v1FromJSON.ExecutionId)
@@ -143,4 +129,5 @@ func main() {
 	fmt.Println("✅ New consumers can read old data (new fields get default values)")
 	fmt.Println("✅ Old consumers can read new data (unknown fields are ignored)")
 	fmt.Println("✅ Schema evolution works seamlessly in both directions")
+	return nil
 }
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"flag"
+	"strings"
+
+	"github.com/example/protobuf-compat/internal/schema"
+	"github.com/example/protobuf-compat/internal/wire"
+)
+
+// addDescriptorSetFlag registers the -descriptor-set flag and returns a
+// function loading the named sets; call it after parsing flags. Loaded types
+// can be named as schemas and resolve google.protobuf.Any values.
+func addDescriptorSetFlag(fs *flag.FlagSet) func() error {
+	paths := fs.String("descriptor-set", "", "comma-separated FileDescriptorSet files (protoc --descriptor_set_out) with additional types")
+	return func() error {
+		if *paths != "" {
+			for _, path := range strings.Split(*paths, ",") {
+				if err := schema.LoadDescriptorSet(strings.TrimSpace(path)); err != nil {
+					return parseFailure(err)
+				}
+				logger.Debug("loaded descriptor set", "file", strings.TrimSpace(path))
+			}
+		}
+		wire.AnyResolver = schema.Resolver()
+		return nil
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/example/protobuf-compat/internal/schema"
+	"github.com/example/protobuf-compat/internal/wire"
+)
+
+func init() {
+	register(&command{
+		name:    "diff",
+		summary: "show the fields added, removed or changed between two payloads",
+		run:     runDiff,
+	})
+}
+
+func runDiff(args []string) error {
+	fs := newFlagSet("diff")
+	name := fs.String("schema", "", "decode both payloads with this schema, "+schemaHelp+", and name the changed fields (default: compare the wire format)")
+	typ := addTypeFlag(fs, name)
+	loadSets := addDescriptorSetFlag(fs)
+	decode := addEncodingFlag(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := loadSets(); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return usageError("protocompat diff [flags] <old payload> <new payload>")
+	}
+	oldData, err := decode(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("old payload: %w", err)
+	}
+	newData, err := decode(fs.Arg(1))
+	if err != nil {
+		return fmt.Errorf("new payload: %w", err)
+	}
+
+	var lines []string
+	if *name != "" || *typ.name != "" {
+		mt, err := typ.lookup()
+		if err != nil {
+			return err
+		}
+		oldMsg, err := schema.Decode(mt, oldData)
+		if err != nil {
+			return schemaMismatch(fmt.Errorf("old payload: %w", err))
+		}
+		newMsg, err := schema.Decode(mt, newData)
+		if err != nil {
+			return schemaMismatch(fmt.Errorf("new payload: %w", err))
+		}
+		for _, c := range schema.Diff(oldMsg, newMsg) {
+			lines = append(lines, c.String())
+		}
+	} else {
+		oldFields, err := wire.Parse(oldData)
+		if err != nil {
+			return parseFailure(fmt.Errorf("old payload: %w", err))
+		}
+		newFields, err := wire.Parse(newData)
+		if err != nil {
+			return parseFailure(fmt.Errorf("new payload: %w", err))
+		}
+		for _, d := range wire.Compare(oldFields, newFields) {
+			lines = append(lines, d.String())
+		}
+	}
+
+	if len(lines) == 0 {
+		fmt.Println("✅ no differences")
+		return nil
+	}
+	for _, l := range lines {
+		fmt.Println(l)
+	}
+	return nil
+}
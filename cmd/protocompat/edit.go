@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/example/protobuf-compat/internal/wire"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func init() {
+	register(&command{
+		name:    "edit",
+		summary: "set, replace or delete fields at the wire level and emit the new payload",
+		run:     runEdit,
+	})
+}
+
+// fieldEdit is one -set, -replace or -delete flag.
+type fieldEdit struct {
+	op    wire.EditOp
+	path  []protowire.Number
+	value wire.Value
+}
+
+// editList collects edits from several flags, keeping command-line order.
+type editList []fieldEdit
+
+func (l *editList) flag(op wire.EditOp) func(string) error {
+	return func(s string) error {
+		spec, value, _ := strings.Cut(s, "=")
+		path, err := wire.ParsePath(spec)
+		if err != nil {
+			return err
+		}
+		e := fieldEdit{op: op, path: path}
+		if op != wire.Delete {
+			if e.value, err = wire.ParseValue(value); err != nil {
+				return err
+			}
+		}
+		*l = append(*l, e)
+		return nil
+	}
+}
+
+func runEdit(args []string) error {
+	fs := newFlagSet("edit")
+	var edits editList
+	fs.Func("set", "set PATH=KIND:VALUE, replacing all occurrences or adding the field (repeatable)", edits.flag(wire.Set))
+	fs.Func("replace", "like -set, but fail if the field is absent (repeatable)", edits.flag(wire.Replace))
+	fs.Func("delete", "delete every occurrence of the field at PATH (repeatable)", edits.flag(wire.Delete))
+	redact := fs.Bool("redact", false, "after editing, replace string and bytes contents with same-length placeholders")
+	out := fs.String("o", "", "write the raw result to this file instead of printing it as hex")
+	pf := addPayloadFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 || len(edits) == 0 && !*redact {
+		return usageError("protocompat edit [-set|-replace|-delete PATH[=KIND:VALUE]]... [-redact] [payload]\n" +
+			"  PATH is a dotted field-number path such as 5.1; KIND is varint, sint, bool,\n" +
+			"  fixed32, fixed64, float, double, string, bytes (hex) or message (hex)")
+	}
+	data, err := pf.read(fs.Args())
+	if err != nil {
+		return err
+	}
+	for _, e := range edits {
+		if data, err = wire.Edit(data, e.path, e.op, e.value); err != nil {
+			return err
+		}
+	}
+	if *redact {
+		data = wire.Redact(data)
+	}
+	if *out != "" {
+		if err := writeFile(*out, data); err != nil {
+			return err
+		}
+		logger.Info("wrote payload", "file", *out, "bytes", len(data))
+		return nil
+	}
+	fmt.Printf("%X\n", data)
+	return nil
+}
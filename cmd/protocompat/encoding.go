@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/example/protobuf-compat/internal/payload"
+)
+
+// addEncodingFlag registers the -encoding flag of commands that take
+// payloads as text and returns a function decoding such text.
+func addEncodingFlag(fs *flag.FlagSet) func(text string) ([]byte, error) {
+	enc := fs.String("encoding", "auto", "payload text encoding: auto, hex, base64 or base64url")
+	return func(text string) ([]byte, error) {
+		e, err := payload.ParseEncoding(*enc)
+		if err != nil {
+			return nil, withExitCode(exitUsage, err)
+		}
+		data, err := payload.Decode(text, e)
+		return data, parseFailure(err)
+	}
+}
+
+// payloadFlags are the flags of commands inspecting a single payload,
+// which is given as an argument, in the -encoding text encoding or naming
+// a file, with -hex, -base64 or -file, or piped to stdin.
+type payloadFlags struct {
+	encoding          *string
+	hex, base64, file *string
+}
+
+func addPayloadFlags(fs *flag.FlagSet) *payloadFlags {
+	return &payloadFlags{
+		encoding: fs.String("encoding", "auto", "encoding of payload arguments, files and stdin: auto, raw, hex, base64 or base64url"),
+		hex:      fs.String("hex", "", "payload as hex"),
+		base64:   fs.String("base64", "", "payload as standard or URL-safe base64"),
+		file:     fs.String("file", "", "read the payload from this file (\"-\" for stdin), raw binary or text"),
+	}
+}
+
+var errNoPayload = withExitCode(exitUsage, errors.New("no payload: give it as an argument, with -hex, -base64 or -file, or on stdin"))
+
+// read returns the payload, given by the flags or as the only one of
+// args, which is read as a file if it names one. Without either, it reads
+// stdin unless that is a terminal.
+func (p *payloadFlags) read(args []string) ([]byte, error) {
+	enc, err := payload.ParseEncoding(*p.encoding)
+	if err != nil {
+		return nil, withExitCode(exitUsage, err)
+	}
+	given := len(args)
+	for _, s := range []string{*p.hex, *p.base64, *p.file} {
+		if s != "" {
+			given++
+		}
+	}
+	var data []byte
+	switch {
+	case given > 1:
+		return nil, withExitCode(exitUsage, errors.New("give a single payload: as an argument or with one of -hex, -base64 and -file"))
+	case *p.hex != "":
+		data, err = payload.Decode(*p.hex, payload.Hex)
+	case *p.base64 != "" && strings.ContainsAny(*p.base64, "-_"):
+		data, err = payload.Decode(*p.base64, payload.Base64URL)
+	case *p.base64 != "":
+		data, err = payload.Decode(*p.base64, payload.Base64)
+	case *p.file != "":
+		data, err = payload.ReadFile(*p.file, enc)
+	case len(args) == 1 && isFile(args[0]):
+		data, err = payload.ReadFile(args[0], enc)
+	case len(args) == 1:
+		data, err = payload.Decode(args[0], enc)
+	default:
+		if fi, err := os.Stdin.Stat(); err != nil || fi.Mode()&os.ModeCharDevice != 0 {
+			return nil, errNoPayload
+		}
+		data, err = payload.Read(os.Stdin, enc)
+	}
+	return data, parseFailure(err)
+}
+
+// stdin reports whether the payload is read from stdin.
+func (p *payloadFlags) stdin(args []string) bool {
+	return len(args) == 0 && *p.hex == "" && *p.base64 == "" && (*p.file == "" || *p.file == "-")
+}
+
+// files returns the payload files named by args, for processing each on
+// its own: any number of file names or glob patterns, such as
+// 'payloads/*.bin' quoted for the shell to leave it alone, or one pattern
+// as -file. It returns nil if args and -file name a single payload.
+func (p *payloadFlags) files(args []string) ([]string, error) {
+	patterns := args
+	switch {
+	case len(args) > 1 || len(args) == 1 && isGlob(args[0]):
+		if *p.hex != "" || *p.base64 != "" || *p.file != "" {
+			return nil, withExitCode(exitUsage, errors.New("give payload files as arguments or with -file, not both"))
+		}
+	case len(args) == 0 && isGlob(*p.file):
+		patterns = []string{*p.file}
+	default:
+		return nil, nil
+	}
+	var files []string
+	for _, pattern := range patterns {
+		if !isGlob(pattern) {
+			files = append(files, pattern)
+			continue
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, withExitCode(exitUsage, fmt.Errorf("%s: %w", pattern, err))
+		}
+		n := len(files)
+		for _, m := range matches {
+			if isFile(m) {
+				files = append(files, m)
+			}
+		}
+		if len(files) == n {
+			return nil, withExitCode(exitIO, fmt.Errorf("no payload files match %s", pattern))
+		}
+	}
+	return files, nil
+}
+
+// isFile reports whether s names a regular file, as payloads given as
+// text seldom do.
+func isFile(s string) bool {
+	fi, err := os.Stat(s)
+	return err == nil && fi.Mode().IsRegular()
+}
+
+// isGlob reports whether s has glob metacharacters, which neither paths
+// nor encoded payloads usually do.
+func isGlob(s string) bool {
+	return strings.ContainsAny(s, "*?[")
+}
+
+// readFiles calls fn with the payload of every file, read in the -encoding
+// encoding, until ctx is done.
+func (p *payloadFlags) readFiles(ctx context.Context, files []string, fn payloadFunc) error {
+	enc, err := payload.ParseEncoding(*p.encoding)
+	if err != nil {
+		return withExitCode(exitUsage, err)
+	}
+	prog := startProgress(int64(len(files)), false)
+	defer prog.finish()
+	for _, name := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		data, err := payload.ReadFile(name, enc)
+		if err := fn(name, data, parseFailure(err)); err != nil {
+			return err
+		}
+		prog.step(int64(len(data)), err != nil)
+	}
+	return nil
+}
+
+// decode decodes a payload given as text in the -encoding encoding.
+func (p *payloadFlags) decode(text string) ([]byte, error) {
+	enc, err := payload.ParseEncoding(*p.encoding)
+	if err != nil {
+		return nil, withExitCode(exitUsage, err)
+	}
+	data, err := payload.Decode(text, enc)
+	return data, parseFailure(err)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io/fs"
+	"net"
+	"net/url"
+
+	"github.com/example/protobuf-compat/internal/schema"
+)
+
+// Exit codes, one per class of failure, so scripts can branch on the kind
+// of failure without reading the messages.
+const (
+	exitOK           = 0
+	exitFailure      = 1 // failures of no other class
+	exitUsage        = 2 // bad flags, arguments or configuration
+	exitParse        = 3 // input that is not a well-formed payload or descriptor set
+	exitSchema       = 4 // a payload that does not match its schema
+	exitIncompatible = 5 // a schema change the compatibility checks reject
+	exitIO           = 6 // files or services that cannot be read or written
+)
+
+// exitCodeNames name the exit codes in capabilities.
+var exitCodeNames = []string{
+	exitOK:           "ok",
+	exitFailure:      "failure",
+	exitUsage:        "usage",
+	exitParse:        "parse",
+	exitSchema:       "schema",
+	exitIncompatible: "incompatible",
+	exitIO:           "io",
+}
+
+// exitError gives err the exit code of its class.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+func (e *exitError) Unwrap() error { return e.err }
+
+// withExitCode classes err, if not nil and not classed already, under
+// code.
+func withExitCode(code int, err error) error {
+	var e *exitError
+	if err == nil || errors.As(err, &e) {
+		return err
+	}
+	return &exitError{code: code, err: err}
+}
+
+// usageError reports a command run with the wrong arguments.
+func usageError(usage string) error {
+	return withExitCode(exitUsage, errors.New("usage: "+usage))
+}
+
+// parseFailure, schemaMismatch and incompatible class err, if not nil.
+func parseFailure(err error) error   { return withExitCode(exitParse, err) }
+func schemaMismatch(err error) error { return withExitCode(exitSchema, err) }
+func incompatible(err error) error   { return withExitCode(exitIncompatible, err) }
+
+// exitCode returns the exit code for a command failing with err. Failing
+// to read or write files or reach services takes precedence over the
+// class of the operation it interrupted. Naming a schema that does not
+// exist is a usage error wherever the name is given.
+func exitCode(err error) int {
+	var pathErr *fs.PathError
+	var urlErr *url.Error
+	var netErr net.Error
+	var e *exitError
+	var pe pluginExit
+	switch {
+	case errors.As(err, &pathErr), errors.As(err, &urlErr), errors.As(err, &netErr):
+		return exitIO
+	case errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.Is(err, schema.ErrUnknown):
+		return exitUsage
+	case errors.As(err, &e):
+		return e.code
+	case errors.As(err, &pe):
+		return int(pe)
+	}
+	return exitFailure
+}
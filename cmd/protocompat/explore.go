@@ -0,0 +1,358 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/example/protobuf-compat/internal/wire"
+	"golang.org/x/term"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func init() {
+	register(&command{
+		name:    "explore",
+		summary: "browse a payload interactively as a tree with a synchronised hex view",
+		run:     runExplore,
+	})
+}
+
+// ANSI escape sequences used by the explorer.
+const (
+	ansiClear   = "\x1b[H\x1b[2J"
+	ansiReverse = "\x1b[7m"
+	ansiTag     = "\x1b[33m"
+	ansiDim     = "\x1b[2m"
+	ansiReset   = "\x1b[0m"
+	ansiHide    = "\x1b[?25l"
+	ansiShow    = "\x1b[?25h"
+)
+
+// hexRows is the height of the hex pane.
+const hexRows = 8
+
+func runExplore(args []string) error {
+	fs := newFlagSet("explore")
+	loadSets := addDescriptorSetFlag(fs)
+	addFixedFlag(fs)
+	pf := addPayloadFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := loadSets(); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return usageError("protocompat explore [flags] [payload]")
+	}
+	data, err := pf.read(fs.Args())
+	if err != nil {
+		return err
+	}
+	fields, parseErr := wire.Parse(data)
+	if len(fields) == 0 {
+		return parseFailure(fmt.Errorf("nothing to explore: %v", parseErr))
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return errors.New("explore needs an interactive terminal; use analyze instead")
+	}
+
+	old, err := term.MakeRaw(int(os.Stdin.Fd()))
+	if err != nil {
+		return err
+	}
+	defer term.Restore(int(os.Stdin.Fd()), old)
+	fmt.Print(ansiHide)
+	defer fmt.Print(ansiShow + ansiClear)
+
+	x := &explorer{data: data, roots: newNodes(fields, 0), parseErr: parseErr}
+	in := bufio.NewReader(os.Stdin)
+	for {
+		x.draw()
+		key, err := readKey(in)
+		if err != nil {
+			return err
+		}
+		if !x.handle(key) {
+			return nil
+		}
+	}
+}
+
+// node is one field in the explorer tree.
+type node struct {
+	field    wire.Field
+	depth    int
+	expanded bool
+	children []*node
+}
+
+func newNodes(fields []wire.Field, depth int) []*node {
+	nodes := make([]*node, len(fields))
+	for i, f := range fields {
+		n := &node{field: f, depth: depth}
+		if len(f.Nested) > 0 && (f.Type == protowire.StartGroupType || !wire.IsText(f.Value)) {
+			n.children = newNodes(f.Nested, depth+1)
+		}
+		nodes[i] = n
+	}
+	return nodes
+}
+
+func (n *node) label() string {
+	f := n.field
+	s := fmt.Sprintf("field %d (%s): %s", f.Number, wire.TypeName(f.Type), describeValue(f))
+	if wk, ok := wire.DetectWellKnown(f); ok {
+		s += fmt.Sprintf("  ⟶ %s %s", wk.Type, wk.Text)
+	} else if guess, ok := wire.FieldTime(f); ok {
+		s += "  ⏱ " + guess.Format()
+	} else if guess, ok := wire.GuessBytes(f); ok {
+		s += "  ≈ " + guess.String()
+	}
+	return s
+}
+
+// explorer holds the state of an interactive session.
+type explorer struct {
+	data     []byte
+	roots    []*node
+	parseErr error
+
+	selected int // index into visible()
+	top      int // first visible row of the tree pane
+}
+
+// visible returns the nodes currently shown, in display order.
+func (x *explorer) visible() []*node {
+	var out []*node
+	var walk func([]*node)
+	walk = func(nodes []*node) {
+		for _, n := range nodes {
+			out = append(out, n)
+			if n.expanded {
+				walk(n.children)
+			}
+		}
+	}
+	walk(x.roots)
+	return out
+}
+
+// Keys understood by the explorer.
+const (
+	keyNone = iota
+	keyUp
+	keyDown
+	keyLeft
+	keyRight
+	keyToggle
+	keyExpandAll
+	keyCollapseAll
+	keyQuit
+)
+
+func readKey(in *bufio.Reader) (int, error) {
+	b, err := in.ReadByte()
+	if err != nil {
+		return keyNone, err
+	}
+	switch b {
+	case 'k':
+		return keyUp, nil
+	case 'j':
+		return keyDown, nil
+	case 'h':
+		return keyLeft, nil
+	case 'l':
+		return keyRight, nil
+	case '\r', ' ':
+		return keyToggle, nil
+	case 'E':
+		return keyExpandAll, nil
+	case 'C':
+		return keyCollapseAll, nil
+	case 'q', 3: // q or Ctrl-C
+		return keyQuit, nil
+	case 0x1b:
+		if in.Buffered() < 2 {
+			return keyQuit, nil // a lone Escape
+		}
+		seq := make([]byte, 2)
+		if _, err := in.Read(seq); err != nil {
+			return keyNone, err
+		}
+		if seq[0] != '[' {
+			return keyNone, nil
+		}
+		switch seq[1] {
+		case 'A':
+			return keyUp, nil
+		case 'B':
+			return keyDown, nil
+		case 'C':
+			return keyRight, nil
+		case 'D':
+			return keyLeft, nil
+		}
+	}
+	return keyNone, nil
+}
+
+// handle applies a key press and reports whether to keep running.
+func (x *explorer) handle(key int) bool {
+	nodes := x.visible()
+	cur := nodes[x.selected]
+	switch key {
+	case keyQuit:
+		return false
+	case keyUp:
+		x.selected = max(x.selected-1, 0)
+	case keyDown:
+		x.selected = min(x.selected+1, len(nodes)-1)
+	case keyRight:
+		if len(cur.children) > 0 {
+			if cur.expanded {
+				x.selected++
+			}
+			cur.expanded = true
+		}
+	case keyLeft:
+		if cur.expanded {
+			cur.expanded = false
+			break
+		}
+		// Jump to the parent.
+		for i := x.selected - 1; i >= 0; i-- {
+			if nodes[i].depth < cur.depth {
+				x.selected = i
+				break
+			}
+		}
+	case keyToggle:
+		if len(cur.children) > 0 {
+			cur.expanded = !cur.expanded
+		}
+	case keyExpandAll, keyCollapseAll:
+		var walk func([]*node)
+		walk = func(ns []*node) {
+			for _, n := range ns {
+				n.expanded = key == keyExpandAll && len(n.children) > 0
+				walk(n.children)
+			}
+		}
+		walk(x.roots)
+		x.selected = indexOf(x.visible(), cur)
+	}
+	return true
+}
+
+func indexOf(nodes []*node, n *node) int {
+	for i, m := range nodes {
+		if m == n {
+			return i
+		}
+	}
+	return 0
+}
+
+func (x *explorer) draw() {
+	width, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 || height <= 0 {
+		width, height = 80, 24
+	}
+	treeRows := max(height-hexRows-3, 3)
+
+	nodes := x.visible()
+	if x.selected < x.top {
+		x.top = x.selected
+	}
+	if x.selected >= x.top+treeRows {
+		x.top = x.selected - treeRows + 1
+	}
+
+	var b strings.Builder
+	b.WriteString(ansiClear)
+	for row := 0; row < treeRows; row++ {
+		i := x.top + row
+		if i < len(nodes) {
+			n := nodes[i]
+			marker := "  "
+			if len(n.children) > 0 {
+				marker = "▸ "
+				if n.expanded {
+					marker = "▾ "
+				}
+			}
+			line := truncate(strings.Repeat("  ", n.depth)+marker+n.label(), width)
+			if i == x.selected {
+				line = ansiReverse + line + ansiReset
+			}
+			b.WriteString(line)
+		}
+		b.WriteString("\r\n")
+	}
+
+	f := nodes[x.selected].field
+	status := fmt.Sprintf("bytes %d-%d: tag+length %d, value %d", f.Offset, f.End, f.ValueOffset-f.Offset, f.End-f.ValueOffset)
+	if x.parseErr != nil {
+		status += fmt.Sprintf("  (payload only partially parsed: %v)", x.parseErr)
+	}
+	b.WriteString(ansiDim + truncate(strings.Repeat("─", width), width) + ansiReset + "\r\n")
+	b.WriteString(truncate(status, width) + "\r\n")
+	x.drawHex(&b, f)
+	b.WriteString(ansiDim + "↑↓ move  → expand  ← collapse/parent  E/C expand/collapse all  q quit" + ansiReset)
+	fmt.Print(b.String())
+}
+
+// drawHex prints a hex dump around f, highlighting its tag and length
+// prefix and its value.
+func (x *explorer) drawHex(b *strings.Builder, f wire.Field) {
+	first := max(f.Offset/16-1, 0)
+	for row := first; row < first+hexRows; row++ {
+		start := row * 16
+		if start >= len(x.data) {
+			b.WriteString("\r\n")
+			continue
+		}
+		fmt.Fprintf(b, "%06x  ", start)
+		var ascii strings.Builder
+		for i := start; i < start+16; i++ {
+			if i >= len(x.data) {
+				b.WriteString("   ")
+				continue
+			}
+			style := ""
+			switch {
+			case i >= f.Offset && i < f.ValueOffset:
+				style = ansiTag
+			case i >= f.ValueOffset && i < f.End:
+				style = ansiReverse
+			}
+			c := x.data[i]
+			ch := "."
+			if c >= 0x20 && c < 0x7f {
+				ch = string(c)
+			}
+			if style != "" {
+				fmt.Fprintf(b, "%s%02x%s ", style, c, ansiReset)
+				ascii.WriteString(style + ch + ansiReset)
+			} else {
+				fmt.Fprintf(b, "%02x ", c)
+				ascii.WriteString(ch)
+			}
+		}
+		b.WriteString(" " + ascii.String() + "\r\n")
+	}
+}
+
+// truncate shortens s to at most width runes.
+func truncate(s string, width int) string {
+	r := []rune(s)
+	if len(r) <= width {
+		return s
+	}
+	return string(r[:max(width-1, 0)]) + "…"
+}
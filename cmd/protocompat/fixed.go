@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/example/protobuf-compat/internal/wire"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// fixedModes lists the renderings of fixed32 and fixed64 values: as
+// fixed32/fixed64, sfixed32/sfixed64, raw hex or float/double, or all four.
+var fixedModes = []string{"unsigned", "signed", "hex", "float", "all"}
+
+// fixedDisplay is the rendering describeValue uses for fixed-width fields.
+var fixedDisplay = "unsigned"
+
+// addFixedFlag registers the -fixed flag selecting fixedDisplay.
+func addFixedFlag(fs *flag.FlagSet) {
+	fs.Func("fixed", "render fixed32/fixed64 values as "+strings.Join(fixedModes, ", ")+" (default unsigned)", func(s string) error {
+		for _, m := range fixedModes {
+			if s == m {
+				fixedDisplay = s
+				return nil
+			}
+		}
+		return fmt.Errorf("want one of %s", strings.Join(fixedModes, ", "))
+	})
+}
+
+// formatFixed renders a fixed32 or fixed64 value according to fixedDisplay.
+func formatFixed(f wire.Field) string {
+	is32 := f.Type == protowire.Fixed32Type
+	signed := fmt.Sprint(int64(f.Varint))
+	hex := fmt.Sprintf("0x%016x", f.Varint)
+	float := fmt.Sprint(math.Float64frombits(f.Varint))
+	if is32 {
+		signed = fmt.Sprint(int32(f.Varint))
+		hex = fmt.Sprintf("0x%08x", f.Varint)
+		float = fmt.Sprint(math.Float32frombits(uint32(f.Varint)))
+	}
+	switch fixedDisplay {
+	case "signed":
+		return signed
+	case "hex":
+		return hex
+	case "float":
+		return float
+	case "all":
+		return fmt.Sprintf("%d (signed %s, %s, float %s)", f.Varint, signed, hex, float)
+	default:
+		return fmt.Sprint(f.Varint)
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// outputFormats are the values of the global -format flag. Commands list
+// the ones besides text they support when registering; the others are
+// rejected before the command runs.
+var outputFormats = []string{"text", "json", "ndjson", "yaml", "protoscope"}
+
+// outputFormat is the output format chosen with the global -format flag.
+var outputFormat = "text"
+
+// honors reports whether c supports output format f.
+func (c *command) honors(f string) bool {
+	if f == "text" {
+		return true
+	}
+	for _, g := range c.formats {
+		if g == f {
+			return true
+		}
+	}
+	return false
+}
+
+// printJSON prints v as indented JSON.
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// printNDJSON prints v as JSON on a single line, so that a stream of
+// results can be read a line at a time.
+func printNDJSON(v any) error {
+	return json.NewEncoder(os.Stdout).Encode(v)
+}
+
+// printStructured prints v as YAML for format "yaml", as a line of JSON
+// for "ndjson" and as indented JSON otherwise. Each value is a document of
+// its own, so commands may print several in a row.
+func printStructured(format string, v any) error {
+	switch format {
+	case "ndjson":
+		return printNDJSON(v)
+	case "yaml":
+	default:
+		return printJSON(v)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return printYAML(os.Stdout, data)
+}
+
+// printYAML prints the JSON document data as a YAML document, keeping
+// the order of object keys.
+func printYAML(w io.Writer, data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	n, err := readJSONNode(dec)
+	if err != nil {
+		return err
+	}
+	var b bytes.Buffer
+	b.WriteString("---\n")
+	if n.isInline() {
+		b.WriteString(n.inline() + "\n")
+	} else {
+		n.write(&b, 0)
+	}
+	_, err = w.Write(b.Bytes())
+	return err
+}
+
+// jsonNode is a JSON value read with its object keys in order.
+type jsonNode struct {
+	scalar any // string, json.Number or bool; nil for null and collections
+	null   bool
+	keys   []string    // of an object
+	values []*jsonNode // of an object, or the items of an array
+	array  bool
+}
+
+func readJSONNode(dec *json.Decoder) (*jsonNode, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	n := &jsonNode{}
+	switch tok {
+	case json.Delim('{'):
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			v, err := readJSONNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			n.keys = append(n.keys, key.(string))
+			n.values = append(n.values, v)
+		}
+		_, err = dec.Token()
+	case json.Delim('['):
+		n.array = true
+		for dec.More() {
+			v, err := readJSONNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			n.values = append(n.values, v)
+		}
+		_, err = dec.Token()
+	case nil:
+		n.null = true
+	default:
+		n.scalar = tok
+	}
+	return n, err
+}
+
+// isInline reports whether n is a scalar or an empty collection, which
+// YAML writes on the line of its key.
+func (n *jsonNode) isInline() bool {
+	return n.scalar != nil || n.null || len(n.values) == 0
+}
+
+// inline renders a scalar or an empty collection.
+func (n *jsonNode) inline() string {
+	switch {
+	case n.null:
+		return "null"
+	case n.scalar != nil:
+		if s, ok := n.scalar.(string); ok {
+			return yamlString(s)
+		}
+		return fmt.Sprint(n.scalar)
+	case n.array:
+		return "[]"
+	}
+	return "{}"
+}
+
+// write writes a non-empty collection in block style at the indentation
+// given. Collections in arrays start on the line of their dash.
+func (n *jsonNode) write(b *bytes.Buffer, indent int) {
+	pad := strings.Repeat(" ", indent)
+	for i, v := range n.values {
+		switch {
+		case n.array && v.isInline():
+			b.WriteString(pad + "- " + v.inline() + "\n")
+		case n.array:
+			start := b.Len()
+			v.write(b, indent+2)
+			item := b.String()[start:]
+			b.Truncate(start) // rewritten with the dash in place of the indentation
+			b.WriteString(pad + "- " + item[indent+2:])
+		case v.isInline():
+			b.WriteString(pad + yamlString(n.keys[i]) + ": " + v.inline() + "\n")
+		default:
+			b.WriteString(pad + yamlString(n.keys[i]) + ":\n")
+			v.write(b, indent+2)
+		}
+	}
+}
+
+// plainYAML matches strings YAML reads back as the same string unquoted.
+var plainYAML = regexp.MustCompile(`^[A-Za-z_/][A-Za-z0-9_./-]*$`)
+
+// yamlString renders s plain if that reads back as the same string, and
+// double-quoted, which YAML shares with JSON, otherwise.
+func yamlString(s string) string {
+	switch strings.ToLower(s) {
+	case "y", "n", "yes", "no", "on", "off", "true", "false", "null":
+	default:
+		if plainYAML.MatchString(s) {
+			return s
+		}
+	}
+	b, _ := json.Marshal(s)
+	return string(b)
+}
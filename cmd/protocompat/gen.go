@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/example/protobuf-compat/internal/sample"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+func init() {
+	register(&command{
+		name:    "gen",
+		summary: "generate sample payloads of a message type, with every field filled in",
+		run:     runGen,
+		formats: []string{"json", "ndjson", "yaml"},
+	})
+}
+
+// genJSON is a payload of gen -format json.
+type genJSON struct {
+	Binary  []byte          `json:"binary"`
+	Hex     string          `json:"hex"`
+	Decoded json.RawMessage `json:"decoded"`
+}
+
+func runGen(args []string) error {
+	const usage = "protocompat gen [-schema NAME] [-type MESSAGE] [-descriptor-set FILE] [-n N] [-seed N] [-as hex|json|binary] [-o DIR]"
+	fs := newFlagSet("gen")
+	name := fs.String("schema", "v1", "schema of the payloads, "+schemaHelp)
+	typ := addTypeFlag(fs, name)
+	loadSets := addDescriptorSetFlag(fs)
+	n := fs.Int("n", 1, "number of payloads to generate")
+	seed := fs.Uint64("seed", 1, "seed of the generator; the same seed generates the same payloads")
+	as := fs.String("as", "", "print only this form of the payloads: hex (one per line), json (one per line) or binary (length-delimited)")
+	dir := fs.String("o", "", "write each payload to `DIR` as NNN.binpb, NNN.json and NNN.hex instead")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 || *n < 1 {
+		return usageError(usage)
+	}
+	switch *as {
+	case "", "hex", "json", "binary":
+	default:
+		return withExitCode(exitUsage, fmt.Errorf("-as %q: want hex, json or binary", *as))
+	}
+	if *dir != "" && (*as != "" || outputFormat != "text") {
+		return withExitCode(exitUsage, errors.New("-o writes every form; it takes neither -as nor -format"))
+	}
+	if err := loadSets(); err != nil {
+		return err
+	}
+	mt, err := typ.lookup()
+	if err != nil {
+		return err
+	}
+
+	g := sample.New(*seed)
+	samples := make([]genJSON, *n)
+	msgs := make([]proto.Message, *n)
+	for i := range samples {
+		msg := g.Message(mt.Descriptor())
+		msgs[i] = msg
+		data, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+		if err != nil {
+			return err
+		}
+		marshaled, err := protojson.Marshal(msg)
+		if err != nil {
+			return err
+		}
+		// Compacted, protojson varying its spacing from run to run.
+		var decoded bytes.Buffer
+		if err := json.Compact(&decoded, marshaled); err != nil {
+			return err
+		}
+		samples[i] = genJSON{Binary: data, Hex: fmt.Sprintf("%X", data), Decoded: decoded.Bytes()}
+	}
+	logger.Debug("generated payloads", "message", mt.Descriptor().FullName(), "n", *n, "seed", *seed)
+
+	switch {
+	case *dir != "":
+		if err := os.MkdirAll(*dir, 0o755); err != nil {
+			return err
+		}
+		for i, s := range samples {
+			base := filepath.Join(*dir, fmt.Sprintf("%03d", i+1))
+			indented, err := protojson.MarshalOptions{Multiline: true}.Marshal(msgs[i])
+			if err != nil {
+				return err
+			}
+			for ext, data := range map[string][]byte{
+				".binpb": s.Binary,
+				".json":  append(indented, '\n'),
+				".hex":   []byte(s.Hex + "\n"),
+			} {
+				if err := writeFile(base+ext, data); err != nil {
+					return err
+				}
+			}
+		}
+		logger.Info("wrote payloads", "dir", *dir, "n", len(samples))
+		return nil
+	case outputFormat == "ndjson":
+		for _, s := range samples {
+			if err := printNDJSON(s); err != nil {
+				return err
+			}
+		}
+		return nil
+	case outputFormat != "text":
+		return printStructured(outputFormat, samples)
+	}
+
+	for i, s := range samples {
+		switch *as {
+		case "hex":
+			fmt.Println(s.Hex)
+		case "json":
+			fmt.Println(string(s.Decoded))
+		case "binary":
+			// Length-delimited, as read by protocompat delimited.
+			if _, err := os.Stdout.Write(protowire.AppendBytes(nil, s.Binary)); err != nil {
+				return err
+			}
+		default:
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Printf("# %s %d/%d, %d bytes\n", mt.Descriptor().FullName(), i+1, len(samples), len(s.Binary))
+			fmt.Println("hex:  " + s.Hex)
+			fmt.Println("json: " + string(s.Decoded))
+		}
+	}
+	return nil
+}
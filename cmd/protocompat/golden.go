@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/example/protobuf-compat/pkg/compat"
+)
+
+// goldenManifest is the file describing one version's directory of a
+// golden corpus.
+const goldenManifest = "golden.json"
+
+// goldenVersion is the layout of goldenManifest.
+type goldenVersion struct {
+	Schema  string `json:"schema"`
+	Message string `json:"message"`
+	Seed    uint64 `json:"seed"`
+	Count   int    `json:"count"`
+}
+
+// Files of a golden payload, after its index.
+const (
+	goldenBinary = ".binpb"
+	goldenJSON   = ".json"
+	goldenWant   = ".want"
+)
+
+func runCompatRecord(args []string) error {
+	fs := newFlagSet("compat record")
+	dir := fs.String("dir", "testdata/golden", "corpus directory")
+	as := fs.String("as", "", "version name to record under (default: SCHEMA without its extension)")
+	message := fs.String("message", "", "message to record, by name within its package or in full (default: the message of a schema name)")
+	n := fs.Int("n", 20, "number of payloads")
+	seed := fs.Uint64("seed", 1, "random seed")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return usageError("protocompat compat record [flags] SCHEMA (schema name, descriptor set, .proto files or grpc:// server)")
+	}
+	arg := fs.Arg(0)
+	name, err := messageName(*message, arg)
+	if err != nil {
+		return err
+	}
+	md, err := findMessage(arg, name)
+	if err != nil {
+		return err
+	}
+	goldens, err := compat.RecordGolden(md, *n, *seed)
+	if err != nil {
+		return err
+	}
+
+	version := *as
+	if version == "" {
+		version = strings.TrimSuffix(filepath.Base(arg), filepath.Ext(arg))
+	}
+	vdir := filepath.Join(*dir, version)
+	// Re-recording a version replaces its payloads rather than mixing in new ones.
+	if err := os.RemoveAll(vdir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(vdir, 0o755); err != nil {
+		return err
+	}
+	manifest, err := json.MarshalIndent(goldenVersion{Schema: arg, Message: name, Seed: *seed, Count: *n}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(vdir, goldenManifest), append(manifest, '\n'), 0o644); err != nil {
+		return err
+	}
+	for i, g := range goldens {
+		base := filepath.Join(vdir, fmt.Sprintf("%03d", i))
+		for ext, data := range map[string][]byte{
+			goldenBinary: g.Binary,
+			goldenJSON:   append(g.JSON, '\n'),
+			goldenWant:   []byte(g.Want + "\n"),
+		} {
+			if err := os.WriteFile(base+ext, data, 0o644); err != nil {
+				return err
+			}
+		}
+	}
+	fmt.Printf("✅ recorded %d %s payloads from %s in %s\n", *n, name, arg, vdir)
+	return nil
+}
+
+func runCompatReplay(args []string) error {
+	fs := newFlagSet("compat replay")
+	dir := fs.String("dir", "testdata/golden", "corpus directory")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return usageError("protocompat compat replay [flags] SCHEMA (the current schema name, descriptor set, .proto files or grpc:// server)")
+	}
+	manifests, err := filepath.Glob(filepath.Join(*dir, "*", goldenManifest))
+	if err != nil {
+		return err
+	}
+	if len(manifests) == 0 {
+		return fmt.Errorf("no recorded versions in %s (see compat record)", *dir)
+	}
+	sort.Strings(manifests)
+
+	var payloads, failed int
+	for _, path := range manifests {
+		vdir := filepath.Dir(path)
+		var v goldenVersion
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, &v); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		md, err := findMessage(fs.Arg(0), v.Message)
+		if err != nil {
+			return err
+		}
+		bad := 0
+		for i := 0; i < v.Count; i++ {
+			g, err := readGolden(filepath.Join(vdir, fmt.Sprintf("%03d", i)))
+			if err != nil {
+				return err
+			}
+			payloads++
+			problems := g.Replay(md)
+			if len(problems) == 0 {
+				continue
+			}
+			bad++
+			fmt.Printf("❌ %s/%03d\n", filepath.Base(vdir), i)
+			for _, p := range problems {
+				fmt.Printf("   %s\n", p)
+			}
+		}
+		if bad == 0 {
+			fmt.Printf("✅ %s: %d payloads decode as recorded\n", filepath.Base(vdir), v.Count)
+		}
+		failed += bad
+	}
+	if failed > 0 {
+		return incompatible(fmt.Errorf("%d of %d golden payloads no longer decode as recorded with %s", failed, payloads, fs.Arg(0)))
+	}
+	return nil
+}
+
+func readGolden(base string) (compat.Golden, error) {
+	var g compat.Golden
+	var err error
+	if g.Binary, err = os.ReadFile(base + goldenBinary); err != nil {
+		return g, err
+	}
+	if g.JSON, err = os.ReadFile(base + goldenJSON); err != nil {
+		return g, err
+	}
+	want, err := os.ReadFile(base + goldenWant)
+	if err != nil {
+		return g, err
+	}
+	g.Want = strings.TrimSuffix(string(want), "\n")
+	return g, nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/example/protobuf-compat/internal/schema"
+)
+
+func init() {
+	register(&command{
+		name:    "identify",
+		summary: "decode a payload with several schemas at once and pick the one that fits",
+		run:     runIdentify,
+	})
+}
+
+func runIdentify(args []string) error {
+	fs := newFlagSet("identify")
+	schemas := fs.String("schemas", strings.Join(schema.Aliases(), ","), "comma-separated candidate schemas")
+	tie := fs.String("tie", string(schema.FirstClean), "tie-break among clean decodes: first, order or most-fields")
+	timeout := fs.Duration("timeout", 5*time.Second, "give up after this long")
+	loadSets := addDescriptorSetFlag(fs)
+	pf := addPayloadFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := loadSets(); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return usageError("protocompat identify [flags] [payload]")
+	}
+	data, err := pf.read(fs.Args())
+	if err != nil {
+		return err
+	}
+	var candidates []schema.Candidate
+	for _, name := range strings.Split(*schemas, ",") {
+		mt, err := lookupSchema(strings.TrimSpace(name))
+		if err != nil {
+			return err
+		}
+		candidates = append(candidates, schema.Candidate{Name: strings.TrimSpace(name), Type: mt})
+	}
+
+	ctx, cancel := context.WithTimeout(rootContext, *timeout)
+	defer cancel()
+	winner, attempts, err := schema.Race(ctx, data, candidates, schema.TieBreak(*tie))
+	for _, a := range attempts {
+		switch {
+		case a.Err != nil:
+			fmt.Printf("❌ %-4s %v\n", a.Name, a.Err)
+		case a.Unknown > 0:
+			fmt.Printf("⚠️  %-4s %d fields, %d bytes of unknown fields (%v)\n", a.Name, a.Populated, a.Unknown, a.Elapsed)
+		default:
+			fmt.Printf("✅ %-4s %d fields, clean (%v)\n", a.Name, a.Populated, a.Elapsed)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if winner == nil {
+		return schemaMismatch(errors.New("no candidate schema decodes the payload cleanly"))
+	}
+	fmt.Printf("\nBest match: %s (%s)\n", winner.Name, winner.Type.Descriptor().FullName())
+	return nil
+}
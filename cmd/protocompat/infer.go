@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/example/protobuf-compat/internal/wire"
+)
+
+func init() {
+	register(&command{
+		name:    "infer",
+		summary: "generate a best-guess .proto message definition from payloads",
+		run:     runInfer,
+	})
+}
+
+func runInfer(args []string) error {
+	fs := newFlagSet("infer")
+	name := fs.String("name", "Inferred", "name of the generated message")
+	pkg := fs.String("package", "", "package declaration for the generated file")
+	format := fs.String("format", "proto", "output format: proto, or a diagram in "+strings.Join(wire.DiagramFormats, " or "))
+	decode := addEncodingFlag(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return usageError("protocompat infer [flags] <payload>...")
+	}
+
+	// Every payload is a sample of the same message; more samples give
+	// better guesses about which fields are repeated or optional.
+	samples := make([][]wire.Field, 0, fs.NArg())
+	for i, arg := range fs.Args() {
+		data, err := decode(arg)
+		if err != nil {
+			return fmt.Errorf("payload %d: %w", i+1, err)
+		}
+		fields, err := wire.Parse(data)
+		if err != nil {
+			return parseFailure(fmt.Errorf("payload %d: %w", i+1, err))
+		}
+		samples = append(samples, fields)
+	}
+	if *format == "proto" {
+		fmt.Print(wire.InferProto(*pkg, *name, samples...))
+		return nil
+	}
+	out, err := wire.InferDiagram(*format, *name, samples...)
+	if err != nil {
+		return err
+	}
+	fmt.Print(out)
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/example/protobuf-compat/internal/kube"
+)
+
+func init() {
+	register(&command{
+		name:    "k8s",
+		summary: "decode protobuf payloads stored in ConfigMaps, Secrets or custom resources",
+		run:     runK8s,
+	})
+}
+
+// addKubeFlags registers the API server connection flags.
+func addKubeFlags(fs *flag.FlagSet) func() (*kube.Client, error) {
+	server := fs.String("server", "", "API server URL (default: in-cluster service account)")
+	token := fs.String("token", "", "bearer token")
+	tokenFile := fs.String("token-file", "", "file containing a bearer token")
+	caFile := fs.String("ca-file", "", "CA bundle for the API server certificate")
+	insecure := fs.Bool("insecure-skip-tls-verify", false, "do not verify the API server certificate")
+	newAuth := addAuthFlag(fs)
+	return func() (*kube.Client, error) {
+		cfg := kube.Config{Server: *server, Token: *token, TokenFile: *tokenFile, CAFile: *caFile, Insecure: *insecure}
+		if cfg.Server == "" {
+			inCluster, err := kube.InClusterConfig()
+			if err != nil {
+				return nil, fmt.Errorf("%w (pass -server, e.g. the address of \"kubectl proxy\")", err)
+			}
+			cfg.Server = inCluster.Server
+			// The service account credentials stand in only for those not
+			// given on the command line.
+			if cfg.Token == "" && cfg.TokenFile == "" && cfg.CAFile == "" {
+				cfg.TokenFile, cfg.CAFile = inCluster.TokenFile, inCluster.CAFile
+			}
+		}
+		var err error
+		if cfg.Auth, err = newAuth(); err != nil {
+			return nil, err
+		}
+		return kube.NewClient(cfg)
+	}
+}
+
+func runK8s(args []string) error {
+	fs := newFlagSet("k8s")
+	namespace := fs.String("n", "default", "namespace")
+	timeout := fs.Duration("timeout", 30*time.Second, "request timeout")
+	newClient := addKubeFlags(fs)
+	sf := addSchemaFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: protocompat k8s [flags] REF...")
+		fmt.Fprintln(fs.Output(), "\nREF is configmap/NAME[:KEY], secret/NAME[:KEY] or GROUP/VERSION/PLURAL/NAME:FIELD.PATH")
+		fmt.Fprintln(fs.Output(), "\nFlags:")
+		fs.PrintDefaults()
+	}
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return withExitCode(exitUsage, errors.New("no object references given"))
+	}
+	if err := sf.resolve(); err != nil {
+		return err
+	}
+	refs := make([]kube.Ref, fs.NArg())
+	for i, arg := range fs.Args() {
+		ref, err := kube.ParseRef(arg)
+		if err != nil {
+			return withExitCode(exitUsage, err)
+		}
+		refs[i] = ref
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(rootContext, *timeout)
+	defer cancel()
+
+	for _, ref := range refs {
+		payloads, err := client.Fetch(ctx, *namespace, ref)
+		if err != nil {
+			return err
+		}
+		for _, p := range payloads {
+			fmt.Printf("%s (%d bytes)\n", p.Source, len(p.Data))
+			sf.printDecoded(p.Data)
+			fmt.Println()
+		}
+	}
+	return sf.strictError()
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/example/protobuf-compat/internal/auth"
+	"github.com/example/protobuf-compat/internal/kv"
+)
+
+func init() {
+	register(&command{
+		name:    "kv",
+		summary: "decode protobuf values stored under an etcd or Consul key prefix",
+		run:     runKV,
+	})
+}
+
+func runKV(args []string) error {
+	fs := newFlagSet("kv")
+	backend := fs.String("backend", "etcd", "key/value store: etcd or consul")
+	endpoint := fs.String("endpoint", "http://127.0.0.1:2379", "store HTTP endpoint")
+	prefix := fs.String("prefix", "", "key prefix to list")
+	timeout := fs.Duration("timeout", 30*time.Second, "request timeout")
+	newAuth := addAuthFlag(fs)
+	sf := addSchemaFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *prefix == "" {
+		return withExitCode(exitUsage, errors.New("-prefix is required"))
+	}
+	if err := sf.resolve(); err != nil {
+		return err
+	}
+
+	provider, err := newAuth()
+	if err != nil {
+		return err
+	}
+	store, err := kv.New(*backend, *endpoint, auth.Client(provider))
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(rootContext, *timeout)
+	defer cancel()
+	entries, err := store.List(ctx, *prefix)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d keys under %q\n\n", len(entries), *prefix)
+
+	for _, e := range entries {
+		fmt.Printf("%s (revision %d, %d bytes)\n", e.Key, e.Revision, len(e.Value))
+		sf.printDecoded(e.Value)
+		fmt.Println()
+	}
+	return sf.strictError()
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// logger reports diagnostics on stderr, leaving stdout to results. The
+// global -verbose and -quiet flags set its level and -log-format its
+// format.
+var logger = slog.New(newTextHandler(os.Stderr, slog.LevelInfo))
+
+// logFormats are the values of the global -log-format flag.
+var logFormats = []string{"text", "json"}
+
+// setupLogger replaces logger according to the global logging flags.
+func setupLogger(format string, verbose, quiet bool) error {
+	level := slog.LevelInfo
+	switch {
+	case verbose && quiet:
+		return fmt.Errorf("-verbose and -quiet are exclusive")
+	case verbose:
+		level = slog.LevelDebug
+	case quiet:
+		level = slog.LevelError
+	}
+	switch format {
+	case "text":
+		logger = slog.New(newTextHandler(os.Stderr, level))
+	case "json":
+		logger = slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+	default:
+		return fmt.Errorf("unknown log format %q (want %s)", format, strings.Join(logFormats, ", "))
+	}
+	return nil
+}
+
+// textHandler writes records as a line each, in the style of the errors
+// commands report:
+//
+//	protocompat decode: warning: payload only partially parsed file=a.bin
+//
+// The command attribute, if any, follows the program name; the others
+// follow the message as key=value pairs.
+type textHandler struct {
+	mu    *sync.Mutex
+	w     io.Writer
+	level slog.Leveler
+	attrs []slog.Attr
+	group string // prefix of the keys of attributes added
+}
+
+func newTextHandler(w io.Writer, level slog.Leveler) *textHandler {
+	return &textHandler{mu: new(sync.Mutex), w: w, level: level}
+}
+
+func (h *textHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *textHandler) Handle(_ context.Context, r slog.Record) error {
+	prefix := "protocompat"
+	var b strings.Builder
+	add := func(a slog.Attr) {
+		if a.Equal(slog.Attr{}) {
+			return
+		}
+		if a.Key == "command" {
+			prefix += " " + a.Value.String()
+			return
+		}
+		b.WriteString(" " + a.Key + "=" + logValue(a.Value.Resolve()))
+	}
+	for _, a := range h.attrs {
+		add(a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		a.Key = h.group + a.Key
+		add(a)
+		return true
+	})
+	var level string
+	switch {
+	case r.Level >= slog.LevelError:
+	case r.Level >= slog.LevelWarn:
+		level = "warning: "
+	case r.Level < slog.LevelInfo:
+		level = "debug: "
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	activeProgress.clear()
+	_, err := fmt.Fprintf(h.w, "%s: %s%s%s\n", prefix, level, r.Message, b.String())
+	return err
+}
+
+func (h *textHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.attrs = append([]slog.Attr(nil), h.attrs...)
+	for _, a := range attrs {
+		a.Key = h.group + a.Key
+		h2.attrs = append(h2.attrs, a)
+	}
+	return &h2
+}
+
+func (h *textHandler) WithGroup(name string) slog.Handler {
+	h2 := *h
+	h2.group += name + "."
+	return &h2
+}
+
+// logValue renders v, quoted if it has spaces, quotes or '='.
+func logValue(v slog.Value) string {
+	s := v.String()
+	if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+		return strconv.Quote(s)
+	}
+	return s
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/example/protobuf-compat/internal/kube"
+)
+
+func init() {
+	register(&command{
+		name:    "logs",
+		summary: "extract and decode payloads from the logs of matching pods",
+		run:     runLogs,
+	})
+}
+
+// maxLogLine bounds a single log line; payload dumps can be long.
+const maxLogLine = 4 << 20
+
+func runLogs(args []string) error {
+	fs := newFlagSet("logs")
+	namespace := fs.String("n", "default", "namespace")
+	selector := fs.String("l", "", "label selector of the pods to read, e.g. app=executor")
+	container := fs.String("c", "", "only read this container (default: all containers)")
+	pattern := fs.String("regex", `payload=([0-9A-Za-z+/_=-]+)`, "extraction regex; the first capture group (or whole match) is the payload")
+	decode := addEncodingFlag(fs)
+	follow := fs.Bool("follow", true, "keep streaming new log lines")
+	since := fs.Duration("since", 0, "only read lines newer than this (e.g. 10m)")
+	newClient := addKubeFlags(fs)
+	sf := addSchemaFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *selector == "" {
+		return withExitCode(exitUsage, errors.New("-l is required"))
+	}
+	re, err := regexp.Compile(*pattern)
+	if err != nil {
+		return withExitCode(exitUsage, fmt.Errorf("bad -regex: %w", err))
+	}
+	if err := sf.resolve(); err != nil {
+		return err
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(rootContext)
+	defer cancel()
+
+	pods, err := client.Pods(ctx, *namespace, *selector)
+	if err != nil {
+		return err
+	}
+	if len(pods) == 0 {
+		return fmt.Errorf("no pods match %q in namespace %s", *selector, *namespace)
+	}
+
+	opts := kube.LogOptions{Follow: *follow, SinceSeconds: int64(since.Seconds())}
+	s := &logScraper{re: re, decode: decode, schema: sf}
+	var wg sync.WaitGroup
+	errs := make(chan error, 1)
+	var openErr error
+pods:
+	for _, pod := range pods {
+		for _, ctr := range pod.Containers {
+			if *container != "" && ctr != *container {
+				continue
+			}
+			stream, err := client.Logs(ctx, *namespace, pod.Name, ctr, opts)
+			if err != nil {
+				// Stop the streams already open, and let them finish.
+				openErr = err
+				cancel()
+				break pods
+			}
+			wg.Add(1)
+			go func(source string) {
+				defer wg.Done()
+				defer stream.Close()
+				if err := s.scan(source, stream); err != nil && ctx.Err() == nil {
+					select {
+					case errs <- fmt.Errorf("%s: %w", source, err):
+					default:
+					}
+				}
+			}(pod.Name + "/" + ctr)
+		}
+	}
+	wg.Wait()
+	if openErr != nil {
+		return openErr
+	}
+	select {
+	case err := <-errs:
+		return err
+	default:
+		return sf.strictError()
+	}
+}
+
+// logScraper extracts payloads from log lines and prints their decodes.
+// Streams from several containers share it, so output is serialised.
+type logScraper struct {
+	re     *regexp.Regexp
+	decode func(string) ([]byte, error)
+	schema *schemaFlags
+
+	mu sync.Mutex
+}
+
+func (s *logScraper) scan(source string, r io.Reader) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), maxLogLine)
+	for line := 1; sc.Scan(); line++ {
+		for _, m := range s.re.FindAllStringSubmatch(sc.Text(), -1) {
+			blob := m[0]
+			if len(m) > 1 {
+				blob = m[1]
+			}
+			s.print(source, line, blob)
+		}
+	}
+	return sc.Err()
+}
+
+func (s *logScraper) print(source string, line int, blob string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Printf("%s %s line %d\n", time.Now().Format(time.TimeOnly), source, line)
+	data, err := s.decode(blob)
+	if err != nil {
+		fmt.Printf("  ❌ %v\n\n", err)
+		return
+	}
+	s.schema.printDecoded(data)
+	fmt.Println()
+}
@@ -0,0 +1,262 @@
+// Command protocompat inspects protobuf payloads and checks how they decode
+// under different schema versions.
+//
+// Usage:
+//
+//	protocompat [-config FILE] [-format text|json|ndjson|yaml|protoscope] [-verbose|-quiet] [-log-format text|json] [-out FILE]
+//	            [-time-format FORMAT] [-tz ZONE] <command> [flags]
+//
+// Run "protocompat help" for the list of commands.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/example/protobuf-compat/internal/config"
+	"github.com/example/protobuf-compat/internal/wire"
+)
+
+// command is a single protocompat subcommand.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+	formats []string // output formats supported besides text
+}
+
+var commands = map[string]*command{}
+
+// rootContext is cancelled when protocompat is interrupted or terminated,
+// for long-running work, such as batches, streams and fetches, to stop on.
+// Work that does not notice goes on; a second interrupt kills it.
+var rootContext = context.Background()
+
+// exitInterrupted is the status of a command stopped by an interrupt, as
+// shells report a process killed by SIGINT.
+const exitInterrupted = 130
+
+// register adds a subcommand; each command file registers itself in init.
+func register(c *command) {
+	if _, dup := commands[c.name]; dup {
+		panic("duplicate command " + c.name)
+	}
+	commands[c.name] = c
+}
+
+func main() {
+	global := flag.NewFlagSet("protocompat", flag.ContinueOnError)
+	global.Usage = usage
+	global.StringVar(&outputFormat, "format", "text", "output format: "+strings.Join(outputFormats, ", "))
+	configPath := global.String("config", "", "read default flag values from this file (default "+config.Name+" if present)")
+	verbose := global.Bool("verbose", false, "also log debugging details, such as the files and services used")
+	quiet := global.Bool("quiet", false, "log errors only")
+	timeFormat := global.String("time-format", "rfc3339", "how to render times: rfc3339, unix, unix-ms, unix-us, unix-ns or a Go time layout such as \"2006-01-02 15:04:05 MST\"")
+	tz := global.String("tz", "UTC", "time zone to render times in: UTC, Local or a name such as Europe/Paris")
+	global.StringVar(&outPath, "out", "", "write results to this file, replacing it only once complete, instead of to stdout")
+	logFormat := global.String("log-format", "text", "format of the diagnostics logged to stderr: "+strings.Join(logFormats, ", "))
+	if err := global.Parse(os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		os.Exit(2)
+	}
+	if err := loadConfig(*configPath, global); err != nil {
+		logger.Error(err.Error())
+		os.Exit(2)
+	}
+	if err := setupLogger(*logFormat, *verbose, *quiet); err != nil {
+		logger.Error(err.Error())
+		os.Exit(2)
+	}
+	if cfg != nil {
+		logger.Debug("read configuration", "file", cfg.Path)
+	}
+	times, err := wire.ParseTimeFormat(*timeFormat, *tz)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(2)
+	}
+	wire.Times = times
+	if global.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+	name, args := global.Arg(0), global.Args()[1:]
+	if name == "help" {
+		usage()
+		return
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		cmd = pluginCommand(name)
+		ok = cmd != nil
+	}
+	if !ok {
+		logger.Error(fmt.Sprintf("unknown command %q", name))
+		usage()
+		os.Exit(2)
+	}
+	if !cmd.honors(outputFormat) {
+		if !slices.Contains(outputFormats, outputFormat) {
+			logger.Error(fmt.Sprintf("unknown format %q (want %s)", outputFormat, strings.Join(outputFormats, ", ")))
+		} else {
+			logger.Error(fmt.Sprintf("-format %s is not supported (want %s)", outputFormat, strings.Join(append([]string{"text"}, cmd.formats...), ", ")), "command", name)
+		}
+		os.Exit(2)
+	}
+	logger = logger.With("command", name)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	context.AfterFunc(ctx, stop)
+	rootContext = ctx
+	if err := writeOutput(func() error { return cmd.run(args) }); err != nil {
+		var pe pluginExit
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		} else if errors.Is(err, context.Canceled) && ctx.Err() != nil {
+			logger.Error("interrupted")
+			os.Exit(exitInterrupted)
+		} else if !errors.As(err, &pe) {
+			logger.Error(err.Error())
+		}
+		os.Exit(exitCode(err))
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: protocompat [-config FILE] [-format %s] [-verbose|-quiet] [-log-format %s] [-out FILE] [-time-format FORMAT] [-tz ZONE] <command> [flags]\n",
+		strings.Join(outputFormats, "|"), strings.Join(logFormats, "|"))
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	for _, name := range sortedCommandNames() {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", name, commands[name].summary)
+	}
+	if found := plugins(); len(found) > 0 {
+		fmt.Fprintln(os.Stderr, "\nPlugins:")
+		for _, name := range sortedKeys(found) {
+			fmt.Fprintf(os.Stderr, "  %-12s %s\n", name, found[name])
+		}
+	}
+}
+
+func sortedCommandNames() []string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// cfg holds the settings of the configuration file, if there is one.
+var cfg *config.Config
+
+// loadConfig reads the configuration file at path, or config.Name in the
+// current directory if path is empty and it exists, and applies its
+// settings of global flags, such as format or verbose, unless set on the
+// command line.
+func loadConfig(path string, global *flag.FlagSet) error {
+	if path == "" {
+		if _, err := os.Stat(config.Name); err != nil {
+			return nil
+		}
+		path = config.Name
+	}
+	var err error
+	if cfg, err = config.Load(path); err != nil {
+		return err
+	}
+	for name := range cfg.Commands {
+		cmd, sub, _ := strings.Cut(name, " ")
+		_, ok := commands[cmd]
+		if cmd == "compat" && sub != "" {
+			_, ok = compatCommands[sub]
+		} else if cmd == "types" && sub != "" {
+			ok = sub == "list"
+		} else if sub != "" {
+			ok = false
+		} else if !ok {
+			ok = pluginCommand(cmd) != nil
+		}
+		if !ok {
+			return fmt.Errorf("%s: no command %q", path, name)
+		}
+	}
+	set := make(map[string]bool)
+	global.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	for _, name := range globalFlags {
+		if v := cfg.Defaults[name]; len(v) > 0 && !set[name] {
+			if err := setFlag(global, name, v[len(v)-1:]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// globalFlags are the flags given before the command that the
+// configuration file may set.
+var globalFlags = []string{"format", "verbose", "quiet", "log-format", "out", "time-format", "tz"}
+
+// parseFlags parses the flags of a command after setting those the
+// configuration file sets, for every command or for this one.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if cfg != nil {
+		name := strings.TrimPrefix(fs.Name(), "protocompat ")
+		for _, key := range slices.Sorted(maps.Keys(cfg.Defaults)) {
+			// Global flags were set before the command, and the global
+			// -format is what commands default their own to.
+			if slices.Contains(globalFlags, key) || fs.Lookup(key) == nil {
+				continue
+			}
+			if err := setFlag(fs, key, cfg.Defaults[key]); err != nil {
+				return err
+			}
+		}
+		settings := cfg.Commands[name]
+		for _, key := range slices.Sorted(maps.Keys(settings)) {
+			if fs.Lookup(key) == nil {
+				return withExitCode(exitUsage, fmt.Errorf("%s: %s has no flag -%s", cfg.Path, name, key))
+			}
+			if err := setFlag(fs, key, settings[key]); err != nil {
+				return err
+			}
+		}
+	}
+	err := fs.Parse(args)
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		return withExitCode(exitUsage, err)
+	}
+	return err
+}
+
+func setFlag(fs *flag.FlagSet, name string, values []string) error {
+	for _, v := range values {
+		if err := fs.Set(name, v); err != nil {
+			return withExitCode(exitUsage, fmt.Errorf("%s: -%s %s: %v", cfg.Path, name, v, err))
+		}
+	}
+	return nil
+}
+
+// newFlagSet returns a flag set for a subcommand that reports errors
+// instead of exiting, so run functions can return them.
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("protocompat "+name, flag.ContinueOnError)
+	if flagSetCreated != nil {
+		flagSetCreated(fs)
+	}
+	return fs
+}
+
+// flagSetCreated, if set, is called with each flag set newFlagSet
+// creates, for completion to learn the flags of commands.
+var flagSetCreated func(fs *flag.FlagSet)
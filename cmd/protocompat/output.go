@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// outPath is the file the global -out flag writes results to instead of
+// stdout, or "" for stdout.
+var outPath string
+
+// writeOutput calls run with stdout redirected to a temporary file next to
+// outPath, if set, and renames it over outPath only if run succeeds, so
+// that a failed or interrupted run never leaves a partial result where
+// later steps look for one. Diagnostics still go to stderr.
+func writeOutput(run func() error) error {
+	if outPath == "" {
+		return run()
+	}
+	stdout := os.Stdout
+	err := writeFileAtomic(outPath, func(w io.Writer) error {
+		os.Stdout = w.(*os.File)
+		defer func() { os.Stdout = stdout }()
+		return run()
+	})
+	if err == nil {
+		logger.Debug("wrote output", "file", outPath)
+	}
+	return err
+}
+
+// writeFileAtomic creates the file name with the contents write writes,
+// replacing it only once they are complete: write writes to a temporary
+// file in the same directory, which is then renamed to name, or removed if
+// write or anything else fails.
+func writeFileAtomic(name string, write func(w io.Writer) error) (err error) {
+	f, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+	if err := write(f); err != nil {
+		return err
+	}
+	// CreateTemp creates files readable by the owner only.
+	if err := f.Chmod(0o644); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), name); err != nil {
+		return fmt.Errorf("writing %s: %w", name, err)
+	}
+	return nil
+}
+
+// writeFile is os.WriteFile, written with writeFileAtomic.
+func writeFile(name string, data []byte) error {
+	return writeFileAtomic(name, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
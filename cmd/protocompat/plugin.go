@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/example/protobuf-compat/pkg/wireanalyze"
+)
+
+// pluginPrefix starts the names of the executables on the PATH that are
+// run as the commands named by the rest of their name, as kubectl runs
+// its plugins: protocompat-lint is run for "protocompat lint".
+const pluginPrefix = "protocompat-"
+
+// plugins returns the paths of the plugins on the PATH by command name.
+// Those shadowed by commands of their name, or by plugins earlier on the
+// PATH, are left out.
+func plugins() map[string]string {
+	found := make(map[string]string)
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		entries, err := os.ReadDir(pathDir(dir))
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			name, ok := strings.CutPrefix(e.Name(), pluginPrefix)
+			name = strings.TrimSuffix(name, filepath.Ext(name)) // .exe
+			if !ok || name == "" || commands[name] != nil || found[name] != "" {
+				continue
+			}
+			if path, err := exec.LookPath(filepath.Join(pathDir(dir), e.Name())); err == nil {
+				found[name] = path
+			}
+		}
+	}
+	return found
+}
+
+// pathDir is dir as a directory of the PATH, where "" means the current
+// one.
+func pathDir(dir string) string {
+	if dir == "" {
+		return "."
+	}
+	return dir
+}
+
+// pluginCommand returns the command running the plugin for name, or nil
+// if there is none.
+func pluginCommand(name string) *command {
+	if strings.ContainsAny(name, `/\`) {
+		return nil
+	}
+	path, err := exec.LookPath(pluginPrefix + name)
+	if err != nil {
+		return nil
+	}
+	return &command{
+		name:    name,
+		summary: "plugin " + path,
+		run:     func(args []string) error { return runPlugin(name, path, args) },
+		// The global -format is passed on for the plugin to honor.
+		formats: outputFormats[1:],
+	}
+}
+
+// pluginInput is the JSON document plugins read on stdin: the payload
+// given with the payload flags, its wire-format structure and, with a
+// schema, the message decoded.
+type pluginInput struct {
+	Payload []byte              `json:"payload"`
+	Fields  []wireanalyze.Field `json:"fields"`
+	Schema  string              `json:"schema,omitempty"`
+	Message string              `json:"message,omitempty"`
+	Decoded json.RawMessage     `json:"decoded,omitempty"`
+	// Error is why the payload could not be parsed or decoded, the fields
+	// and message holding what could be.
+	Error string `json:"error,omitempty"`
+}
+
+// runPlugin runs the plugin at path for command name. The flags before
+// the plugin's own arguments, or before "--" if its arguments start with
+// flags, are those of decode; the payload they give, if any, is decoded
+// and passed to the plugin on stdin as a pluginInput.
+func runPlugin(name, path string, args []string) error {
+	fs := newFlagSet(name)
+	sf := addSchemaFlags(fs)
+	pf := addPayloadFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *sf.diffName != "" {
+		return withExitCode(exitUsage, errors.New("plugins take a single schema; -diff is for decode"))
+	}
+	cmd := exec.Command(path, fs.Args()...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	cmd.Env = append(os.Environ(), "PROTOCOMPAT_FORMAT="+outputFormat)
+	if self, err := os.Executable(); err == nil {
+		cmd.Env = append(cmd.Env, "PROTOCOMPAT="+self)
+	}
+
+	data, err := pf.read(nil)
+	switch {
+	case errors.Is(err, errNoPayload):
+		logger.Debug("running plugin without a payload", "plugin", path)
+	case err != nil:
+		return err
+	default:
+		if err := sf.resolve(); err != nil {
+			return err
+		}
+		in, err := json.Marshal(decodeForPlugin(sf, data))
+		if err != nil {
+			return err
+		}
+		cmd.Stdin = bytes.NewReader(append(in, '\n'))
+		logger.Debug("running plugin", "plugin", path, "bytes", len(data))
+	}
+
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 {
+		return pluginExit(exitErr.ExitCode())
+	}
+	return err
+}
+
+func decodeForPlugin(sf *schemaFlags, data []byte) *pluginInput {
+	in := &pluginInput{Payload: data}
+	fields, err := wireanalyze.Options{}.Fields(data)
+	in.Fields = fields
+	if err != nil {
+		in.Error = err.Error()
+	}
+	if sf.msgType == nil {
+		return in
+	}
+	in.Schema = *sf.name
+	in.Message = string(sf.msgType.Descriptor().FullName())
+	decoded, err := decodedValue(sf, data)
+	if err != nil {
+		in.Error = err.Error()
+		return in
+	}
+	in.Decoded = decoded.(json.RawMessage)
+	return in
+}
+
+// pluginExit is the status of a plugin that failed, which protocompat
+// exits with in turn. The plugin reported why itself.
+type pluginExit int
+
+func (e pluginExit) Error() string {
+	return fmt.Sprintf("plugin exited with status %d", int(e))
+}
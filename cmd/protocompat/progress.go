@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// Progress is shown only once a batch has run for progressDelay, so that
+// short ones print nothing; on a terminal it is redrawn at most every
+// progressRedraw, and otherwise logged every progressInterval.
+const (
+	progressDelay    = time.Second
+	progressRedraw   = 100 * time.Millisecond
+	progressInterval = 10 * time.Second
+)
+
+// progress reports on stderr how far a batch of payloads has got: the
+// payloads processed and failed and, if the size of the batch is known,
+// the share done and the time left. On a terminal it is a line redrawn in
+// place, otherwise a log line now and then; -quiet turns it off.
+type progress struct {
+	total  int64 // payloads, or bytes if bytes is set; 0 if unknown
+	bytes  bool
+	done   int64 // payloads, or bytes, processed
+	count  int   // payloads processed
+	failed int
+
+	start, last time.Time
+	bar         bool // draw a bar rather than log lines
+	shown       bool // the bar is on screen
+}
+
+// activeProgress is the progress of the batch being read, if any, for
+// the code processing its payloads to count failures with.
+var activeProgress *progress
+
+// startProgress starts reporting the progress of a batch of total
+// payloads or, with bytes, of total bytes; 0 if unknown.
+func startProgress(total int64, bytes bool) *progress {
+	_, text := logger.Handler().(*textHandler)
+	now := time.Now()
+	p := &progress{
+		total: total,
+		bytes: bytes,
+		start: now,
+		last:  now,
+		bar:   text && term.IsTerminal(int(os.Stderr.Fd())),
+	}
+	activeProgress = p
+	return p
+}
+
+// step records a payload of size bytes processed, and failed if it did.
+func (p *progress) step(size int64, failed bool) {
+	p.count++
+	if p.bytes {
+		p.done += size
+	} else {
+		p.done++
+	}
+	if failed {
+		p.failed++
+	}
+	now := time.Now()
+	switch {
+	case now.Sub(p.start) < progressDelay || !logger.Enabled(context.Background(), slog.LevelInfo):
+	case p.bar && now.Sub(p.last) >= progressRedraw:
+		fmt.Fprintf(os.Stderr, "\r%s\x1b[K", p.line(true))
+		p.shown, p.last = true, now
+	case !p.bar && now.Sub(p.last) >= progressInterval:
+		logger.Info(p.line(false))
+		p.last = now
+	}
+}
+
+// fail counts a payload that was read but failed to process.
+func (p *progress) fail() {
+	if p != nil {
+		p.failed++
+	}
+}
+
+// finish stops reporting, removing the bar.
+func (p *progress) finish() {
+	p.clear()
+	activeProgress = nil
+}
+
+// clear removes the bar, if shown, for other output to take its line. It
+// is drawn again on the next step.
+func (p *progress) clear() {
+	if p != nil && p.shown {
+		fmt.Fprint(os.Stderr, "\r\x1b[K")
+		p.shown = false
+	}
+}
+
+// line describes the progress, starting with a bar if asked to and the
+// size of the batch is known.
+func (p *progress) line(bar bool) string {
+	var b strings.Builder
+	if p.total > 0 {
+		share := min(float64(p.done)/float64(p.total), 1)
+		if bar {
+			const width = 20
+			n := int(share * width)
+			b.WriteString("[" + strings.Repeat("=", n) + strings.Repeat(" ", width-n) + "] ")
+		}
+		fmt.Fprintf(&b, "%.0f%% ", 100*share)
+	}
+	fmt.Fprintf(&b, "%d payloads, %d failed", p.count, p.failed)
+	if p.total > 0 && p.done > 0 {
+		elapsed := time.Since(p.start)
+		left := time.Duration(float64(elapsed) * float64(p.total-p.done) / float64(p.done))
+		fmt.Fprintf(&b, ", %s left", max(left, 0).Round(time.Second))
+	}
+	return b.String()
+}
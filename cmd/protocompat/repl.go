@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/example/protobuf-compat/internal/payload"
+	"github.com/example/protobuf-compat/internal/source"
+	"golang.org/x/term"
+)
+
+func init() {
+	register(&command{
+		name:    "repl",
+		summary: "decode and analyze pasted payloads interactively, switching schemas as you go",
+		run:     runREPL,
+	})
+}
+
+// replHelp lists the commands of the REPL.
+const replHelp = `Paste a hex or base64 payload to decode it with the active schema.
+Commands, where PAYLOAD is optional and defaults to the last one given:
+  decode [PAYLOAD] [FLAGS]    decode with the active schema (flags as for protocompat decode)
+  analyze [PAYLOAD] [FLAGS]   show the wire-format structure (flags as for protocompat analyze)
+  diff SCHEMA [PAYLOAD]       decode with the active schema and SCHEMA, and show what changes
+  schema [SPEC | -]           show or set the active schema; - for none
+  type [MESSAGE | -]          show or set the message of the active schema; - for its only one
+  payload                     show the last payload
+  history                     list the lines entered
+  help                        show this help
+  quit                        leave (or Ctrl-D)
+`
+
+func runREPL(args []string) error {
+	fs := newFlagSet("repl")
+	sf := addSchemaFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 || *sf.diffName != "" {
+		return usageError("protocompat repl [-schema NAME] [-type MESSAGE] [-descriptor-set FILE]")
+	}
+	if err := sf.resolve(); err != nil {
+		return err
+	}
+	r := &repl{schema: *sf.name, typ: *sf.typ.name}
+	if r.typ != "" {
+		// -type alone names a linked-in or -descriptor-set type.
+		r.schema = ""
+		fs.Visit(func(f *flag.Flag) {
+			if f.Name == "schema" {
+				r.schema = *sf.name
+			}
+		})
+	}
+
+	// On a terminal, lines can be edited and recalled with the arrow keys;
+	// otherwise they are read as they come, for scripted sessions.
+	var readLine func() (string, error)
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		t := term.NewTerminal(struct {
+			io.Reader
+			io.Writer
+		}{os.Stdin, os.Stdout}, "")
+		if width, height, err := term.GetSize(int(os.Stdout.Fd())); err == nil && width > 0 {
+			t.SetSize(width, height)
+		}
+		readLine = func() (string, error) {
+			// Raw mode only while reading, so commands print as usual.
+			old, err := term.MakeRaw(int(os.Stdin.Fd()))
+			if err != nil {
+				return "", err
+			}
+			defer term.Restore(int(os.Stdin.Fd()), old)
+			t.SetPrompt(r.prompt())
+			line, err := t.ReadLine()
+			if err == io.EOF {
+				fmt.Print("\r\n") // past the prompt
+			}
+			return line, err
+		}
+		fmt.Println(`protocompat repl: paste a payload, or type "help"`)
+	} else {
+		sc := bufio.NewScanner(os.Stdin)
+		sc.Buffer(make([]byte, 64*1024), maxLogLine)
+		readLine = func() (string, error) {
+			if !sc.Scan() {
+				return "", cmp.Or(sc.Err(), io.EOF)
+			}
+			return sc.Text(), nil
+		}
+	}
+
+	for {
+		line, err := readLine()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		r.history = append(r.history, line)
+		quit, err := r.exec(line)
+		if err != nil && !errors.Is(err, flag.ErrHelp) {
+			logger.Error(err.Error())
+		}
+		if quit {
+			return nil
+		}
+	}
+}
+
+// repl is the state of a REPL session.
+type repl struct {
+	schema  string // "" for schema-less analysis
+	typ     string // message of schema; "" for its only one
+	data    []byte // last payload given
+	history []string
+}
+
+func (r *repl) prompt() string {
+	return "protocompat (" + cmp.Or(r.spec(), "no schema") + ")> "
+}
+
+// spec returns the active schema as a single spec for lookupSchema.
+func (r *repl) spec() string {
+	switch {
+	case r.typ == "":
+		return r.schema
+	case r.schema == "":
+		return r.typ
+	}
+	return r.schema + "#" + r.typ
+}
+
+// exec runs a line of the session, reporting whether it ends it.
+func (r *repl) exec(line string) (quit bool, err error) {
+	words := strings.Fields(line)
+	name, args := words[0], words[1:]
+	switch name {
+	case "quit", "exit":
+		return true, nil
+	case "help":
+		fmt.Print(replHelp)
+	case "history":
+		for i, l := range r.history {
+			fmt.Printf("%4d  %s\n", i+1, l)
+		}
+	case "payload":
+		if r.data == nil {
+			return false, errors.New("no payload yet")
+		}
+		fmt.Printf("%d bytes: %X\n", len(r.data), r.data)
+	case "schema":
+		return false, r.setSchema(args, &r.schema)
+	case "type":
+		return false, r.setSchema(args, &r.typ)
+	case "decode":
+		return false, r.run(runDecode, args, r.schemaArgs())
+	case "analyze":
+		return false, r.run(runAnalyze, args, nil)
+	case "diff":
+		if len(args) == 0 {
+			return false, errors.New("usage: diff SCHEMA [PAYLOAD]")
+		}
+		return false, r.run(runDecode, args[1:], append(r.schemaArgs(), "-diff", args[0]))
+	default:
+		// Anything else is a payload to decode.
+		return false, r.run(runDecode, []string{line}, r.schemaArgs())
+	}
+	return false, nil
+}
+
+// setSchema shows the schema setting v, or sets it to args[0] if the
+// schema selected then can be loaded.
+func (r *repl) setSchema(args []string, v *string) error {
+	switch len(args) {
+	case 0:
+		fmt.Println(cmp.Or(r.spec(), "no schema"))
+		return nil
+	case 1:
+	default:
+		return errors.New("usage: schema [SPEC | -] or type [MESSAGE | -]")
+	}
+	oldSchema, oldType := r.schema, r.typ
+	if *v = args[0]; *v == "-" {
+		*v = ""
+	}
+	if v == &r.schema {
+		r.typ = ""
+	}
+	var err error
+	switch {
+	case r.spec() == "":
+	case r.typ == "" && !strings.Contains(r.schema, "#"):
+		// A source declaring several messages will do; type picks one.
+		if _, err = lookupSchema(r.schema); err != nil {
+			_, err = loadSource(r.schema, source.Options{})
+		}
+	default:
+		_, err = lookupSchema(r.spec())
+	}
+	if err != nil {
+		r.schema, r.typ = oldSchema, oldType
+	}
+	return err
+}
+
+func (r *repl) schemaArgs() []string {
+	return []string{"-schema", r.spec()}
+}
+
+// run runs a protocompat command on the payload first in args, if any,
+// or the last payload, with the flags in args after it and those in
+// flags.
+func (r *repl) run(cmd func(args []string) error, args, flags []string) error {
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		data, err := payload.Decode(args[0], payload.Auto)
+		if err != nil {
+			return err
+		}
+		r.data, args = data, args[1:]
+	}
+	if r.data == nil {
+		return errors.New("no payload yet: paste one first")
+	}
+	flags = append(append(flags, args...), "-hex", fmt.Sprintf("%X", r.data))
+	return cmd(flags)
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/example/protobuf-compat/internal/schema"
+	"github.com/example/protobuf-compat/internal/source"
+	"github.com/example/protobuf-compat/internal/wire"
+	"github.com/example/protobuf-compat/pkg/compat"
+	"github.com/example/protobuf-compat/pkg/decode"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// schemaFlags are the -schema/-diff flags shared by commands that decode
+// payloads fetched from somewhere else.
+type schemaFlags struct {
+	name     *string
+	diffName *string
+	loadSets func() error
+
+	msgType  protoreflect.MessageType
+	diffType protoreflect.MessageType
+	typ      *typeFlag
+
+	strict     *bool
+	violations int // payloads -strict rejected
+}
+
+func addSchemaFlags(fs *flag.FlagSet) *schemaFlags {
+	s := &schemaFlags{
+		name:     fs.String("schema", "v1", "schema to decode values with, "+schemaHelp+" (empty for schema-less analysis)"),
+		diffName: fs.String("diff", "", "also decode with this schema and show what changes"),
+		loadSets: addDescriptorSetFlag(fs),
+		strict:   fs.Bool("strict", false, "fail on payloads with fields the schema does not know, listing their numbers"),
+	}
+	s.typ = addTypeFlag(fs, s.name)
+	return s
+}
+
+// decode decodes data as mt, failing with -strict if data has fields mt
+// does not know.
+func (s *schemaFlags) decode(mt protoreflect.MessageType, data []byte) (proto.Message, error) {
+	r, err := decode.Decode(data, decode.Options{Type: mt, Strict: *s.strict})
+	switch {
+	case errors.Is(err, decode.ErrUnknownFields):
+		s.violations++
+		return nil, schemaMismatch(err)
+	case err != nil:
+		return nil, err
+	}
+	return r.Message, nil
+}
+
+// strictError reports the payloads -strict rejected, for commands that
+// carry on past them to fail in the end.
+func (s *schemaFlags) strictError() error {
+	if s.violations == 0 {
+		return nil
+	}
+	return schemaMismatch(fmt.Errorf("-strict: %d payloads with unknown fields", s.violations))
+}
+
+// resolve looks up the named schemas; call it after parsing flags.
+func (s *schemaFlags) resolve() error {
+	if err := s.loadSets(); err != nil {
+		return err
+	}
+	var err error
+	if *s.name == "" && *s.typ.name == "" {
+		if *s.diffName != "" {
+			return withExitCode(exitUsage, errors.New("-diff requires -schema"))
+		}
+		return nil
+	}
+	if s.msgType, err = s.typ.lookup(); err != nil {
+		return err
+	}
+	if *s.diffName != "" {
+		if s.diffType, err = lookupSchema(*s.diffName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printDecoded decodes data with the selected schema, prints it as JSON
+// and, when -diff is set, the field changes seen by the other schema.
+// Without a schema it prints the wire-format structure instead. Failures
+// are printed; only those of -strict are also returned.
+func (s *schemaFlags) printDecoded(data []byte) error {
+	if s.msgType == nil {
+		fields, err := wire.Parse(data)
+		printFields(fields, 1)
+		if err != nil {
+			fmt.Printf("  ❌ %v\n", err)
+		}
+		return nil
+	}
+	cur, err := s.decode(s.msgType, data)
+	if err != nil {
+		fmt.Printf("  ❌ %v\n", err)
+		if errors.Is(err, decode.ErrUnknownFields) {
+			return err
+		}
+		return nil
+	}
+	out, _ := schema.MarshalJSON(cur)
+	fmt.Printf("  ✅ %s: %s\n", *s.name, out)
+
+	if s.diffType == nil {
+		return nil
+	}
+	next, err := schema.Decode(s.diffType, data)
+	if err != nil {
+		fmt.Printf("  ❌ %v\n", err)
+		return nil
+	}
+	changes := schema.Diff(cur, next)
+	if len(changes) == 0 {
+		fmt.Printf("  no differences under %s\n", *s.diffName)
+		return nil
+	}
+	fmt.Printf("  %s -> %s:\n", *s.name, *s.diffName)
+	for _, c := range changes {
+		fmt.Printf("    %s\n", c)
+	}
+	return nil
+}
+
+// typeFlag is the -type flag, naming the message to use by full name,
+// within the source of the -schema flag it goes with when that is set.
+type typeFlag struct {
+	name   *string
+	schema *string
+	fs     *flag.FlagSet
+}
+
+func addTypeFlag(fs *flag.FlagSet, schema *string) *typeFlag {
+	return &typeFlag{
+		name:   fs.String("type", "", "message to use, by full name, such as example.v2.InfrastructureExecution: one of -schema's source, or without -schema a linked-in or -descriptor-set type"),
+		schema: schema,
+		fs:     fs,
+	}
+}
+
+// lookup resolves the message named by the -schema and -type flags.
+func (t *typeFlag) lookup() (protoreflect.MessageType, error) {
+	schemaSet := false
+	t.fs.Visit(func(f *flag.Flag) { schemaSet = schemaSet || f.Name == "schema" })
+	switch {
+	case *t.name == "":
+		return lookupSchema(*t.schema)
+	case !schemaSet || *t.schema == "":
+		return lookupSchema(*t.name)
+	case strings.Contains(*t.schema, "#"):
+		return nil, withExitCode(exitUsage, errors.New("name the message with -type or as SOURCE#MESSAGE, not both"))
+	}
+	return lookupSchema(*t.schema + "#" + *t.name)
+}
+
+// schemaHelp describes the values of flags taking a schema.
+const schemaHelp = "a message name or SOURCE[#MESSAGE], SOURCE being a descriptor set, .proto files or a grpc:// server"
+
+// sourceTimeout bounds loading a schema source, such as compiling .proto
+// files or calling a reflection service, in commands without -timeout.
+const sourceTimeout = 30 * time.Second
+
+// lookupSchema resolves a schema given to a flag such as -schema: the name
+// of a linked-in or loaded message, or a schema source as accepted by
+// source.Parse holding the message, given as SOURCE#MESSAGE, or as SOURCE
+// alone if it declares a single message. The types of the source are
+// loaded as if from a descriptor set.
+func lookupSchema(spec string) (protoreflect.MessageType, error) {
+	if mt, err := schema.Lookup(spec); err == nil {
+		return mt, nil
+	}
+	spec, name, _ := strings.Cut(spec, "#")
+	files, err := loadSource(spec, source.Options{})
+	if err != nil {
+		return nil, err
+	}
+	if err := schema.RegisterFiles(files); err != nil {
+		return nil, err
+	}
+	var md protoreflect.MessageDescriptor
+	if name != "" {
+		md, err = compat.FindMessage(files, name)
+	} else {
+		md, err = onlyMessage(files)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", spec, err)
+	}
+	// Not looked up by name: linked-in types of the same name would win.
+	return dynamicpb.NewMessageType(md), nil
+}
+
+// loadSource loads the files of a schema source.
+func loadSource(spec string, opts source.Options) ([]protoreflect.FileDescriptor, error) {
+	ctx, cancel := context.WithTimeout(rootContext, sourceTimeout)
+	defer cancel()
+	files, err := source.Load(ctx, spec, opts)
+	if err != nil {
+		return nil, err
+	}
+	logger.Debug("loaded schema", "source", spec, "files", len(files))
+	return files, nil
+}
+
+// onlyMessage returns the message declared by files, if they declare just
+// one besides the files they import.
+func onlyMessage(files []protoreflect.FileDescriptor) (protoreflect.MessageDescriptor, error) {
+	imported := make(map[string]bool)
+	for _, fd := range files {
+		for i := 0; i < fd.Imports().Len(); i++ {
+			imported[fd.Imports().Get(i).Path()] = true
+		}
+	}
+	var found []protoreflect.MessageDescriptor
+	for _, fd := range files {
+		if imported[fd.Path()] {
+			continue
+		}
+		for i := 0; i < fd.Messages().Len(); i++ {
+			found = append(found, fd.Messages().Get(i))
+		}
+	}
+	if len(found) != 1 {
+		return nil, fmt.Errorf("%d messages declared; name one as SOURCE#MESSAGE", len(found))
+	}
+	return found[0], nil
+}
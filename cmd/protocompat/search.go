@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/example/protobuf-compat/internal/payload"
+	"github.com/example/protobuf-compat/internal/wire"
+)
+
+func init() {
+	register(&command{
+		name:    "search",
+		summary: "find which field paths of one or many payloads hold a string, bytes or number",
+		run:     runSearch,
+	})
+}
+
+func runSearch(args []string) error {
+	fs := newFlagSet("search")
+	text := fs.String("string", "", "search for this text")
+	hexPattern := fs.String("hex", "", "search for these bytes, given in hex")
+	number := fs.Int64("int", 0, "search for this integer (varint, zigzag, fixed32 or fixed64)")
+	corpus := fs.String("corpus", "", "search every payload of a directory or line-per-payload file (\"-\" for stdin) instead of payload arguments")
+	decode := addEncodingFlag(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	var search func([]wire.Field) []wire.Match
+	var set []string
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "string", "hex", "int":
+			set = append(set, f.Name)
+		}
+	})
+	if len(set) != 1 {
+		return withExitCode(exitUsage, errors.New("exactly one of -string, -hex or -int is required"))
+	}
+	switch set[0] {
+	case "string":
+		if *text == "" {
+			return withExitCode(exitUsage, errors.New("-string must not be empty"))
+		}
+		search = func(fields []wire.Field) []wire.Match { return wire.SearchBytes(fields, []byte(*text)) }
+	case "hex":
+		pattern, err := hex.DecodeString(*hexPattern)
+		if err != nil || len(pattern) == 0 {
+			return withExitCode(exitUsage, fmt.Errorf("bad -hex %q", *hexPattern))
+		}
+		search = func(fields []wire.Field) []wire.Match { return wire.SearchBytes(fields, pattern) }
+	default:
+		search = func(fields []wire.Field) []wire.Match { return wire.SearchInt(fields, *number) }
+	}
+
+	total := 0
+	check := func(name string, data []byte, err error) error {
+		if err != nil {
+			logger.Warn("skipped payload", "payload", name, "error", err)
+			return nil
+		}
+		fields, err := wire.Parse(data)
+		if err != nil {
+			logger.Warn("payload only partially parsed", "payload", name, "error", err)
+		}
+		for _, m := range search(fields) {
+			fmt.Printf("%s: field %s at byte %d (%s)\n", name, wire.FormatPath(m.Path), m.Offset, m.How)
+			total++
+		}
+		return nil
+	}
+	if *corpus != "" {
+		enc, err := payload.ParseEncoding(fs.Lookup("encoding").Value.String())
+		if err != nil {
+			return withExitCode(exitUsage, err)
+		}
+		if err := readCorpus(rootContext, *corpus, enc, check); err != nil {
+			return err
+		}
+	} else {
+		if fs.NArg() == 0 {
+			return usageError("protocompat search -string S | -hex H | -int N <payload>... | -corpus PATH")
+		}
+		for i, arg := range fs.Args() {
+			data, err := decode(arg)
+			check(fmt.Sprintf("payload %d", i+1), data, err)
+		}
+	}
+	if total == 0 {
+		return errors.New("no matches")
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/example/protobuf-compat/pkg/compat"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// outcomeMarks prefixes simulated fields in text output.
+var outcomeMarks = map[compat.Outcome]string{
+	compat.Survived:  "✅",
+	compat.Changed:   "❌",
+	compat.Dropped:   "⚠️ ",
+	compat.Defaulted: "ℹ️ ",
+}
+
+func runCompatSimulate(args []string) error {
+	fs := newFlagSet("compat simulate")
+	message := fs.String("message", "", "message to simulate, by name within its package or in full (default: the message of a schema name given as WRITER)")
+	payloadText := fs.String("payload", "", "payload written by WRITER (default: a random message)")
+	decode := addEncodingFlag(fs)
+	seed := fs.Uint64("seed", 1, "random seed for the generated message")
+	format := fs.String("format", outputFormat, "report format: text, json or yaml")
+	viaJSON := fs.String("json", "", "also send the message through protojson, with readers that are \"strict\" or \"discard\" unknown fields, and compare")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return usageError("protocompat compat simulate [flags] WRITER READER (schema names, descriptor sets, .proto files or grpc:// servers)")
+	}
+	name, err := messageName(*message, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	writer, err := findMessage(fs.Arg(0), name)
+	if err != nil {
+		return err
+	}
+	reader, err := findMessage(fs.Arg(1), name)
+	if err != nil {
+		return err
+	}
+
+	var msg proto.Message
+	if *payloadText == "" {
+		msg = compat.RandomMessage(writer, *seed)
+	} else {
+		data, err := decode(*payloadText)
+		if err != nil {
+			return err
+		}
+		m := dynamicpb.NewMessage(writer)
+		if err := proto.Unmarshal(data, m); err != nil {
+			return schemaMismatch(fmt.Errorf("payload is not a %s: %w", fs.Arg(0), err))
+		}
+		msg = m
+	}
+	sim, err := compat.Simulate(msg, reader)
+	if err != nil {
+		return err
+	}
+	if *format != "text" && *format != "json" && *format != "yaml" {
+		return withExitCode(exitUsage, fmt.Errorf("unknown format %q (want text, json or yaml)", *format))
+	}
+	if *viaJSON == "" {
+		if *format != "text" {
+			return printStructured(*format, sim)
+		}
+		fmt.Printf("%s written by %s (%d bytes) and read by %s\n\n", name, fs.Arg(0), sim.Bytes, fs.Arg(1))
+		printSimulation(sim)
+		return nil
+	}
+
+	if *viaJSON != "strict" && *viaJSON != "discard" {
+		return withExitCode(exitUsage, fmt.Errorf("-json %q: want strict or discard", *viaJSON))
+	}
+	jsim, jerr := compat.SimulateJSON(msg, reader, *viaJSON == "discard")
+	var divergences []compat.Divergence
+	if jerr == nil {
+		divergences = compat.Diverge(sim, jsim)
+	}
+	if *format != "text" {
+		out := simulationJSON{Binary: sim, JSON: jsim, Divergences: divergences}
+		if jerr != nil {
+			out.JSONError = jerr.Error()
+		}
+		return printStructured(*format, out)
+	}
+
+	fmt.Printf("%s written by %s as protojson and read by %s (%s)\n\n", name, fs.Arg(0), fs.Arg(1), *viaJSON)
+	if jerr != nil {
+		fmt.Printf("❌ %v\n   binary readers accept the same message\n", jerr)
+		return nil
+	}
+	printSimulation(jsim)
+	fmt.Println()
+	if len(divergences) == 0 {
+		fmt.Println("✅ every field fares as it does in the binary format")
+	} else {
+		fmt.Println("Fields that fare differently in the binary format:")
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, d := range divergences {
+			fmt.Fprintf(tw, "  %s\t%s\tbinary: %s\tjson: %s\n", d.Path, d.Number, truncate(d.Binary, 40), truncate(d.JSON, 40))
+		}
+		tw.Flush()
+	}
+	if sim.Unknown > 0 {
+		fmt.Printf("ℹ️  binary readers keep %d bytes of undeclared fields and pass them on when re-encoding; JSON readers never do\n", sim.Unknown)
+	}
+	return nil
+}
+
+// simulationJSON is the layout of compat simulate -json ... -format json.
+type simulationJSON struct {
+	Binary      *compat.Simulation  `json:"binary"`
+	JSON        *compat.Simulation  `json:"json,omitempty"`
+	JSONError   string              `json:"json_error,omitempty"`
+	Divergences []compat.Divergence `json:"divergences"`
+}
+
+func printSimulation(sim *compat.Simulation) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, f := range sim.Fields {
+		var detail string
+		switch f.Outcome {
+		case compat.Survived, compat.Dropped:
+			detail = f.Written
+		case compat.Changed:
+			detail = f.Written + " ⟶ " + f.Read
+		case compat.Defaulted:
+			detail = f.Read
+		}
+		fmt.Fprintf(tw, "%s %s\t%s\t%s\t%s\n", outcomeMarks[f.Outcome], f.Outcome, f.Path, f.Number, truncate(detail, 60))
+	}
+	tw.Flush()
+	fmt.Printf("\n%d survived, %d changed, %d dropped, %d defaulted", sim.Count(compat.Survived), sim.Count(compat.Changed),
+		sim.Count(compat.Dropped), sim.Count(compat.Defaulted))
+	if sim.Unknown > 0 {
+		fmt.Printf("; %d bytes kept as unknown fields", sim.Unknown)
+	}
+	fmt.Println()
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/example/protobuf-compat/internal/payload"
+	"github.com/example/protobuf-compat/internal/wire"
+)
+
+func init() {
+	register(&command{
+		name:    "stats",
+		summary: "report field presence, cardinality and sizes across many payloads",
+		run:     runStats,
+	})
+}
+
+func runStats(args []string) error {
+	fs := newFlagSet("stats")
+	enc := fs.String("encoding", "auto", "payload encoding: auto, hex, base64 or base64url (raw is also accepted for directories)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return usageError("protocompat stats [flags] <dir | file | ->")
+	}
+	e, err := payload.ParseEncoding(*enc)
+	if err != nil {
+		return err
+	}
+
+	var corpus wire.Corpus
+	failed := 0
+	err = readCorpus(rootContext, fs.Arg(0), e, func(name string, data []byte, err error) error {
+		var fields []wire.Field
+		if err == nil {
+			if fields, err = wire.Parse(data); err != nil {
+				activeProgress.fail()
+			}
+		}
+		if err != nil {
+			logger.Warn("skipped payload", "payload", name, "error", err)
+			failed++
+			return nil
+		}
+		corpus.Add(fields)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if corpus.Messages == 0 {
+		return parseFailure(errors.New("no payloads could be parsed"))
+	}
+
+	fmt.Printf("Payloads: %d", corpus.Messages)
+	if failed > 0 {
+		fmt.Printf(" (%d skipped)", failed)
+	}
+	fmt.Print("\n\n")
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FIELD\tTYPE\tPRESENCE\tCOUNT\tDISTINCT\tMIN SIZE\tMAX SIZE")
+	for _, s := range corpus.Fields() {
+		types := make([]string, len(s.Types))
+		for i, t := range s.Types {
+			types[i] = wire.TypeName(t)
+		}
+		distinct, capped := s.Cardinality()
+		more := ""
+		if capped {
+			more = "+"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%.1f%%\t%d\t%d%s\t%d\t%d\n",
+			s.PathString(), strings.Join(types, ","),
+			100*float64(s.Messages)/float64(corpus.Messages),
+			s.Occurrences, distinct, more, s.MinSize, s.MaxSize)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/example/protobuf-compat/internal/wire"
+)
+
+func init() {
+	register(&command{
+		name:    "timestamps",
+		summary: "find fields of a payload that look like timestamps, without a schema",
+		run:     runTimestamps,
+		formats: []string{"json", "yaml"},
+	})
+}
+
+func runTimestamps(args []string) error {
+	fs := newFlagSet("timestamps")
+	pf := addPayloadFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return usageError("protocompat timestamps [payload]")
+	}
+	data, err := pf.read(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	fields, parseErr := wire.Parse(data)
+	found := findTimestamps(fields, "")
+	if outputFormat != "text" {
+		if parseErr != nil {
+			return fmt.Errorf("payload only partially parsed: %w", parseErr)
+		}
+		return printStructured(outputFormat, found)
+	}
+
+	fmt.Print("=== Decoding the Protobuf Message ===\n\n")
+	if parseErr != nil {
+		fmt.Printf("⚠️  payload only partially parsed: %v\n\n", parseErr)
+	}
+	for _, t := range found {
+		fmt.Printf("Field %s at byte %d: %s (%s)\n", t.Path, t.Offset, t.Time, t.Unit)
+	}
+	if len(found) == 0 {
+		fmt.Println("No plausible timestamps found.")
+	}
+	return nil
+}
+
+// timestamp is a field that looks like a timestamp, identified by its
+// field-number path.
+type timestamp struct {
+	Path   string `json:"path"`
+	Offset int    `json:"offset"`
+	Time   string `json:"time"`
+	Unit   string `json:"unit"`
+}
+
+// findTimestamps returns every field, including nested ones, that looks
+// like a timestamp.
+func findTimestamps(fields []wire.Field, prefix string) []timestamp {
+	found := []timestamp{}
+	for _, f := range fields {
+		path := fmt.Sprintf("%s%d", prefix, f.Number)
+		if guess, ok := wire.FieldTime(f); ok {
+			found = append(found, timestamp{path, f.Offset, wire.Times.Format(guess.Time), guess.Unit})
+			continue
+		}
+		if len(f.Nested) > 0 && !wire.IsText(f.Value) {
+			found = append(found, findTimestamps(f.Nested, path+".")...)
+		}
+	}
+	return found
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"text/tabwriter"
+
+	"github.com/example/protobuf-compat/internal/schema"
+	"github.com/example/protobuf-compat/internal/source"
+)
+
+func init() {
+	register(&command{
+		name:    "types",
+		summary: "list the message types available to -schema and -type (types list [PATTERN])",
+		run:     runTypes,
+		formats: []string{"json", "yaml"},
+	})
+}
+
+// typeJSON is an element of types list -format json.
+type typeJSON struct {
+	Name   string `json:"name"`
+	Alias  string `json:"alias,omitempty"`
+	Origin string `json:"origin"`
+	File   string `json:"file"`
+}
+
+func runTypes(args []string) error {
+	if len(args) == 0 || args[0] != "list" {
+		return usageError("protocompat types list [-descriptor-set FILE] [-source SOURCE]... [PATTERN]")
+	}
+	fs := newFlagSet("types list")
+	loadSets := addDescriptorSetFlag(fs)
+	var sources []string
+	fs.Func("source", "also list the types of this schema source: "+schemaHelp+" (repeatable)", func(s string) error {
+		sources = append(sources, s)
+		return nil
+	})
+	if err := parseFlags(fs, args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return usageError("protocompat types list [-descriptor-set FILE] [-source SOURCE]... [PATTERN]")
+	}
+	pattern := fs.Arg(0)
+	if _, err := path.Match(pattern, ""); err != nil {
+		return withExitCode(exitUsage, fmt.Errorf("bad pattern %q: %w", pattern, err))
+	}
+	if err := loadSets(); err != nil {
+		return err
+	}
+	for _, spec := range sources {
+		files, err := loadSource(spec, source.Options{})
+		if err != nil {
+			return err
+		}
+		if err := schema.RegisterFiles(files); err != nil {
+			return err
+		}
+	}
+
+	aliases := make(map[string]string)
+	for _, alias := range schema.Aliases() {
+		if mt, err := schema.Lookup(alias); err == nil {
+			aliases[string(mt.Descriptor().FullName())] = alias
+		}
+	}
+	types := []typeJSON{}
+	for _, t := range schema.Types() {
+		if ok, _ := path.Match(pattern, t.Name); ok || pattern == "" {
+			types = append(types, typeJSON{Name: t.Name, Alias: aliases[t.Name], Origin: t.Origin, File: t.File})
+		}
+	}
+	if outputFormat != "text" {
+		return printStructured(outputFormat, types)
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TYPE\tALIAS\tORIGIN\tFILE")
+	for _, t := range types {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", t.Name, t.Alias, t.Origin, t.File)
+	}
+	return tw.Flush()
+}
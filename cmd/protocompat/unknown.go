@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/example/protobuf-compat/internal/schema"
+	"github.com/example/protobuf-compat/internal/wire"
+)
+
+func init() {
+	register(&command{
+		name:    "unknown",
+		summary: "pull out the fields a schema does not know and analyze or save them",
+		run:     runUnknown,
+	})
+}
+
+func runUnknown(args []string) error {
+	fs := newFlagSet("unknown")
+	name := fs.String("schema", "v1", "schema to decode the payload with, "+schemaHelp)
+	typ := addTypeFlag(fs, name)
+	outDir := fs.String("o", "", "write each message's unknown bytes to a file in this directory instead of analyzing them")
+	loadSets := addDescriptorSetFlag(fs)
+	addFixedFlag(fs)
+	pf := addPayloadFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := loadSets(); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return usageError("protocompat unknown [flags] [payload]")
+	}
+	mt, err := typ.lookup()
+	if err != nil {
+		return err
+	}
+	data, err := pf.read(fs.Args())
+	if err != nil {
+		return err
+	}
+	msg, err := schema.Decode(mt, data)
+	if err != nil {
+		return schemaMismatch(err)
+	}
+
+	blobs := schema.Unknown(msg.ProtoReflect())
+	if len(blobs) == 0 {
+		fmt.Printf("✅ no unknown fields under %s\n", *name)
+		return nil
+	}
+	for _, b := range blobs {
+		path := b.Path
+		if path == "" {
+			path = "(top level)"
+		}
+		if *outDir != "" {
+			file := filepath.Join(*outDir, blobFileName(b.Path))
+			if err := writeFile(file, b.Data); err != nil {
+				return err
+			}
+			fmt.Printf("⚠️  %s: %d bytes of unknown fields written to %s\n", path, len(b.Data), file)
+			continue
+		}
+		fmt.Printf("⚠️  %s: %d bytes of unknown fields: %X\n", path, len(b.Data), b.Data)
+		fields, err := wire.Parse(b.Data)
+		printFields(fields, 1)
+		if err != nil {
+			fmt.Printf("  ❌ %v\n", err)
+		}
+	}
+	return nil
+}
+
+// blobFileName turns an unknown-field path into a file name.
+func blobFileName(path string) string {
+	if path == "" {
+		return "unknown.bin"
+	}
+	name := []byte(path)
+	for i, c := range name {
+		if c == '/' || c == os.PathSeparator {
+			name[i] = '_'
+		}
+	}
+	return string(name) + ".bin"
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/example/protobuf-compat/internal/schema"
+)
+
+func init() {
+	register(&command{
+		name:    "validate",
+		summary: "report where a payload does not match a schema",
+		run:     runValidate,
+	})
+}
+
+func runValidate(args []string) error {
+	fs := newFlagSet("validate")
+	schemaName := fs.String("schema", "v2", "schema to validate against, "+schemaHelp)
+	typ := addTypeFlag(fs, schemaName)
+	loadSets := addDescriptorSetFlag(fs)
+	pf := addPayloadFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := loadSets(); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return usageError("protocompat validate [-schema NAME] [-type MESSAGE] [payload]")
+	}
+	data, err := pf.read(fs.Args())
+	if err != nil {
+		return err
+	}
+	mt, err := typ.lookup()
+	if err != nil {
+		return err
+	}
+
+	report, err := schema.Validate(mt.Descriptor(), data)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Validating %d bytes against %s\n\n", len(data), report.Message)
+	for _, issue := range report.Issues {
+		mark := "❌"
+		switch issue.Kind {
+		case schema.MissingField:
+			mark = "ℹ️ "
+		case schema.DuplicateField:
+			mark = "⚠️ "
+		}
+		fmt.Printf("%s %s\n", mark, issue)
+	}
+	if n := report.Mismatches(); n > 0 {
+		return schemaMismatch(fmt.Errorf("%d mismatches against %s", n, report.Message))
+	}
+	fmt.Println("✅ payload matches the schema")
+	return nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/example/protobuf-compat/internal/schema"
+	"github.com/example/protobuf-compat/internal/wire"
+)
+
+func init() {
+	register(&command{
+		name:    "verify",
+		summary: "decode and re-encode a payload and check the bytes survive unchanged",
+		run:     runVerify,
+	})
+}
+
+func runVerify(args []string) error {
+	fs := newFlagSet("verify")
+	name := fs.String("schema", "v1", "schema to decode the payload with, "+schemaHelp)
+	typ := addTypeFlag(fs, name)
+	loadSets := addDescriptorSetFlag(fs)
+	pf := addPayloadFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := loadSets(); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return usageError("protocompat verify [flags] [payload]")
+	}
+	mt, err := typ.lookup()
+	if err != nil {
+		return err
+	}
+	data, err := pf.read(fs.Args())
+	if err != nil {
+		return err
+	}
+	out, err := schema.RoundTrip(mt, data)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(data, out) {
+		fmt.Printf("✅ %d bytes round-trip unchanged through %s\n", len(data), *name)
+		return nil
+	}
+
+	fmt.Printf("❌ re-encoding through %s changes the payload (%d -> %d bytes)\n", *name, len(data), len(out))
+	fmt.Printf("  before: %X\n  after:  %X\n", data, out)
+	before, err := wire.Parse(data)
+	if err != nil {
+		return err
+	}
+	after, err := wire.Parse(out)
+	if err != nil {
+		return err
+	}
+	for _, d := range wire.Compare(before, after) {
+		fmt.Printf("  %s\n", d)
+	}
+	return schemaMismatch(errors.New("payload does not round-trip"))
+}
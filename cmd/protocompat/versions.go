@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/example/protobuf-compat/internal/auth"
+	"github.com/example/protobuf-compat/internal/grpcreflect"
+	"github.com/example/protobuf-compat/internal/registry"
+	"github.com/example/protobuf-compat/internal/source"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// versionFlags are the flags of commands whose schema versions may be
+// fetched from running servers, given as grpc://host:port or
+// grpcs://host:port, through the gRPC reflection service, or from a
+// schema registry.
+type versionFlags struct {
+	timeout *time.Duration
+	newAuth func() (auth.Provider, error)
+}
+
+func addVersionFlags(fs *flag.FlagSet) *versionFlags {
+	return &versionFlags{
+		timeout: fs.Duration("timeout", 30*time.Second, "timeout for fetching versions from gRPC servers or a schema registry"),
+		newAuth: addAuthFlag(fs),
+	}
+}
+
+// load loads a version named on the command line.
+func (vf *versionFlags) load(name string) ([]protoreflect.FileDescriptor, error) {
+	var opts source.Options
+	if grpcreflect.IsTarget(name) {
+		var err error
+		if opts.Auth, err = vf.newAuth(); err != nil {
+			return nil, err
+		}
+	}
+	ctx, cancel := context.WithTimeout(rootContext, *vf.timeout)
+	defer cancel()
+	files, err := source.Load(ctx, name, opts)
+	if err != nil {
+		return nil, err
+	}
+	logger.Debug("loaded schema", "source", name, "files", len(files))
+	return files, nil
+}
+
+// registryHistory fetches the versions registered under subject, oldest
+// first, with their names for reports and the subject's compatibility
+// mode. A subject not registered yet has no versions.
+func (vf *versionFlags) registryHistory(url, subject string) (versions [][]protoreflect.FileDescriptor, names []string, mode string, err error) {
+	provider, err := vf.newAuth()
+	if err != nil {
+		return nil, nil, "", err
+	}
+	client := registry.New(url, auth.Client(provider))
+	ctx, cancel := context.WithTimeout(rootContext, *vf.timeout)
+	defer cancel()
+	numbers, err := client.Versions(ctx, subject)
+	if registry.NotFound(err) {
+		return nil, nil, "", nil
+	}
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if mode, err = client.Compatibility(ctx, subject); err != nil {
+		return nil, nil, "", err
+	}
+	logger.Debug("found registry subject", "subject", subject, "versions", len(numbers), "mode", mode)
+	for _, n := range numbers {
+		files, err := client.Schema(ctx, subject, n)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		versions = append(versions, files)
+		names = append(names, fmt.Sprintf("%s version %d", subject, n))
+	}
+	return versions, names, mode, nil
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/example/protobuf-compat/internal/schema"
+)
+
+// watchInterval is how often -watch looks for changed files.
+const watchInterval = 500 * time.Millisecond
+
+// watchFlags are the flags of commands that can run again whenever the
+// files they read change, as a feedback loop while editing a schema.
+type watchFlags struct {
+	watch    *bool
+	patterns []string
+	rebuild  *string
+}
+
+func addWatchFlags(fs *flag.FlagSet) *watchFlags {
+	w := &watchFlags{
+		watch:   fs.Bool("watch", false, "run again whenever a file read changes, until interrupted"),
+		rebuild: fs.String("rebuild", "", "with -watch, shell command to run after a change and before running again, e.g. to build descriptor sets from .proto files"),
+	}
+	fs.Func("watch-also", "with -watch, also watch the files matching this glob, such as the .proto files -rebuild compiles (repeatable)", func(s string) error {
+		w.patterns = append(w.patterns, s)
+		return nil
+	})
+	return w
+}
+
+// watching is set while -watch runs a command, so that the runs it
+// repeats do not watch in turn.
+var watching bool
+
+// enabled reports whether the command should watch, running itself
+// through run.
+func (w *watchFlags) enabled() bool {
+	return *w.watch && !watching
+}
+
+// run calls run, which runs the command again with the same arguments,
+// then again after every change to the files named by the flags and
+// arguments of fs or matching -watch-also, until interrupted. Errors of
+// the runs are logged rather than returned.
+func (w *watchFlags) run(fs *flag.FlagSet, run func() error) error {
+	if outPath != "" {
+		return withExitCode(exitUsage, errors.New("-watch and -out are exclusive"))
+	}
+	watching = true
+	defer func() { watching = false }()
+	ctx := rootContext
+
+	for first := true; ; first = false {
+		if !first && *w.rebuild != "" {
+			if err := w.runRebuild(ctx); err != nil {
+				logger.Error("rebuild failed", "rebuild", *w.rebuild, "error", err)
+			}
+		}
+		// Files rebuilt above have changed already.
+		files := w.files(fs)
+		before := modTimes(files)
+		schema.UnloadDescriptorSets()
+		if err := run(); err != nil {
+			logger.Error(err.Error())
+		}
+		logger.Info("watching for changes", "files", len(files))
+		changed, err := waitForChange(ctx, files, before)
+		if err != nil {
+			return nil // interrupted
+		}
+		logger.Info("changed, running again", "file", changed)
+	}
+}
+
+func (w *watchFlags) runRebuild(ctx context.Context) error {
+	cmd := exec.CommandContext(ctx, "sh", "-c", *w.rebuild)
+	cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
+	return cmd.Run()
+}
+
+// files returns the files to watch: flag values and arguments naming
+// files, such as payload and descriptor set files, the configuration file
+// and the files matching -watch-also.
+func (w *watchFlags) files(fs *flag.FlagSet) []string {
+	var files []string
+	add := func(s string) {
+		for _, name := range strings.Split(s, ",") {
+			name = strings.TrimSpace(name)
+			if isGlob(name) {
+				matches, _ := filepath.Glob(name)
+				files = append(files, matches...)
+			} else if fi, err := os.Stat(name); err == nil && fi.Mode().IsRegular() {
+				files = append(files, name)
+			}
+		}
+	}
+	fs.VisitAll(func(f *flag.Flag) {
+		if f.Name != "rebuild" {
+			add(f.Value.String())
+		}
+	})
+	for _, arg := range fs.Args() {
+		add(arg)
+	}
+	for _, pattern := range w.patterns {
+		add(pattern)
+	}
+	if cfg != nil {
+		add(cfg.Path)
+	}
+	return files
+}
+
+// modTimes returns the modification times of files, zero for those that
+// do not exist.
+func modTimes(files []string) map[string]time.Time {
+	times := make(map[string]time.Time, len(files))
+	for _, name := range files {
+		var t time.Time
+		if fi, err := os.Stat(name); err == nil {
+			t = fi.ModTime()
+		}
+		times[name] = t
+	}
+	return times
+}
+
+// waitForChange waits until one of files changes from its time in before
+// and returns its name, or the context's error.
+func waitForChange(ctx context.Context, files []string, before map[string]time.Time) (string, error) {
+	tick := time.NewTicker(watchInterval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-tick.C:
+		}
+		now := modTimes(files)
+		for _, name := range files {
+			if !now[name].Equal(before[name]) {
+				return name, nil
+			}
+		}
+	}
+}
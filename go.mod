@@ -1,5 +1,10 @@
 module github.com/example/protobuf-compat
 
-go 1.23
+go 1.24
 
-require google.golang.org/protobuf v1.36.11
+require (
+	golang.org/x/term v0.27.0
+	google.golang.org/protobuf v1.36.11
+)
+
+require golang.org/x/sys v0.28.0 // indirect
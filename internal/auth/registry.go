@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Factory builds a Provider from the argument part of a spec.
+type Factory func(arg string) (Provider, error)
+
+var (
+	mu        sync.RWMutex
+	factories = map[string]Factory{
+		"none": func(string) (Provider, error) { return None, nil },
+		"bearer": func(arg string) (Provider, error) {
+			return Bearer(StaticToken(arg)), nil
+		},
+		"bearer-file": func(arg string) (Provider, error) {
+			return Bearer(FileToken(arg)), nil
+		},
+		"header": func(arg string) (Provider, error) {
+			name, value, ok := strings.Cut(arg, "=")
+			if !ok || name == "" {
+				return nil, fmt.Errorf("want header:NAME=VALUE, got %q", arg)
+			}
+			return Header(name, StaticToken(value)), nil
+		},
+		"exec": func(arg string) (Provider, error) {
+			fields := strings.Fields(arg)
+			if len(fields) == 0 {
+				return nil, fmt.Errorf("exec: no command given")
+			}
+			return Bearer(&ExecToken{Command: fields[0], Args: fields[1:]}), nil
+		},
+	}
+)
+
+// Register makes a provider available to New under name. It is meant to be
+// called from an init function in a file added to the build, and panics if
+// name is already taken.
+func Register(name string, f Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, dup := factories[name]; dup {
+		panic("auth: provider " + name + " registered twice")
+	}
+	factories[name] = f
+}
+
+// Names returns the registered provider names in sorted order.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New builds a Provider from a spec of the form "NAME[:ARG]", e.g.
+//
+//	bearer:TOKEN
+//	bearer-file:/var/run/secrets/token
+//	header:X-Consul-Token=TOKEN
+//	exec:/usr/local/bin/cred-helper --audience kv
+//
+// An empty spec yields None.
+func New(spec string) (Provider, error) {
+	if spec == "" {
+		return None, nil
+	}
+	name, arg, _ := strings.Cut(spec, ":")
+	mu.RLock()
+	f, ok := factories[name]
+	mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown auth provider %q (registered: %s)", name, strings.Join(Names(), ", "))
+	}
+	p, err := f(arg)
+	if err != nil {
+		return nil, fmt.Errorf("auth provider %s: %w", name, err)
+	}
+	return p, nil
+}
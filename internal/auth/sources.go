@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StaticToken always returns the same token.
+type StaticToken string
+
+// Token returns t.
+func (t StaticToken) Token(context.Context) (string, error) { return string(t), nil }
+
+// FileToken reads the token from a file on every call, so rotated
+// credentials (e.g. projected service account tokens) are picked up.
+type FileToken string
+
+// Token returns the trimmed contents of the file.
+func (path FileToken) Token(context.Context) (string, error) {
+	b, err := os.ReadFile(string(path))
+	if err != nil {
+		return "", fmt.Errorf("read token: %w", err)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// defaultExecTTL is how long an exec token without an expiry is reused.
+const defaultExecTTL = 5 * time.Minute
+
+// ExecToken runs a credential helper and caches its output. The helper
+// prints either the bare token or a JSON object
+//
+//	{"token": "...", "expires_at": "2006-01-02T15:04:05Z"}
+//
+// on stdout. Tokens without an expiry are reused for five minutes.
+type ExecToken struct {
+	Command string
+	Args    []string
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// Token returns the cached token, running the helper when it has expired.
+func (e *ExecToken) Token(ctx context.Context) (string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.token != "" && time.Now().Before(e.expires) {
+		return e.token, nil
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, e.Command, e.Args...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("credential helper %s: %w: %s", e.Command, err, strings.TrimSpace(stderr.String()))
+	}
+
+	out := bytes.TrimSpace(stdout.Bytes())
+	token, expires := string(out), time.Now().Add(defaultExecTTL)
+	var structured struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if len(out) > 0 && out[0] == '{' {
+		if err := json.Unmarshal(out, &structured); err != nil {
+			return "", fmt.Errorf("credential helper %s: parse output: %w", e.Command, err)
+		}
+		token = structured.Token
+		if !structured.ExpiresAt.IsZero() {
+			expires = structured.ExpiresAt
+		}
+	}
+	if token == "" {
+		return "", fmt.Errorf("credential helper %s returned no token", e.Command)
+	}
+	e.token, e.expires = token, expires
+	return token, nil
+}
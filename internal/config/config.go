@@ -0,0 +1,254 @@
+// Package config reads protocompat configuration files, which set default
+// flag values so repeated invocations on a project need fewer flags:
+//
+//	# Flags of every command that has them.
+//	schema: v2
+//	descriptor-set: [schemas/orders.pb, schemas/billing.pb]
+//	format: json
+//
+//	# Flags of one command, overriding the ones above.
+//	compat check:
+//	  policy: BACKWARD_TRANSITIVE
+//	  severity:
+//	    - JSON_*=info
+//	    - FIELD_RENAMED=off
+//
+// Files are written in the subset of YAML shown: comments, "key: value"
+// pairs, one level of sections, and lists in flow or block style. Values
+// are flag values, quoted if they contain '#' or start with a quote or '['.
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Name is the file commands read from the current directory.
+const Name = ".protocompat.yaml"
+
+// Config holds flag values by flag name, each a list as flags may be
+// repeatable.
+type Config struct {
+	Path string
+	// Defaults apply to every command defining the flag.
+	Defaults map[string][]string
+	// Commands holds the settings of commands by name, such as "decode"
+	// or "compat check".
+	Commands map[string]map[string][]string
+}
+
+// Load reads the configuration file at path.
+func Load(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	c := &Config{Path: path, Defaults: map[string][]string{}, Commands: map[string]map[string][]string{}}
+	p := &parser{config: c}
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		p.line++
+		if err := p.parse(sc.Text()); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, p.line, err)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if err := p.end(); err != nil {
+		return nil, fmt.Errorf("%s:%d: %w", path, p.line, err)
+	}
+	return c, nil
+}
+
+// parser reads a file line by line.
+type parser struct {
+	config *Config
+	line   int
+
+	section map[string][]string // settings of the current section, if any
+	// key is the last key read without a value, at indentation indent:
+	// a list or section follows.
+	key    string
+	indent int
+	items  bool // list items of key seen
+}
+
+func (p *parser) parse(line string) error {
+	text := strings.TrimRight(stripComment(line), " \t")
+	if strings.TrimSpace(text) == "" {
+		return nil
+	}
+	trimmed := strings.TrimLeft(text, " ")
+	if strings.HasPrefix(trimmed, "\t") {
+		return fmt.Errorf("indent with spaces, not tabs")
+	}
+	indent := len(text) - len(trimmed)
+
+	if item, ok := strings.CutPrefix(trimmed+" ", "- "); ok {
+		if p.key == "" || indent < p.indent {
+			return fmt.Errorf("list item outside a list")
+		}
+		v, err := scalar(strings.TrimSpace(item))
+		if err != nil {
+			return err
+		}
+		p.items = true
+		p.set(p.key, v)
+		return nil
+	}
+
+	switch {
+	case p.key != "" && !p.items && indent > p.indent && p.indent == 0:
+		if err := p.startSection(); err != nil {
+			return err
+		}
+	case p.key != "" && !p.items:
+		if err := p.end(); err != nil {
+			return err
+		}
+	case indent > 0 && p.section == nil:
+		return fmt.Errorf("unexpected indentation")
+	}
+	p.key = ""
+	if indent == 0 {
+		p.section = nil
+	}
+
+	key, value, ok := strings.Cut(trimmed, ":")
+	if !ok || key == "" || value != "" && value[0] != ' ' {
+		return fmt.Errorf("want \"key: value\", got %q", trimmed)
+	}
+	key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+	if value == "" {
+		p.key, p.indent, p.items = key, indent, false
+		return nil
+	}
+	values, err := values(value)
+	if err != nil {
+		return err
+	}
+	p.set(key, values...)
+	return nil
+}
+
+// startSection makes the last key a section of command settings.
+func (p *parser) startSection() error {
+	if _, dup := p.config.Commands[p.key]; dup {
+		return fmt.Errorf("section %s repeated", p.key)
+	}
+	p.section = map[string][]string{}
+	p.config.Commands[p.key] = p.section
+	return nil
+}
+
+// end checks the last key has a value, a list or, at the top level, an
+// empty section.
+func (p *parser) end() error {
+	if p.key == "" || p.items {
+		return nil
+	}
+	if p.indent > 0 {
+		return fmt.Errorf("%s: no value", p.key)
+	}
+	return p.startSection()
+}
+
+func (p *parser) set(key string, values ...string) {
+	m := p.config.Defaults
+	if p.section != nil {
+		m = p.section
+	}
+	m[key] = append(m[key], values...)
+}
+
+// stripComment removes a comment, which starts with '#' at the start of
+// the line or after a space, outside quotes.
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else if c == '\\' && quote == '"' {
+				i++
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// values parses a value: a flow list such as [a, "b c"] or a scalar.
+func values(s string) ([]string, error) {
+	inner, ok := strings.CutPrefix(s, "[")
+	if !ok {
+		v, err := scalar(s)
+		return []string{v}, err
+	}
+	inner, ok = strings.CutSuffix(inner, "]")
+	if !ok {
+		return nil, fmt.Errorf("unterminated list %s", s)
+	}
+	var out []string
+	for _, item := range splitList(inner) {
+		v, err := scalar(strings.TrimSpace(item))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+// splitList splits the items of a flow list at commas outside quotes.
+func splitList(s string) []string {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	var out []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else if c == '\\' && quote == '"' {
+				i++
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ',':
+			out = append(out, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(out, s[start:])
+}
+
+// scalar parses a plain, single-quoted or double-quoted value.
+func scalar(s string) (string, error) {
+	switch {
+	case strings.HasPrefix(s, `"`):
+		v, err := strconv.Unquote(s)
+		if err != nil {
+			return "", fmt.Errorf("bad quoted value %s", s)
+		}
+		return v, nil
+	case strings.HasPrefix(s, "'"):
+		if len(s) < 2 || !strings.HasSuffix(s, "'") {
+			return "", fmt.Errorf("bad quoted value %s", s)
+		}
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	}
+	return s, nil
+}
@@ -0,0 +1,331 @@
+// Package grpcreflect fetches the schema a running gRPC server serves
+// through its reflection service, speaking gRPC over HTTP/2 with net/http
+// rather than depending on grpc-go.
+package grpcreflect
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/example/protobuf-compat/internal/auth"
+)
+
+// Schemes of reflection targets: grpc for plaintext HTTP/2, grpcs for TLS.
+const (
+	Scheme    = "grpc://"
+	SchemeTLS = "grpcs://"
+)
+
+// IsTarget reports whether s names a server, as "grpc://host:port" or
+// "grpcs://host:port", rather than a schema.
+func IsTarget(s string) bool {
+	return strings.HasPrefix(s, Scheme) || strings.HasPrefix(s, SchemeTLS)
+}
+
+// Reflection service methods, newest first. Servers older than grpc-go
+// 1.57 and many other implementations only serve v1alpha, whose messages
+// are the same.
+var methods = []string{
+	"/grpc.reflection.v1.ServerReflection/ServerReflectionInfo",
+	"/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo",
+}
+
+// Client fetches descriptors from one server.
+type Client struct {
+	base   string // http:// or https:// URL of the server
+	client *http.Client
+	method int // index into methods of the version the server serves
+}
+
+// New returns a client for the server at target. p, which may be nil,
+// authenticates each call, e.g. with a bearer token.
+func New(target string, p auth.Provider) (*Client, error) {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Protocols = new(http.Protocols)
+	var base string
+	switch {
+	case strings.HasPrefix(target, Scheme):
+		base = "http://" + strings.TrimPrefix(target, Scheme)
+		t.Protocols.SetUnencryptedHTTP2(true)
+	case strings.HasPrefix(target, SchemeTLS):
+		base = "https://" + strings.TrimPrefix(target, SchemeTLS)
+		t.Protocols.SetHTTP2(true)
+	default:
+		return nil, fmt.Errorf("bad gRPC target %q: want %shost:port or %shost:port", target, Scheme, SchemeTLS)
+	}
+	if u, err := url.Parse(base); err != nil || u.Host == "" || strings.Trim(u.Path, "/") != "" {
+		return nil, fmt.Errorf("bad gRPC target %q: want %shost:port or %shost:port", target, Scheme, SchemeTLS)
+	}
+	return &Client{
+		base:   strings.TrimRight(base, "/"),
+		client: &http.Client{Transport: auth.Transport(t, p)},
+	}, nil
+}
+
+// Files returns the files declaring the services the server exposes,
+// along with the files they import. The reflection service itself is
+// left out.
+func (c *Client) Files(ctx context.Context) ([]protoreflect.FileDescriptor, error) {
+	resp, err := c.call(ctx, listServices, "*")
+	if err != nil {
+		return nil, err
+	}
+	protos := make(map[string]*descriptorpb.FileDescriptorProto)
+	add := func(files [][]byte) error {
+		for _, b := range files {
+			fd := new(descriptorpb.FileDescriptorProto)
+			if err := proto.Unmarshal(b, fd); err != nil {
+				return fmt.Errorf("bad file descriptor from server: %w", err)
+			}
+			protos[fd.GetName()] = fd
+		}
+		return nil
+	}
+	for _, svc := range resp.services {
+		if strings.HasPrefix(svc, "grpc.reflection.") {
+			continue
+		}
+		resp, err := c.call(ctx, fileContainingSymbol, svc)
+		if err != nil {
+			return nil, fmt.Errorf("service %s: %w", svc, err)
+		}
+		if err := add(resp.files); err != nil {
+			return nil, err
+		}
+	}
+	// Servers usually send the imports along, but need not.
+	for missing := true; missing; {
+		missing = false
+		for _, name := range sortedKeys(protos) {
+			for _, dep := range protos[name].GetDependency() {
+				if protos[dep] != nil {
+					continue
+				}
+				resp, err := c.call(ctx, fileByFilename, dep)
+				if err != nil {
+					return nil, fmt.Errorf("file %s: %w", dep, err)
+				}
+				if err := add(resp.files); err != nil {
+					return nil, err
+				}
+				if protos[dep] == nil {
+					return nil, fmt.Errorf("file %s: not sent by the server", dep)
+				}
+				missing = true
+			}
+		}
+	}
+
+	set := &descriptorpb.FileDescriptorSet{}
+	for _, name := range sortedKeys(protos) {
+		set.File = append(set.File, protos[name])
+	}
+	reg, err := protodesc.NewFiles(set)
+	if err != nil {
+		return nil, err
+	}
+	var out []protoreflect.FileDescriptor
+	for _, fd := range set.File {
+		f, err := reg.FindFileByPath(fd.GetName())
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, f)
+	}
+	return out, nil
+}
+
+// Fields of grpc.reflection.v1.ServerReflectionRequest.
+const (
+	fileByFilename       protowire.Number = 3
+	fileContainingSymbol protowire.Number = 4
+	listServices         protowire.Number = 7
+)
+
+// response holds the parts of a ServerReflectionResponse the client uses.
+type response struct {
+	files    [][]byte // serialized FileDescriptorProtos
+	services []string
+}
+
+// call sends one request on a stream of its own and reads the response.
+func (c *Client) call(ctx context.Context, field protowire.Number, value string) (*response, error) {
+	var msg []byte
+	msg = protowire.AppendTag(msg, field, protowire.BytesType)
+	msg = protowire.AppendString(msg, value)
+	for {
+		body, status, err := c.post(ctx, methods[c.method], msg)
+		if status == codeUnimplemented && c.method < len(methods)-1 {
+			c.method++
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return parseResponse(body)
+	}
+}
+
+// gRPC status codes the client tells apart.
+const (
+	codeOK            = "0"
+	codeUnimplemented = "12"
+)
+
+// post makes a gRPC call with a single request message and returns the
+// first response message and the gRPC status code.
+func (c *Client) post(ctx context.Context, method string, msg []byte) ([]byte, string, error) {
+	frame := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(msg)))
+	frame = append(frame, msg...)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.base+method, bytes.NewReader(frame))
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, codeUnimplemented, fmt.Errorf("%s: no reflection service", c.base)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("POST %s%s: %s", c.base, method, resp.Status)
+	}
+	// Calls failing before any message put the status in the headers.
+	status, message := resp.Trailer.Get("Grpc-Status"), resp.Trailer.Get("Grpc-Message")
+	if status == "" {
+		status, message = resp.Header.Get("Grpc-Status"), resp.Header.Get("Grpc-Message")
+	}
+	if status != codeOK {
+		if m, err := url.PathUnescape(message); err == nil {
+			message = m
+		}
+		return nil, status, fmt.Errorf("%s%s: gRPC status %s: %s", c.base, method, status, message)
+	}
+	if len(body) < 5 {
+		return nil, status, errors.New("empty response from server")
+	}
+	if body[0] != 0 {
+		return nil, status, errors.New("server sent a compressed response")
+	}
+	n := binary.BigEndian.Uint32(body[1:5])
+	if uint32(len(body)-5) < n {
+		return nil, status, errors.New("truncated response from server")
+	}
+	return body[5 : 5+n], status, nil
+}
+
+// parseResponse decodes a ServerReflectionResponse.
+func parseResponse(b []byte) (*response, error) {
+	out := &response{}
+	err := eachField(b, func(num protowire.Number, v []byte) error {
+		switch num {
+		case 4: // file_descriptor_response
+			return eachField(v, func(num protowire.Number, v []byte) error {
+				if num == 1 {
+					out.files = append(out.files, v)
+				}
+				return nil
+			})
+		case 6: // list_services_response
+			return eachField(v, func(num protowire.Number, v []byte) error {
+				if num != 1 {
+					return nil
+				}
+				return eachField(v, func(num protowire.Number, v []byte) error {
+					if num == 1 {
+						out.services = append(out.services, string(v))
+					}
+					return nil
+				})
+			})
+		case 7: // error_response
+			var code uint64
+			var message string
+			for len(v) > 0 {
+				num, typ, n := protowire.ConsumeTag(v)
+				if n < 0 {
+					break
+				}
+				v = v[n:]
+				switch {
+				case num == 1 && typ == protowire.VarintType:
+					code, n = protowire.ConsumeVarint(v)
+				case num == 2 && typ == protowire.BytesType:
+					var s string
+					s, n = protowire.ConsumeString(v)
+					message = s
+				default:
+					n = protowire.ConsumeFieldValue(num, typ, v)
+				}
+				if n < 0 {
+					break
+				}
+				v = v[n:]
+			}
+			return fmt.Errorf("reflection error %d: %s", code, message)
+		}
+		return nil
+	})
+	return out, err
+}
+
+// eachField calls fn with the number and value of each length-delimited
+// field of message b, skipping the others.
+func eachField(b []byte, fn func(protowire.Number, []byte) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		if typ != protowire.BytesType {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			b = b[n:]
+			continue
+		}
+		v, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		if err := fn(num, v); err != nil {
+			return err
+		}
+		b = b[n:]
+	}
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,131 @@
+// Package kube reads serialized payloads out of Kubernetes objects
+// (ConfigMaps, Secrets and custom resources) through the API server's REST
+// interface.
+package kube
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/example/protobuf-compat/internal/auth"
+)
+
+// Service account files mounted into every pod.
+const (
+	serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+	tokenFile         = serviceAccountDir + "/token"
+	caFile            = serviceAccountDir + "/ca.crt"
+)
+
+// Config describes how to reach the API server.
+type Config struct {
+	// Server is the API server URL, e.g. https://10.0.0.1:6443 or the
+	// http://127.0.0.1:8001 address of "kubectl proxy".
+	Server string
+	// Token is a bearer token; TokenFile is read when Token is empty.
+	Token     string
+	TokenFile string
+	// Auth, when set, authenticates requests instead of Token/TokenFile.
+	Auth auth.Provider
+	// CAFile verifies the server certificate; Insecure skips verification.
+	CAFile   string
+	Insecure bool
+}
+
+// InClusterConfig returns the configuration of the pod's service account,
+// or an error when not running inside a cluster.
+func InClusterConfig() (Config, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return Config{}, errors.New("not running in a cluster: KUBERNETES_SERVICE_HOST/PORT unset")
+	}
+	return Config{
+		Server:    "https://" + net.JoinHostPort(host, port),
+		TokenFile: tokenFile,
+		CAFile:    caFile,
+	}, nil
+}
+
+// Client issues read-only requests against the API server.
+type Client struct {
+	server string
+	http   *http.Client
+}
+
+// NewClient builds a Client from cfg.
+func NewClient(cfg Config) (*Client, error) {
+	if cfg.Server == "" {
+		return nil, errors.New("no API server configured")
+	}
+	provider := cfg.Auth
+	switch {
+	case provider != nil:
+	case cfg.Token != "":
+		provider = auth.Bearer(auth.StaticToken(cfg.Token))
+	case cfg.TokenFile != "":
+		// Re-read on every request: projected service account tokens rotate.
+		provider = auth.Bearer(auth.FileToken(cfg.TokenFile))
+	default:
+		provider = auth.None
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.Insecure}
+	if cfg.CAFile != "" && !cfg.Insecure {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	return &Client{
+		server: strings.TrimRight(cfg.Server, "/"),
+		http:   &http.Client{Transport: auth.Transport(transport, provider)},
+	}, nil
+}
+
+// get fetches an API path and decodes the JSON response into v.
+func (c *Client) get(ctx context.Context, path string, v any) error {
+	body, err := c.open(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	if err := json.NewDecoder(body).Decode(v); err != nil {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+	return nil
+}
+
+// open issues a GET for an API path and returns the response body.
+func (c *Client) open(ctx context.Context, path string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.server+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("GET %s: %s: %s", path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return resp.Body, nil
+}
@@ -0,0 +1,151 @@
+package kube
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Ref identifies where a payload lives in the cluster. It is written as
+//
+//	configmap/NAME[:KEY]
+//	secret/NAME[:KEY]
+//	GROUP/VERSION/PLURAL/NAME:FIELD.PATH
+//
+// where FIELD.PATH is a dot-separated path into the custom resource's JSON.
+// Without KEY every key of the ConfigMap or Secret is returned.
+type Ref struct {
+	Kind string // "configmap", "secret" or "custom"
+
+	Group, Version, Plural string // custom resources only
+
+	Name string
+	Key  string
+}
+
+// ParseRef parses the textual form documented on Ref.
+func ParseRef(s string) (Ref, error) {
+	obj, key, _ := strings.Cut(s, ":")
+	parts := strings.Split(obj, "/")
+	switch {
+	case len(parts) == 2 && (parts[0] == "configmap" || parts[0] == "cm"):
+		return Ref{Kind: "configmap", Name: parts[1], Key: key}, nil
+	case len(parts) == 2 && parts[0] == "secret":
+		return Ref{Kind: "secret", Name: parts[1], Key: key}, nil
+	case len(parts) == 4 && key != "":
+		return Ref{Kind: "custom", Group: parts[0], Version: parts[1], Plural: parts[2], Name: parts[3], Key: key}, nil
+	}
+	return Ref{}, fmt.Errorf("bad object reference %q (want configmap/NAME[:KEY], secret/NAME[:KEY] or GROUP/VERSION/PLURAL/NAME:FIELD.PATH)", s)
+}
+
+// Payload is a decoded value read from a cluster object.
+type Payload struct {
+	Source string // e.g. "configmap/foo:state"
+	Data   []byte
+}
+
+// Fetch reads the payloads ref points at in namespace ns.
+func (c *Client) Fetch(ctx context.Context, ns string, ref Ref) ([]Payload, error) {
+	switch ref.Kind {
+	case "configmap":
+		var cm struct {
+			Data       map[string]string `json:"data"`
+			BinaryData map[string][]byte `json:"binaryData"`
+		}
+		if err := c.get(ctx, objectPath("", "v1", ns, "configmaps", ref.Name), &cm); err != nil {
+			return nil, err
+		}
+		values := make(map[string][]byte, len(cm.Data)+len(cm.BinaryData))
+		for k, v := range cm.Data {
+			// Text data can only carry binary payloads base64-encoded.
+			values[k] = decodeText(v)
+		}
+		for k, v := range cm.BinaryData {
+			values[k] = v
+		}
+		return selectKeys("configmap/"+ref.Name, values, ref.Key)
+
+	case "secret":
+		var secret struct {
+			Data map[string][]byte `json:"data"`
+		}
+		if err := c.get(ctx, objectPath("", "v1", ns, "secrets", ref.Name), &secret); err != nil {
+			return nil, err
+		}
+		return selectKeys("secret/"+ref.Name, secret.Data, ref.Key)
+
+	case "custom":
+		var obj map[string]any
+		if err := c.get(ctx, objectPath(ref.Group, ref.Version, ns, ref.Plural, ref.Name), &obj); err != nil {
+			return nil, err
+		}
+		v, err := lookupField(obj, ref.Key)
+		if err != nil {
+			return nil, fmt.Errorf("%s/%s: %w", ref.Plural, ref.Name, err)
+		}
+		return []Payload{{Source: ref.Plural + "/" + ref.Name + ":" + ref.Key, Data: decodeText(v)}}, nil
+	}
+	return nil, fmt.Errorf("unsupported object kind %q", ref.Kind)
+}
+
+// objectPath builds the REST path of a namespaced object. An empty group
+// selects the core API.
+func objectPath(group, version, ns, plural, name string) string {
+	prefix := "/api/" + version
+	if group != "" {
+		prefix = "/apis/" + group + "/" + version
+	}
+	return prefix + "/namespaces/" + url.PathEscape(ns) + "/" + plural + "/" + url.PathEscape(name)
+}
+
+func selectKeys(source string, values map[string][]byte, key string) ([]Payload, error) {
+	if key != "" {
+		v, ok := values[key]
+		if !ok {
+			return nil, fmt.Errorf("%s has no key %q", source, key)
+		}
+		return []Payload{{Source: source + ":" + key, Data: v}}, nil
+	}
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	payloads := make([]Payload, 0, len(keys))
+	for _, k := range keys {
+		payloads = append(payloads, Payload{Source: source + ":" + k, Data: values[k]})
+	}
+	return payloads, nil
+}
+
+// lookupField walks a dot-separated path through decoded JSON and returns
+// the string found there.
+func lookupField(obj map[string]any, path string) (string, error) {
+	var cur any = obj
+	for _, part := range strings.Split(path, ".") {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return "", fmt.Errorf("field %q: %q is not an object", path, part)
+		}
+		if cur, ok = m[part]; !ok {
+			return "", fmt.Errorf("field %q not found", path)
+		}
+	}
+	s, ok := cur.(string)
+	if !ok {
+		return "", fmt.Errorf("field %q is not a string", path)
+	}
+	return s, nil
+}
+
+// decodeText returns the base64 decoding of s, or s itself when it is not
+// valid base64.
+func decodeText(s string) []byte {
+	if b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s)); err == nil {
+		return b
+	}
+	return []byte(s)
+}
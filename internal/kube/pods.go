@@ -0,0 +1,66 @@
+package kube
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"strconv"
+)
+
+// Pod is a running pod and the names of its containers.
+type Pod struct {
+	Name       string
+	Containers []string
+}
+
+// Pods lists the pods in ns matching a label selector such as "app=executor".
+func (c *Client) Pods(ctx context.Context, ns, selector string) ([]Pod, error) {
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+			Spec struct {
+				Containers []struct {
+					Name string `json:"name"`
+				} `json:"containers"`
+			} `json:"spec"`
+		} `json:"items"`
+	}
+	path := "/api/v1/namespaces/" + url.PathEscape(ns) + "/pods?labelSelector=" + url.QueryEscape(selector)
+	if err := c.get(ctx, path, &list); err != nil {
+		return nil, err
+	}
+	pods := make([]Pod, 0, len(list.Items))
+	for _, item := range list.Items {
+		pod := Pod{Name: item.Metadata.Name}
+		for _, ctr := range item.Spec.Containers {
+			pod.Containers = append(pod.Containers, ctr.Name)
+		}
+		pods = append(pods, pod)
+	}
+	return pods, nil
+}
+
+// LogOptions selects which part of a container log to read.
+type LogOptions struct {
+	Follow       bool
+	SinceSeconds int64 // 0 reads the whole log
+	TailLines    int64 // 0 reads the whole log
+}
+
+// Logs opens the log of one container. With Follow set the stream stays
+// open until ctx is cancelled or the container exits.
+func (c *Client) Logs(ctx context.Context, ns, pod, container string, opts LogOptions) (io.ReadCloser, error) {
+	q := url.Values{"container": {container}}
+	if opts.Follow {
+		q.Set("follow", "true")
+	}
+	if opts.SinceSeconds > 0 {
+		q.Set("sinceSeconds", strconv.FormatInt(opts.SinceSeconds, 10))
+	}
+	if opts.TailLines > 0 {
+		q.Set("tailLines", strconv.FormatInt(opts.TailLines, 10))
+	}
+	return c.open(ctx, "/api/v1/namespaces/"+url.PathEscape(ns)+"/pods/"+url.PathEscape(pod)+"/log?"+q.Encode())
+}
@@ -0,0 +1,50 @@
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Consul reads keys through the Consul KV HTTP API (/v1/kv).
+type Consul struct {
+	Address string
+	Client  *http.Client
+}
+
+type consulEntry struct {
+	Key         string
+	Value       []byte
+	ModifyIndex int64
+}
+
+// List returns every key that starts with prefix.
+func (c *Consul) List(ctx context.Context, prefix string) ([]Entry, error) {
+	u := c.Address + "/v1/kv/" + (&url.URL{Path: strings.TrimPrefix(prefix, "/")}).EscapedPath() + "?recurse=true"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	body, status, err := do(c.Client, req)
+	if status == http.StatusNotFound {
+		// Consul reports an empty prefix as 404 rather than an empty list.
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var raw []consulEntry
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, fmt.Errorf("parse consul kv response: %w", err)
+	}
+
+	entries := make([]Entry, 0, len(raw))
+	for _, e := range raw {
+		entries = append(entries, Entry{Key: e.Key, Value: e.Value, Revision: e.ModifyIndex})
+	}
+	return entries, nil
+}
@@ -0,0 +1,75 @@
+package kv
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// Etcd reads keys through the etcd v3 JSON gateway (/v3/kv/range).
+type Etcd struct {
+	Endpoint string
+	Client   *http.Client
+}
+
+type etcdRangeRequest struct {
+	Key      []byte `json:"key"`
+	RangeEnd []byte `json:"range_end"`
+}
+
+type etcdRangeResponse struct {
+	Kvs []struct {
+		Key         []byte `json:"key"`
+		Value       []byte `json:"value"`
+		ModRevision string `json:"mod_revision"`
+	} `json:"kvs"`
+}
+
+// List returns every key that starts with prefix.
+func (e *Etcd) List(ctx context.Context, prefix string) ([]Entry, error) {
+	body, err := json.Marshal(etcdRangeRequest{
+		Key:      []byte(prefix),
+		RangeEnd: prefixEnd([]byte(prefix)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.Endpoint+"/v3/kv/range", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	respBody, _, err := do(e.Client, req)
+	if err != nil {
+		return nil, err
+	}
+	var resp etcdRangeResponse
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return nil, fmt.Errorf("parse etcd range response: %w", err)
+	}
+
+	entries := make([]Entry, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		// The gateway encodes int64 fields as JSON strings.
+		rev, _ := strconv.ParseInt(kv.ModRevision, 10, 64)
+		entries = append(entries, Entry{Key: string(kv.Key), Value: kv.Value, Revision: rev})
+	}
+	return entries, nil
+}
+
+// prefixEnd returns the smallest key greater than every key with the given
+// prefix, as etcd expects for range_end. An empty prefix selects all keys.
+func prefixEnd(prefix []byte) []byte {
+	end := bytes.Clone(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	return []byte{0}
+}
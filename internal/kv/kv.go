@@ -0,0 +1,61 @@
+// Package kv lists serialized values stored under a key prefix in etcd or
+// Consul, using each store's HTTP API.
+package kv
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Entry is a single key/value pair read from a store.
+type Entry struct {
+	Key      string
+	Value    []byte
+	Revision int64
+}
+
+// Store lists the entries stored under a key prefix.
+type Store interface {
+	List(ctx context.Context, prefix string) ([]Entry, error)
+}
+
+// Backends lists the supported backend names.
+var Backends = []string{"etcd", "consul"}
+
+// New returns the Store for the named backend ("etcd" or "consul").
+func New(backend, endpoint string, client *http.Client) (Store, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	endpoint = strings.TrimRight(endpoint, "/")
+	switch backend {
+	case "etcd":
+		return &Etcd{Endpoint: endpoint, Client: client}, nil
+	case "consul":
+		return &Consul{Address: endpoint, Client: client}, nil
+	default:
+		return nil, fmt.Errorf("unknown kv backend %q (want etcd or consul)", backend)
+	}
+}
+
+// do sends req and returns the response body, treating any non-2xx status
+// as an error.
+func do(client *http.Client, req *http.Request) ([]byte, int, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, resp.StatusCode, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return body, resp.StatusCode, fmt.Errorf("%s %s: %s: %s",
+			req.Method, req.URL, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return body, resp.StatusCode, nil
+}
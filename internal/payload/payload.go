@@ -0,0 +1,140 @@
+// Package payload turns payloads supplied as raw binary or as text (hex,
+// base64) back into the raw bytes the tools analyze.
+package payload
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Encoding names a textual payload encoding.
+type Encoding string
+
+const (
+	Auto      Encoding = "auto"
+	Raw       Encoding = "raw"
+	Hex       Encoding = "hex"
+	Base64    Encoding = "base64"
+	Base64URL Encoding = "base64url"
+)
+
+// Encodings lists the accepted encoding names.
+var Encodings = []Encoding{Auto, Raw, Hex, Base64, Base64URL}
+
+// ParseEncoding validates an encoding name.
+func ParseEncoding(s string) (Encoding, error) {
+	for _, e := range Encodings {
+		if string(e) == s {
+			return e, nil
+		}
+	}
+	return "", fmt.Errorf("unknown encoding %q (want auto, raw, hex, base64 or base64url)", s)
+}
+
+// Decode decodes text in the given encoding. Whitespace is ignored, as is
+// a leading "0x" on hex. With Auto, text made only of hex digits is read as
+// hex, text containing '-' or '_' as base64url and anything else as standard
+// base64; padding is optional for both base64 variants.
+func Decode(text string, enc Encoding) ([]byte, error) {
+	if enc == Raw {
+		return []byte(text), nil
+	}
+	text = strings.Join(strings.Fields(text), "")
+	if enc == Auto {
+		enc = Detect(text)
+	}
+	switch enc {
+	case Hex:
+		b, err := hex.DecodeString(trimHexPrefix(text))
+		if err != nil {
+			return nil, fmt.Errorf("decode hex: %w", err)
+		}
+		return b, nil
+	case Base64, Base64URL:
+		std, raw := base64.StdEncoding, base64.RawStdEncoding
+		if enc == Base64URL {
+			std, raw = base64.URLEncoding, base64.RawURLEncoding
+		}
+		if !strings.HasSuffix(text, "=") {
+			std = raw
+		}
+		b, err := std.DecodeString(text)
+		if err != nil {
+			return nil, fmt.Errorf("decode %s: %w", enc, err)
+		}
+		return b, nil
+	default:
+		return nil, fmt.Errorf("unknown encoding %q", enc)
+	}
+}
+
+// Detect guesses the encoding of text. Hex wins ties: an even-length run of
+// hex digits is also valid base64, but hex is far more common for dumps.
+func Detect(text string) Encoding {
+	h := trimHexPrefix(text)
+	if len(h) > 0 && len(h)%2 == 0 && strings.Trim(h, "0123456789abcdefABCDEF") == "" {
+		return Hex
+	}
+	if strings.ContainsAny(text, "-_") {
+		return Base64URL
+	}
+	return Base64
+}
+
+func trimHexPrefix(s string) string {
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		return s[2:]
+	}
+	return s
+}
+
+// Read reads a whole payload from r. With Auto, input consisting only of
+// characters of the text encodings is decoded as text and anything else is
+// taken as raw binary.
+func Read(r io.Reader, enc Encoding) ([]byte, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if enc == Raw || enc == Auto && !isEncodedText(data) {
+		return data, nil
+	}
+	return Decode(string(data), enc)
+}
+
+// ReadFile is Read on the named file, or on stdin when path is "-".
+func ReadFile(path string, enc Encoding) ([]byte, error) {
+	if path == "-" {
+		return Read(os.Stdin, enc)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f, enc)
+}
+
+// isEncodedText reports whether data is non-empty and made only of
+// characters used by the hex and base64 encodings, plus whitespace.
+func isEncodedText(data []byte) bool {
+	text := bytes.TrimSpace(data)
+	if len(text) == 0 {
+		return false
+	}
+	for _, c := range text {
+		switch {
+		case c >= '0' && c <= '9', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case c == '+', c == '/', c == '-', c == '_', c == '=':
+		case c == ' ', c == '\t', c == '\n', c == '\r':
+		default:
+			return false
+		}
+	}
+	return true
+}
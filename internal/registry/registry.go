@@ -0,0 +1,207 @@
+// Package registry reads protobuf schemas from a Confluent-compatible
+// schema registry through its REST API.
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	// Registries leave the well-known types out of schema references.
+	_ "google.golang.org/protobuf/types/known/anypb"
+	_ "google.golang.org/protobuf/types/known/apipb"
+	_ "google.golang.org/protobuf/types/known/durationpb"
+	_ "google.golang.org/protobuf/types/known/emptypb"
+	_ "google.golang.org/protobuf/types/known/fieldmaskpb"
+	_ "google.golang.org/protobuf/types/known/sourcecontextpb"
+	_ "google.golang.org/protobuf/types/known/structpb"
+	_ "google.golang.org/protobuf/types/known/timestamppb"
+	_ "google.golang.org/protobuf/types/known/typepb"
+	_ "google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// Client reads subjects from the registry at URL.
+type Client struct {
+	URL    string
+	Client *http.Client
+}
+
+// New returns a client for the registry at rawURL, e.g.
+// http://schema-registry:8081.
+func New(rawURL string, client *http.Client) *Client {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Client{URL: strings.TrimRight(rawURL, "/"), Client: client}
+}
+
+// Versions returns the version numbers registered under subject, oldest
+// first.
+func (c *Client) Versions(ctx context.Context, subject string) ([]int, error) {
+	var versions []int
+	if err := c.get(ctx, "/subjects/"+url.PathEscape(subject)+"/versions", &versions); err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+// Compatibility returns the compatibility mode configured for subject, or
+// the registry's global mode if the subject has none, e.g. BACKWARD or
+// FULL_TRANSITIVE.
+func (c *Client) Compatibility(ctx context.Context, subject string) (string, error) {
+	var config struct {
+		Level string `json:"compatibilityLevel"`
+		Mode  string `json:"compatibility"` // in newer registries
+	}
+	err := c.get(ctx, "/config/"+url.PathEscape(subject)+"?defaultToGlobal=true", &config)
+	if NotFound(err) {
+		// Registries without defaultToGlobal report subjects with no
+		// configuration of their own as not found.
+		err = c.get(ctx, "/config", &config)
+	}
+	if err != nil {
+		return "", err
+	}
+	if config.Level != "" {
+		return config.Level, nil
+	}
+	return config.Mode, nil
+}
+
+// schemaVersion is the layout of /subjects/{subject}/versions/{version}.
+type schemaVersion struct {
+	Subject    string      `json:"subject"`
+	Version    int         `json:"version"`
+	SchemaType string      `json:"schemaType"` // empty for Avro
+	Schema     []byte      `json:"schema"`     // base64, as requested by format=serialized
+	References []reference `json:"references"`
+}
+
+type reference struct {
+	Name    string `json:"name"` // the import path
+	Subject string `json:"subject"`
+	Version int    `json:"version"`
+}
+
+// Schema returns the files of version of subject: the subject's own and
+// those it references, resolved through the registry. Schemas are
+// requested in serialized form, as FileDescriptorProtos, which saves
+// parsing .proto sources.
+func (c *Client) Schema(ctx context.Context, subject string, version int) ([]protoreflect.FileDescriptor, error) {
+	files := &files{local: new(protoregistry.Files)}
+	var out []protoreflect.FileDescriptor
+	var load func(subject string, version int) (protoreflect.FileDescriptor, error)
+	load = func(subject string, version int) (protoreflect.FileDescriptor, error) {
+		var sv schemaVersion
+		path := fmt.Sprintf("/subjects/%s/versions/%d?format=serialized", url.PathEscape(subject), version)
+		if err := c.get(ctx, path, &sv); err != nil {
+			return nil, err
+		}
+		if sv.SchemaType != "PROTOBUF" {
+			return nil, fmt.Errorf("%s version %d is not a PROTOBUF schema", subject, version)
+		}
+		for _, ref := range sv.References {
+			if _, err := files.local.FindFileByPath(ref.Name); err == nil {
+				continue
+			}
+			if _, err := load(ref.Subject, ref.Version); err != nil {
+				return nil, fmt.Errorf("%s version %d: reference %s: %w", subject, version, ref.Name, err)
+			}
+		}
+		fdp := new(descriptorpb.FileDescriptorProto)
+		if err := proto.Unmarshal(sv.Schema, fdp); err != nil {
+			return nil, fmt.Errorf("%s version %d: not a serialized FileDescriptorProto: %w", subject, version, err)
+		}
+		fd, err := protodesc.NewFile(fdp, files)
+		if err != nil {
+			return nil, fmt.Errorf("%s version %d: %w", subject, version, err)
+		}
+		if err := files.local.RegisterFile(fd); err != nil {
+			return nil, fmt.Errorf("%s version %d: %w", subject, version, err)
+		}
+		out = append(out, fd)
+		return fd, nil
+	}
+	if _, err := load(subject, version); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// files resolves imports among the files of a schema, then among the
+// well-known types.
+type files struct {
+	local *protoregistry.Files
+}
+
+func (f *files) FindFileByPath(path string) (protoreflect.FileDescriptor, error) {
+	if fd, err := f.local.FindFileByPath(path); err == nil {
+		return fd, nil
+	}
+	return protoregistry.GlobalFiles.FindFileByPath(path)
+}
+
+func (f *files) FindDescriptorByName(name protoreflect.FullName) (protoreflect.Descriptor, error) {
+	if d, err := f.local.FindDescriptorByName(name); err == nil {
+		return d, nil
+	}
+	return protoregistry.GlobalFiles.FindDescriptorByName(name)
+}
+
+// Error is an error response of the registry.
+type Error struct {
+	Status  int    `json:"-"` // HTTP status
+	Code    int    `json:"error_code"`
+	Message string `json:"message"`
+}
+
+// NotFound reports whether err is the registry's answer for a subject or
+// version that does not exist.
+func NotFound(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.Status == http.StatusNotFound
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("schema registry: %s (error %d)", e.Message, e.Code)
+}
+
+// get fetches path and decodes the JSON response into v.
+func (c *Client) get(ctx context.Context, path string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.URL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.schemaregistry.v1+json, application/json")
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		apiErr := &Error{Status: resp.StatusCode}
+		if json.Unmarshal(body, apiErr) != nil || apiErr.Message == "" {
+			return fmt.Errorf("GET %s: %s: %s", req.URL, resp.Status, strings.TrimSpace(string(body)))
+		}
+		return apiErr
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("GET %s: %w", req.URL, err)
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+// Package sample generates example messages of any type with every field
+// filled in with a plausible value, chosen by the field's type and name,
+// as test inputs for demos and consumers. Unlike the fuzzing in
+// pkg/compat, it aims for values that look like real data rather than
+// ones that stress decoders.
+package sample
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// maxDepth bounds the nesting of messages, for recursive types.
+const maxDepth = 4
+
+// epoch is the time that generated times lead up to, so that the same
+// seed generates the same messages whenever it runs.
+var epoch = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Generator generates example messages. Generators created with the same
+// seed generate the same messages.
+type Generator struct {
+	rng  *rand.Rand
+	last time.Time // time generated last in the message
+}
+
+// New returns a generator seeded with seed.
+func New(seed uint64) *Generator {
+	return &Generator{rng: rand.New(rand.NewPCG(seed, seed))}
+}
+
+// Message returns a message of type md with every field set, one field of
+// each oneof, and one to three elements in repeated fields and maps.
+func (g *Generator) Message(md protoreflect.MessageDescriptor) *dynamicpb.Message {
+	g.last = time.Time{}
+	return g.message(md, 0)
+}
+
+func (g *Generator) message(md protoreflect.MessageDescriptor, depth int) *dynamicpb.Message {
+	m := dynamicpb.NewMessage(md)
+	if wk := g.wellKnown(m); wk {
+		return m
+	}
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if fd.Message() != nil && !fd.IsMap() && depth >= maxDepth && fd.Cardinality() != protoreflect.Required {
+			continue
+		}
+		if od := fd.ContainingOneof(); od != nil && !od.IsSynthetic() && m.WhichOneof(od) != nil {
+			continue
+		}
+		switch {
+		case fd.IsMap():
+			mp := m.Mutable(fd).Map()
+			for n := 1 + g.rng.IntN(3); n > 0; n-- {
+				mp.Set(g.value(fd.MapKey(), depth).MapKey(), g.value(fd.MapValue(), depth))
+			}
+		case fd.IsList():
+			list := m.Mutable(fd).List()
+			for n := 1 + g.rng.IntN(3); n > 0; n-- {
+				list.Append(g.value(fd, depth))
+			}
+		default:
+			m.Set(fd, g.value(fd, depth))
+		}
+	}
+	return m
+}
+
+// wellKnown fills in m if it is of a well-known type whose fields only
+// make sense together, reporting whether it was.
+func (g *Generator) wellKnown(m *dynamicpb.Message) bool {
+	fields := m.Descriptor().Fields()
+	switch m.Descriptor().FullName() {
+	case "google.protobuf.Timestamp":
+		t := g.time()
+		m.Set(fields.ByName("seconds"), protoreflect.ValueOfInt64(t.Unix()))
+		m.Set(fields.ByName("nanos"), protoreflect.ValueOfInt32(int32(t.Nanosecond())))
+	case "google.protobuf.Duration":
+		d := time.Duration(g.rng.Int64N(int64(time.Hour)))
+		m.Set(fields.ByName("seconds"), protoreflect.ValueOfInt64(int64(d/time.Second)))
+		m.Set(fields.ByName("nanos"), protoreflect.ValueOfInt32(int32(d%time.Second/time.Millisecond*time.Millisecond)))
+	case "google.protobuf.Any", "google.protobuf.FieldMask", "google.protobuf.Struct",
+		"google.protobuf.Value", "google.protobuf.ListValue", "google.protobuf.Empty":
+		// Left empty: valid, and any content would be made up.
+	default:
+		return false
+	}
+	return true
+}
+
+// time returns a time in the 30 days before epoch, to the millisecond.
+// The times of a message follow each other by up to a few hours, so that
+// in the usual field order a stop comes after its start.
+func (g *Generator) time() time.Time {
+	if g.last.IsZero() {
+		g.last = epoch.Add(-time.Duration(g.rng.Int64N(int64(30 * 24 * time.Hour))))
+	} else {
+		g.last = g.last.Add(time.Duration(g.rng.Int64N(int64(6 * time.Hour))))
+	}
+	return g.last.Truncate(time.Millisecond)
+}
+
+func (g *Generator) value(fd protoreflect.FieldDescriptor, depth int) protoreflect.Value {
+	name := strings.ToLower(string(fd.Name()))
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return protoreflect.ValueOfBool(g.rng.IntN(2) == 1)
+	case protoreflect.EnumKind:
+		// The zero value usually means unset; prefer the others.
+		values := fd.Enum().Values()
+		v := values.Get(g.rng.IntN(values.Len()))
+		if v.Number() == 0 && values.Len() > 1 {
+			v = values.Get(1 + g.rng.IntN(values.Len()-1))
+		}
+		return protoreflect.ValueOfEnum(v.Number())
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return protoreflect.ValueOfInt32(int32(g.integer(name, 32)))
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return protoreflect.ValueOfInt64(g.integer(name, 64))
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return protoreflect.ValueOfUint32(uint32(g.integer(name, 32)))
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return protoreflect.ValueOfUint64(uint64(g.integer(name, 64)))
+	case protoreflect.FloatKind:
+		return protoreflect.ValueOfFloat32(float32(g.rng.IntN(10000)) / 100)
+	case protoreflect.DoubleKind:
+		return protoreflect.ValueOfFloat64(float64(g.rng.IntN(100000)) / 100)
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(g.text(name))
+	case protoreflect.BytesKind:
+		b := make([]byte, 8+g.rng.IntN(9))
+		for i := range b {
+			b[i] = byte(g.rng.UintN(256))
+		}
+		return protoreflect.ValueOfBytes(b)
+	default: // message, group
+		return protoreflect.ValueOfMessage(g.message(fd.Message(), depth+1))
+	}
+}
+
+// integer returns a number fitting the field's name: a Unix time in
+// seconds or milliseconds for times, a port, or a small count.
+func (g *Generator) integer(name string, bits int) int64 {
+	switch {
+	case bits == 64 && (strings.HasSuffix(name, "_ms") || strings.HasSuffix(name, "millis")):
+		if isTimeName(strings.TrimSuffix(strings.TrimSuffix(name, "_ms"), "millis")) {
+			return g.time().UnixMilli()
+		}
+		return g.rng.Int64N(60000)
+	case isTimeName(name):
+		return g.time().Unix()
+	case strings.Contains(name, "port"):
+		return 1024 + g.rng.Int64N(64511)
+	case strings.HasSuffix(name, "id") || strings.HasSuffix(name, "ids"):
+		return 1 + g.rng.Int64N(100000)
+	}
+	return g.rng.Int64N(100)
+}
+
+func isTimeName(name string) bool {
+	return strings.HasSuffix(name, "_at") || strings.HasSuffix(name, "time") ||
+		strings.HasSuffix(name, "timestamp") || strings.HasSuffix(name, "date")
+}
+
+// words make up made-up text.
+var words = []string{
+	"alpha", "bravo", "cedar", "delta", "ember", "falcon", "granite", "harbor",
+	"indigo", "juniper", "kestrel", "lumen", "meadow", "nimbus", "orchid", "prairie",
+}
+
+// text returns a string fitting the field's name.
+func (g *Generator) text(name string) string {
+	word := func() string { return words[g.rng.IntN(len(words))] }
+	switch {
+	case name == "id" || strings.HasSuffix(name, "_id") || strings.HasSuffix(name, "_ids") || name == "uuid":
+		return fmt.Sprintf("%08x-%04x-4%03x-%04x-%012x", g.rng.Uint32(), g.rng.UintN(1<<16), g.rng.UintN(1<<12),
+			0x8000|g.rng.UintN(1<<14), g.rng.Uint64N(1<<48))
+	case strings.Contains(name, "email"):
+		return word() + "@example.com"
+	case strings.Contains(name, "url") || strings.Contains(name, "uri") || strings.Contains(name, "link"):
+		return "https://example.com/" + word() + "/" + word()
+	case strings.Contains(name, "host"):
+		return word() + ".example.com"
+	case strings.Contains(name, "path") || strings.Contains(name, "file"):
+		return "/var/lib/" + word() + "/" + word() + ".dat"
+	case strings.Contains(name, "version"):
+		return fmt.Sprintf("v%d.%d.%d", g.rng.IntN(3), g.rng.IntN(20), g.rng.IntN(10))
+	case isTimeName(name):
+		return g.time().Format(time.RFC3339)
+	case strings.Contains(name, "name"):
+		return word() + "-" + word()
+	case strings.Contains(name, "description") || strings.Contains(name, "message") || strings.Contains(name, "comment"):
+		first := word()
+		return strings.ToUpper(first[:1]) + first[1:] + " " + word() + " " + word() + "."
+	}
+	return word()
+}
@@ -0,0 +1,54 @@
+package schema
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// catalogFS holds the descriptor sets embedded in the binary; see
+// catalog/README.md.
+//
+//go:embed catalog
+var catalogFS embed.FS
+
+// catalog holds the types of the embedded descriptor sets. Lookup and
+// Resolver consult it after the loaded descriptor sets and before the
+// linked-in types.
+var catalog = new(protoregistry.Types)
+
+func init() {
+	if err := loadCatalog(catalogFS); err != nil {
+		// Descriptor sets are embedded when building; a broken one is a
+		// broken build.
+		panic("schema catalog: " + err.Error())
+	}
+}
+
+func loadCatalog(fsys fs.FS) error {
+	return fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || path.Ext(name) != ".pb" && path.Ext(name) != ".binpb" {
+			return err
+		}
+		raw, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		files, err := parseDescriptorSet(name, raw)
+		if err != nil {
+			return err
+		}
+		files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+			err = registerFile(catalog, fd)
+			return err == nil
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,259 @@
+package schema
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+
+	"github.com/example/protobuf-compat/internal/wire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// loaded holds the types of the descriptor sets loaded so far. Lookup and
+// Resolver consult it before the linked-in types.
+var loaded = new(protoregistry.Types)
+
+// LoadDescriptorSet registers the message and extension types of a
+// FileDescriptorSet, as written by protoc --descriptor_set_out or
+// buf build -o, so that they can be looked up by name and used to resolve
+// google.protobuf.Any values. Types already known are skipped.
+func LoadDescriptorSet(path string) error {
+	files, err := ReadDescriptorSet(path)
+	if err != nil {
+		return err
+	}
+	files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		err = registerFile(loaded, fd)
+		return err == nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// RegisterFiles registers the message and extension types of files, such
+// as those of a schema source, like LoadDescriptorSet.
+func RegisterFiles(files []protoreflect.FileDescriptor) error {
+	for _, fd := range files {
+		if err := registerFile(loaded, fd); err != nil {
+			return fmt.Errorf("%s: %w", fd.Path(), err)
+		}
+	}
+	return nil
+}
+
+// UnloadDescriptorSets forgets the types of the descriptor sets loaded so
+// far, so that changed versions of them can be loaded again.
+func UnloadDescriptorSets() {
+	loaded = new(protoregistry.Types)
+}
+
+// ReadDescriptorSet reads a FileDescriptorSet without registering its
+// types, for looking at the schema itself rather than decoding with it.
+func ReadDescriptorSet(path string) (*protoregistry.Files, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseDescriptorSet(path, raw)
+}
+
+// parseDescriptorSet parses raw, the FileDescriptorSet in the file name.
+func parseDescriptorSet(name string, raw []byte) (*protoregistry.Files, error) {
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(raw, &set); err != nil {
+		return nil, fmt.Errorf("%s: not a FileDescriptorSet: %w", name, err)
+	}
+	files, err := protodesc.NewFiles(&set)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return files, nil
+}
+
+// Version resolves one version of a schema for comparison with another:
+// either a descriptor set file, all of whose files are returned, or a
+// schema name as accepted by Lookup, whose declaring file is returned.
+func Version(arg string) ([]protoreflect.FileDescriptor, error) {
+	if _, err := os.Stat(arg); err != nil {
+		mt, err := Lookup(arg)
+		if err != nil {
+			return nil, err
+		}
+		return []protoreflect.FileDescriptor{mt.Descriptor().ParentFile()}, nil
+	}
+	files, err := ReadDescriptorSet(arg)
+	if err != nil {
+		return nil, err
+	}
+	var out []protoreflect.FileDescriptor
+	files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		out = append(out, fd)
+		return true
+	})
+	sort.Slice(out, func(i, j int) bool { return out[i].Path() < out[j].Path() })
+	return out, nil
+}
+
+// registerFile registers the types of fd in reg.
+func registerFile(reg *protoregistry.Types, fd protoreflect.FileDescriptor) error {
+	if err := registerMessages(reg, fd.Messages()); err != nil {
+		return err
+	}
+	return registerExtensions(reg, fd.Extensions())
+}
+
+func registerMessages(reg *protoregistry.Types, msgs protoreflect.MessageDescriptors) error {
+	for i := 0; i < msgs.Len(); i++ {
+		md := msgs.Get(i)
+		if !known(md.FullName()) {
+			if err := reg.RegisterMessage(dynamicpb.NewMessageType(md)); err != nil {
+				return err
+			}
+		}
+		if err := registerMessages(reg, md.Messages()); err != nil {
+			return err
+		}
+		if err := registerExtensions(reg, md.Extensions()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func registerExtensions(reg *protoregistry.Types, exts protoreflect.ExtensionDescriptors) error {
+	for i := 0; i < exts.Len(); i++ {
+		xd := exts.Get(i)
+		if known(xd.FullName()) {
+			continue
+		}
+		if err := reg.RegisterExtension(dynamicpb.NewExtensionType(xd)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// known reports whether a type of that name is linked in, in the catalog
+// or already loaded.
+func known(name protoreflect.FullName) bool {
+	if _, err := protoregistry.GlobalFiles.FindDescriptorByName(name); err == nil {
+		return true
+	}
+	for _, reg := range []*protoregistry.Types{catalog, loaded} {
+		if _, err := reg.FindMessageByName(name); err == nil {
+			return true
+		}
+		if _, err := reg.FindExtensionByName(name); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// MessageNames returns the full names of the message types of the loaded
+// descriptor sets, the catalog and the linked-in types, sorted.
+func MessageNames() []string {
+	types := Types()
+	names := make([]string, 0, len(types))
+	for _, t := range types {
+		names = append(names, t.Name)
+	}
+	return names
+}
+
+// Origins of types.
+const (
+	Linked  = "linked"  // generated Go code linked into the binary
+	Catalog = "catalog" // descriptor sets embedded in the binary
+	Loaded  = "loaded"  // descriptor sets and schema sources loaded at run time
+)
+
+// TypeInfo describes a message type available for decoding.
+type TypeInfo struct {
+	Name   string // full name
+	File   string // the .proto file declaring it
+	Origin string // Linked, Catalog or Loaded
+}
+
+// Types returns the message types of the loaded descriptor sets, the
+// catalog and the linked-in types, sorted by name. Map entries are left
+// out.
+func Types() []TypeInfo {
+	var types []TypeInfo
+	for _, r := range []struct {
+		reg    *protoregistry.Types
+		origin string
+	}{{loaded, Loaded}, {catalog, Catalog}, {protoregistry.GlobalTypes, Linked}} {
+		r.reg.RangeMessages(func(mt protoreflect.MessageType) bool {
+			md := mt.Descriptor()
+			if !md.IsMapEntry() {
+				types = append(types, TypeInfo{Name: string(md.FullName()), File: md.ParentFile().Path(), Origin: r.origin})
+			}
+			return true
+		})
+	}
+	sort.SliceStable(types, func(i, j int) bool { return types[i].Name < types[j].Name })
+	return slices.CompactFunc(types, func(a, b TypeInfo) bool { return a.Name == b.Name })
+}
+
+// TypeResolver finds message and extension types by name, URL or number.
+// It is the interface protojson and proto.UnmarshalOptions resolve with,
+// declared in package wire, which this package builds on, for its Any
+// resolver to be one.
+type TypeResolver = wire.TypeResolver
+
+// Resolver returns a TypeResolver that consults the loaded descriptor
+// sets, then the catalog, then the linked-in types.
+func Resolver() TypeResolver {
+	return resolver{}
+}
+
+type resolver struct{}
+
+func (resolver) FindMessageByName(name protoreflect.FullName) (protoreflect.MessageType, error) {
+	if mt, err := loaded.FindMessageByName(name); err == nil {
+		return mt, nil
+	}
+	if mt, err := catalog.FindMessageByName(name); err == nil {
+		return mt, nil
+	}
+	return protoregistry.GlobalTypes.FindMessageByName(name)
+}
+
+func (resolver) FindMessageByURL(url string) (protoreflect.MessageType, error) {
+	if mt, err := loaded.FindMessageByURL(url); err == nil {
+		return mt, nil
+	}
+	if mt, err := catalog.FindMessageByURL(url); err == nil {
+		return mt, nil
+	}
+	return protoregistry.GlobalTypes.FindMessageByURL(url)
+}
+
+func (resolver) FindExtensionByName(field protoreflect.FullName) (protoreflect.ExtensionType, error) {
+	if xt, err := loaded.FindExtensionByName(field); err == nil {
+		return xt, nil
+	}
+	if xt, err := catalog.FindExtensionByName(field); err == nil {
+		return xt, nil
+	}
+	return protoregistry.GlobalTypes.FindExtensionByName(field)
+}
+
+func (resolver) FindExtensionByNumber(message protoreflect.FullName, field protoreflect.FieldNumber) (protoreflect.ExtensionType, error) {
+	if xt, err := loaded.FindExtensionByNumber(message, field); err == nil {
+		return xt, nil
+	}
+	if xt, err := catalog.FindExtensionByNumber(message, field); err == nil {
+		return xt, nil
+	}
+	return protoregistry.GlobalTypes.FindExtensionByNumber(message, field)
+}
@@ -0,0 +1,142 @@
+package schema
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/example/protobuf-compat/internal/wire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ChangeKind describes how a field differs between two decodes.
+type ChangeKind string
+
+const (
+	Added   ChangeKind = "added"
+	Removed ChangeKind = "removed"
+	Changed ChangeKind = "changed"
+)
+
+// Change is a single field-level difference between two decoded messages.
+type Change struct {
+	Field string
+	Kind  ChangeKind
+	Old   string
+	New   string
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case Added:
+		return fmt.Sprintf("+ %s: %s", c.Field, c.New)
+	case Removed:
+		return fmt.Sprintf("- %s: %s", c.Field, c.Old)
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", c.Field, c.Old, c.New)
+	}
+}
+
+// Diff compares two messages field by field, matching fields by name so
+// that decodes of the same bytes under different schema versions can be
+// compared. Unknown fields retained by either side are reported under the
+// pseudo-field "<unknown>".
+func Diff(oldMsg, newMsg proto.Message) []Change {
+	oldVals := fieldValues(oldMsg.ProtoReflect())
+	newVals := fieldValues(newMsg.ProtoReflect())
+
+	names := make(map[string]bool)
+	for name := range oldVals {
+		names[name] = true
+	}
+	for name := range newVals {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	var changes []Change
+	for _, name := range sorted {
+		o, inOld := oldVals[name]
+		n, inNew := newVals[name]
+		switch {
+		case !inOld:
+			changes = append(changes, Change{Field: name, Kind: Added, New: n})
+		case !inNew:
+			changes = append(changes, Change{Field: name, Kind: Removed, Old: o})
+		case o != n:
+			changes = append(changes, Change{Field: name, Kind: Changed, Old: o, New: n})
+		}
+	}
+	return changes
+}
+
+// fieldValues renders every populated field of m, keyed by field name.
+func fieldValues(m protoreflect.Message) map[string]string {
+	vals := make(map[string]string)
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		vals[string(fd.Name())] = FormatValue(fd, v)
+		return true
+	})
+	if unknown := m.GetUnknown(); len(unknown) > 0 {
+		vals["<unknown>"] = fmt.Sprintf("%d bytes", len(unknown))
+	}
+	return vals
+}
+
+// FormatValue renders a field value compactly for human-readable output.
+func FormatValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) string {
+	switch {
+	case fd.IsList():
+		list := v.List()
+		parts := make([]string, list.Len())
+		for i := range parts {
+			parts[i] = formatScalar(fd, list.Get(i))
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	case fd.IsMap():
+		var parts []string
+		v.Map().Range(func(k protoreflect.MapKey, mv protoreflect.Value) bool {
+			parts = append(parts, fmt.Sprintf("%v: %s", k.Interface(), formatScalar(fd.MapValue(), mv)))
+			return true
+		})
+		sort.Strings(parts)
+		return "{" + strings.Join(parts, ", ") + "}"
+	default:
+		return formatScalar(fd, v)
+	}
+}
+
+func formatScalar(fd protoreflect.FieldDescriptor, v protoreflect.Value) string {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		if fd.Message().FullName() == "google.protobuf.Timestamp" {
+			m := v.Message()
+			fields := m.Descriptor().Fields()
+			t := time.Unix(m.Get(fields.ByNumber(1)).Int(), m.Get(fields.ByNumber(2)).Int())
+			return wire.Times.JSON(t)
+		}
+		b, err := MarshalJSON(v.Message().Interface())
+		if err != nil {
+			return fmt.Sprintf("<%v>", err)
+		}
+		return string(b)
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return string(ev.Name())
+		}
+		return fmt.Sprint(v.Enum())
+	case protoreflect.StringKind:
+		return fmt.Sprintf("%q", v.String())
+	case protoreflect.BytesKind:
+		return hex.EncodeToString(v.Bytes())
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
@@ -0,0 +1,203 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/example/protobuf-compat/internal/wire"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// MarshalJSON renders m as protojson, resolving types against Resolver,
+// with its google.protobuf.Timestamp values, nested ones included, in the
+// wire.Times format. Only with the default format is the result the
+// protobuf JSON mapping.
+func MarshalJSON(m proto.Message) ([]byte, error) {
+	out, err := protojson.MarshalOptions{Resolver: Resolver()}.Marshal(m)
+	if err != nil || wire.Times == (wire.TimeFormat{Layout: time.RFC3339Nano, Location: time.UTC}) {
+		return out, err
+	}
+	var buf bytes.Buffer
+	if err := retime(&buf, out, m.ProtoReflect().Descriptor()); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// customJSON lists the well-known types with a JSON mapping of their own
+// rather than an object of their fields.
+var customJSON = map[protoreflect.FullName]bool{
+	"google.protobuf.Any":         true,
+	"google.protobuf.Timestamp":   true,
+	"google.protobuf.Duration":    true,
+	"google.protobuf.FieldMask":   true,
+	"google.protobuf.Struct":      true,
+	"google.protobuf.Value":       true,
+	"google.protobuf.ListValue":   true,
+	"google.protobuf.Empty":       true,
+	"google.protobuf.BoolValue":   true,
+	"google.protobuf.Int32Value":  true,
+	"google.protobuf.Int64Value":  true,
+	"google.protobuf.UInt32Value": true,
+	"google.protobuf.UInt64Value": true,
+	"google.protobuf.FloatValue":  true,
+	"google.protobuf.DoubleValue": true,
+	"google.protobuf.StringValue": true,
+	"google.protobuf.BytesValue":  true,
+}
+
+// retime writes data, the protojson of a message of type md, to buf with
+// its timestamps in the wire.Times format.
+func retime(buf *bytes.Buffer, data []byte, md protoreflect.MessageDescriptor) error {
+	switch md.FullName() {
+	case "google.protobuf.Timestamp":
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(wire.Times.JSON(t))
+		return nil
+	case "google.protobuf.Any":
+		return retimeAny(buf, data)
+	}
+	if customJSON[md.FullName()] {
+		buf.Write(data)
+		return nil
+	}
+	return writeObject(buf, data, func(key string, value []byte) error {
+		return retimeField(buf, value, fieldByJSONName(md, key))
+	})
+}
+
+// retimeAny is retime for a google.protobuf.Any, whose JSON holds the
+// fields of the message it packs, or that message's own JSON mapping as
+// "value".
+func retimeAny(buf *bytes.Buffer, data []byte) error {
+	var packed struct {
+		Type string `json:"@type"`
+	}
+	if err := json.Unmarshal(data, &packed); err != nil {
+		return err
+	}
+	mt, err := Resolver().FindMessageByURL(packed.Type)
+	if err != nil {
+		buf.Write(data)
+		return nil
+	}
+	md := mt.Descriptor()
+	return writeObject(buf, data, func(key string, value []byte) error {
+		switch {
+		case key == "@type":
+			buf.Write(value)
+			return nil
+		case customJSON[md.FullName()]:
+			if key == "value" {
+				return retime(buf, value, md)
+			}
+			buf.Write(value)
+			return nil
+		}
+		return retimeField(buf, value, fieldByJSONName(md, key))
+	})
+}
+
+// retimeField writes value, the JSON of field fd, to buf with its
+// timestamps in the wire.Times format. A nil fd writes value unchanged.
+func retimeField(buf *bytes.Buffer, value []byte, fd protoreflect.FieldDescriptor) error {
+	switch {
+	case fd == nil || fd.Message() == nil:
+		buf.Write(value)
+		return nil
+	case fd.IsMap():
+		if fd.MapValue().Message() == nil {
+			buf.Write(value)
+			return nil
+		}
+		return writeObject(buf, value, func(_ string, v []byte) error {
+			return retime(buf, v, fd.MapValue().Message())
+		})
+	case fd.IsList():
+		return writeArray(buf, value, func(v []byte) error {
+			return retime(buf, v, fd.Message())
+		})
+	}
+	return retime(buf, value, fd.Message())
+}
+
+// fieldByJSONName returns the field of md that protojson names key: a
+// field by its JSON name, or an extension by its bracketed full name.
+func fieldByJSONName(md protoreflect.MessageDescriptor, key string) protoreflect.FieldDescriptor {
+	if fd := md.Fields().ByJSONName(key); fd != nil {
+		return fd
+	}
+	if name, ok := strings.CutPrefix(key, "["); ok {
+		if xt, err := Resolver().FindExtensionByName(protoreflect.FullName(strings.TrimSuffix(name, "]"))); err == nil {
+			return xt.TypeDescriptor()
+		}
+	}
+	return nil
+}
+
+// writeObject writes the JSON object data to buf, keeping the order of its
+// members and writing each member's value with fn.
+func writeObject(buf *bytes.Buffer, data []byte, fn func(key string, value []byte) error) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	buf.WriteByte('{')
+	for i := 0; dec.More(); i++ {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, _ := tok.(string)
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return err
+		}
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		buf.Write(k)
+		buf.WriteByte(':')
+		if err := fn(key, value); err != nil {
+			return err
+		}
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+// writeArray writes the JSON array data to buf, writing each element
+// with fn.
+func writeArray(buf *bytes.Buffer, data []byte, fn func(value []byte) error) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	buf.WriteByte('[')
+	for i := 0; dec.More(); i++ {
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return err
+		}
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if err := fn(value); err != nil {
+			return err
+		}
+	}
+	buf.WriteByte(']')
+	return nil
+}
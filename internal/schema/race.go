@@ -0,0 +1,167 @@
+package schema
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Candidate is a schema a payload might have been written with.
+type Candidate struct {
+	Name string
+	Type protoreflect.MessageType
+}
+
+// Attempt is the outcome of decoding a payload with one candidate.
+type Attempt struct {
+	Candidate
+	Message proto.Message
+	Err     error
+	// Unknown counts the bytes of unknown fields kept anywhere in Message.
+	Unknown int
+	// Populated counts the known fields set anywhere in Message.
+	Populated int
+	Elapsed   time.Duration
+}
+
+// Clean reports whether the decode succeeded without leaving unknown fields.
+func (a Attempt) Clean() bool { return a.Err == nil && a.Unknown == 0 }
+
+// TieBreak decides which clean attempt wins a Race.
+type TieBreak string
+
+const (
+	// FirstClean returns the first clean attempt to finish.
+	FirstClean TieBreak = "first"
+	// CandidateOrder prefers the earliest clean candidate in the list,
+	// returning as soon as every candidate before it has failed.
+	CandidateOrder TieBreak = "order"
+	// MostFields waits for all attempts and prefers the clean one that
+	// populated the most known fields, then candidate order.
+	MostFields TieBreak = "most-fields"
+)
+
+// Race decodes data with every candidate concurrently and picks a winner
+// among the clean attempts according to tie. It returns the winner (nil if
+// no attempt was clean) and the attempts that had finished by then, in
+// candidate order.
+func Race(ctx context.Context, data []byte, candidates []Candidate, tie TieBreak) (*Attempt, []Attempt, error) {
+	switch tie {
+	case FirstClean, CandidateOrder, MostFields:
+	default:
+		return nil, nil, fmt.Errorf("unknown tie-break %q (want first, order or most-fields)", tie)
+	}
+
+	type indexed struct {
+		i int
+		a Attempt
+	}
+	// Buffered so abandoned attempts can still finish and exit.
+	results := make(chan indexed, len(candidates))
+	for i, c := range candidates {
+		go func(i int, c Candidate) {
+			results <- indexed{i, attempt(c, data)}
+		}(i, c)
+	}
+
+	done := make([]*Attempt, len(candidates))
+	finished := func() []Attempt {
+		var out []Attempt
+		for _, a := range done {
+			if a != nil {
+				out = append(out, *a)
+			}
+		}
+		return out
+	}
+
+	for n := 0; n < len(candidates); n++ {
+		select {
+		case <-ctx.Done():
+			return nil, finished(), ctx.Err()
+		case r := <-results:
+			done[r.i] = &r.a
+			if w := decided(done, r.i, tie); w != nil {
+				return w, finished(), nil
+			}
+		}
+	}
+	return pickMostFields(done), finished(), nil
+}
+
+// decided returns the winner if it can already be known after attempt
+// last finished.
+func decided(done []*Attempt, last int, tie TieBreak) *Attempt {
+	switch tie {
+	case FirstClean:
+		if done[last].Clean() {
+			return done[last]
+		}
+	case CandidateOrder:
+		for _, a := range done {
+			if a == nil {
+				return nil // an earlier candidate may still win
+			}
+			if a.Clean() {
+				return a
+			}
+		}
+	}
+	return nil
+}
+
+func pickMostFields(done []*Attempt) *Attempt {
+	var best *Attempt
+	for _, a := range done {
+		if a != nil && a.Clean() && (best == nil || a.Populated > best.Populated) {
+			best = a
+		}
+	}
+	return best
+}
+
+func attempt(c Candidate, data []byte) Attempt {
+	start := time.Now()
+	a := Attempt{Candidate: c}
+	a.Message, a.Err = Decode(c.Type, data)
+	if a.Err == nil {
+		a.Unknown, a.Populated = census(a.Message.ProtoReflect())
+	}
+	a.Elapsed = time.Since(start)
+	return a
+}
+
+// census counts unknown-field bytes and populated known fields in m and
+// every message nested in it.
+func census(m protoreflect.Message) (unknown, populated int) {
+	unknown = len(m.GetUnknown())
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		populated++
+		var nested []protoreflect.Message
+		switch {
+		case fd.IsMap():
+			if fd.MapValue().Message() != nil {
+				v.Map().Range(func(_ protoreflect.MapKey, mv protoreflect.Value) bool {
+					nested = append(nested, mv.Message())
+					return true
+				})
+			}
+		case fd.IsList() && fd.Message() != nil:
+			for i := 0; i < v.List().Len(); i++ {
+				nested = append(nested, v.List().Get(i).Message())
+			}
+		case fd.Message() != nil:
+			nested = append(nested, v.Message())
+		}
+		for _, nm := range nested {
+			u, p := census(nm)
+			unknown += u
+			populated += p
+		}
+		return true
+	})
+	return unknown, populated
+}
@@ -0,0 +1,23 @@
+package schema
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// RoundTrip decodes data as mt and re-encodes the result deterministically.
+// Comparing the output with data shows whether the decode lost anything or
+// the producer's encoding was not canonical.
+func RoundTrip(mt protoreflect.MessageType, data []byte) ([]byte, error) {
+	msg, err := Decode(mt, data)
+	if err != nil {
+		return nil, err
+	}
+	out, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("re-encode as %s: %w", mt.Descriptor().FullName(), err)
+	}
+	return out, nil
+}
@@ -0,0 +1,60 @@
+// Package schema resolves the message types the inspection tools decode
+// against and compares how two schema versions see the same bytes.
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	// Register the demo schemas so they can be looked up by name.
+	_ "github.com/example/protobuf-compat/proto/v1"
+	_ "github.com/example/protobuf-compat/proto/v2"
+)
+
+// aliases maps the short names accepted on the command line to the
+// fully-qualified message names of the demo schemas.
+var aliases = map[string]protoreflect.FullName{
+	"v1": "example.v1.InfrastructureExecution",
+	"v2": "example.v2.InfrastructureExecution",
+}
+
+// Aliases returns the short schema names in sorted order.
+func Aliases() []string {
+	names := make([]string, 0, len(aliases))
+	for name := range aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ErrUnknown reports a schema name that names neither an alias nor a
+// loaded message type.
+var ErrUnknown = errors.New("unknown schema")
+
+// Lookup resolves a short alias ("v1", "v2") or a fully-qualified message
+// name against the loaded descriptor sets and the linked-in types.
+func Lookup(name string) (protoreflect.MessageType, error) {
+	full, ok := aliases[name]
+	if !ok {
+		full = protoreflect.FullName(name)
+	}
+	mt, err := Resolver().FindMessageByName(full)
+	if err != nil {
+		return nil, fmt.Errorf("%w %q: %w", ErrUnknown, name, err)
+	}
+	return mt, nil
+}
+
+// Decode unmarshals data into a new message of type mt.
+func Decode(mt protoreflect.MessageType, data []byte) (proto.Message, error) {
+	msg := mt.New().Interface()
+	if err := proto.Unmarshal(data, msg); err != nil {
+		return nil, fmt.Errorf("decode as %s: %w", mt.Descriptor().FullName(), err)
+	}
+	return msg, nil
+}
@@ -0,0 +1,65 @@
+package schema
+
+import (
+	"fmt"
+	"sort"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// UnknownBlob holds the unknown-field bytes of one message within a decoded
+// value.
+type UnknownBlob struct {
+	// Path locates the message: empty for the top-level message, otherwise
+	// dotted field names with list indexes and map keys in brackets, e.g.
+	// "steps[2].labels[env]".
+	Path string
+	Data []byte
+}
+
+// Unknown returns the unknown-field bytes of m and of every message nested
+// in it, in field-number order.
+func Unknown(m protoreflect.Message) []UnknownBlob {
+	var blobs []UnknownBlob
+	collectUnknown(m, "", &blobs)
+	return blobs
+}
+
+func collectUnknown(m protoreflect.Message, path string, blobs *[]UnknownBlob) {
+	if raw := m.GetUnknown(); len(raw) > 0 {
+		*blobs = append(*blobs, UnknownBlob{Path: path, Data: raw})
+	}
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if !m.Has(fd) {
+			continue
+		}
+		name := string(fd.Name())
+		if path != "" {
+			name = path + "." + name
+		}
+		v := m.Get(fd)
+		switch {
+		case fd.IsMap():
+			if fd.MapValue().Message() == nil {
+				continue
+			}
+			var keys []protoreflect.MapKey
+			v.Map().Range(func(k protoreflect.MapKey, _ protoreflect.Value) bool {
+				keys = append(keys, k)
+				return true
+			})
+			sort.Slice(keys, func(a, b int) bool { return keys[a].String() < keys[b].String() })
+			for _, k := range keys {
+				collectUnknown(v.Map().Get(k).Message(), fmt.Sprintf("%s[%s]", name, k), blobs)
+			}
+		case fd.IsList() && fd.Message() != nil:
+			for j := 0; j < v.List().Len(); j++ {
+				collectUnknown(v.List().Get(j).Message(), fmt.Sprintf("%s[%d]", name, j), blobs)
+			}
+		case fd.Message() != nil:
+			collectUnknown(v.Message(), name, blobs)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/example/protobuf-compat/internal/wire"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// IssueKind classifies a mismatch between a payload and a schema.
+type IssueKind string
+
+const (
+	// UnknownField: the payload has a field number the schema lacks.
+	UnknownField IssueKind = "unknown field"
+	// WireTypeMismatch: the field's wire type cannot encode its schema type.
+	WireTypeMismatch IssueKind = "wire type mismatch"
+	// InvalidUTF8: a string field holds bytes that are not valid UTF-8,
+	// which makes proto3 decoders reject the whole payload.
+	InvalidUTF8 IssueKind = "invalid UTF-8"
+	// MalformedMessage: a message-typed field does not parse.
+	MalformedMessage IssueKind = "malformed message"
+	// MissingField: a schema field never appears in the payload. This is
+	// informational; absent fields simply take their default value.
+	MissingField IssueKind = "missing field"
+	// DuplicateField: a singular field, or several members of one oneof,
+	// appear more than once. This is legal, but usually means messages were
+	// concatenated or merged: decoders keep the last scalar value and merge
+	// message values.
+	DuplicateField IssueKind = "duplicate field"
+)
+
+// Issue is one finding of Validate.
+type Issue struct {
+	Kind IssueKind
+	// Path names the field, using schema names where known and "#N" for
+	// field numbers the schema does not define, e.g. "started_at.#7".
+	Path   string
+	Offset int // -1 for missing fields
+	Detail string
+}
+
+func (i Issue) String() string {
+	if i.Offset < 0 {
+		return fmt.Sprintf("%s: %s (%s)", i.Kind, i.Path, i.Detail)
+	}
+	return fmt.Sprintf("%s: %s at byte %d (%s)", i.Kind, i.Path, i.Offset, i.Detail)
+}
+
+// Report is the result of validating a payload.
+type Report struct {
+	Message protoreflect.FullName
+	Issues  []Issue
+}
+
+// Mismatches counts the issues other than missing and duplicate fields,
+// which conformant decoders accept.
+func (r *Report) Mismatches() int {
+	n := 0
+	for _, i := range r.Issues {
+		if i.Kind != MissingField && i.Kind != DuplicateField {
+			n++
+		}
+	}
+	return n
+}
+
+// Validate checks data field by field against md, descending into
+// message-typed fields. It returns an error only if data is not wire
+// format at all.
+func Validate(md protoreflect.MessageDescriptor, data []byte) (*Report, error) {
+	fields, err := wire.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	r := &Report{Message: md.FullName()}
+	r.check(md, fields, "")
+	return r, nil
+}
+
+func (r *Report) add(kind IssueKind, path string, offset int, format string, args ...any) {
+	r.Issues = append(r.Issues, Issue{Kind: kind, Path: path, Offset: offset, Detail: fmt.Sprintf(format, args...)})
+}
+
+func (r *Report) check(md protoreflect.MessageDescriptor, fields []wire.Field, prefix string) {
+	seen := make(map[protowire.Number]bool)
+	singular := make(map[protoreflect.FullName][]wire.Field)
+	var singularOrder []protoreflect.FullName
+	for _, f := range fields {
+		seen[f.Number] = true
+		fd := md.Fields().ByNumber(f.Number)
+		if fd != nil && !fd.IsList() && !fd.IsMap() {
+			// Members of a oneof overwrite each other like a single field.
+			key := fd.FullName()
+			if od := fd.ContainingOneof(); od != nil && !od.IsSynthetic() {
+				key = od.FullName()
+			}
+			if singular[key] == nil {
+				singularOrder = append(singularOrder, key)
+			}
+			singular[key] = append(singular[key], f)
+		}
+		if fd == nil {
+			path := fmt.Sprintf("%s#%d", prefix, f.Number)
+			detail := "not in " + string(md.FullName())
+			if md.ReservedRanges().Has(f.Number) {
+				detail = "reserved in " + string(md.FullName())
+			}
+			r.add(UnknownField, path, f.Offset, "%s, wire type %s", detail, wire.TypeName(f.Type))
+			continue
+		}
+		path := prefix + string(fd.Name())
+
+		if !wireTypeFits(fd, f.Type) {
+			r.add(WireTypeMismatch, path, f.Offset, "%s field encoded as %s", fd.Kind(), wire.TypeName(f.Type))
+			continue
+		}
+		switch fd.Kind() {
+		case protoreflect.StringKind:
+			if f.Type == protowire.BytesType && !utf8.Valid(f.Value) {
+				r.add(InvalidUTF8, path, f.Offset, "%d bytes, starts %X", len(f.Value), head(f.Value))
+			}
+		case protoreflect.MessageKind, protoreflect.GroupKind:
+			nested, err := wire.ParseAt(f.Value, f.ValueOffset)
+			if err != nil {
+				r.add(MalformedMessage, path, f.Offset, "%v", err)
+				continue
+			}
+			r.check(fd.Message(), nested, path+".")
+		}
+	}
+
+	for _, key := range singularOrder {
+		if occ := singular[key]; len(occ) > 1 {
+			r.duplicates(md, occ, prefix)
+		}
+	}
+
+	for i := 0; i < md.Fields().Len(); i++ {
+		fd := md.Fields().Get(i)
+		if !seen[fd.Number()] {
+			r.add(MissingField, prefix+string(fd.Name()), -1, "field %d, %s", fd.Number(), fd.Kind())
+		}
+	}
+}
+
+// duplicates reports the occurrences of a singular field, or of the members
+// of a oneof, together with the value a conformant decoder ends up with.
+func (r *Report) duplicates(md protoreflect.MessageDescriptor, occ []wire.Field, prefix string) {
+	last := occ[len(occ)-1]
+	lastFD := md.Fields().ByNumber(last.Number)
+	values := make([]string, len(occ))
+	for i, f := range occ {
+		fd := md.Fields().ByNumber(f.Number)
+		values[i] = fmt.Sprintf("%s=%s at byte %d", fd.Name(), occurrenceValue(md, fd, f), f.Offset)
+	}
+	path := prefix + string(lastFD.Name())
+	if od := lastFD.ContainingOneof(); od != nil && !od.IsSynthetic() {
+		path = prefix + string(od.Name())
+	}
+	outcome := fmt.Sprintf("a decoder keeps the one at byte %d", last.Offset)
+	if lastFD.Message() != nil && md.Fields().ByNumber(occ[0].Number) == lastFD {
+		outcome = "a decoder merges the message values"
+	}
+	r.add(DuplicateField, path, occ[0].Offset, "%d occurrences: %s; %s",
+		len(occ), strings.Join(values, ", "), outcome)
+}
+
+// occurrenceValue decodes a single field occurrence on its own and
+// formats its value.
+func occurrenceValue(md protoreflect.MessageDescriptor, fd protoreflect.FieldDescriptor, f wire.Field) string {
+	b := protowire.AppendTag(nil, f.Number, f.Type)
+	switch f.Type {
+	case protowire.BytesType:
+		b = protowire.AppendBytes(b, f.Value)
+	case protowire.StartGroupType:
+		b = append(b, f.Value...)
+		b = protowire.AppendTag(b, f.Number, protowire.EndGroupType)
+	default:
+		b = append(b, f.Value...)
+	}
+	m := dynamicpb.NewMessage(md)
+	if err := proto.Unmarshal(b, m); err != nil || !m.Has(fd) {
+		return fmt.Sprintf("%X", f.Value)
+	}
+	return FormatValue(fd, m.Get(fd))
+}
+
+// wireTypeFits reports whether a field of fd's type may be encoded with
+// wire type t. Repeated scalars may appear packed or unpacked.
+func wireTypeFits(fd protoreflect.FieldDescriptor, t protowire.Type) bool {
+	if fd.IsList() && t == protowire.BytesType && isPackable(fd.Kind()) {
+		return true
+	}
+	switch fd.Kind() {
+	case protoreflect.BoolKind, protoreflect.EnumKind,
+		protoreflect.Int32Kind, protoreflect.Int64Kind,
+		protoreflect.Uint32Kind, protoreflect.Uint64Kind,
+		protoreflect.Sint32Kind, protoreflect.Sint64Kind:
+		return t == protowire.VarintType
+	case protoreflect.Fixed32Kind, protoreflect.Sfixed32Kind, protoreflect.FloatKind:
+		return t == protowire.Fixed32Type
+	case protoreflect.Fixed64Kind, protoreflect.Sfixed64Kind, protoreflect.DoubleKind:
+		return t == protowire.Fixed64Type
+	case protoreflect.GroupKind:
+		return t == protowire.StartGroupType
+	default: // string, bytes, message
+		return t == protowire.BytesType
+	}
+}
+
+func isPackable(k protoreflect.Kind) bool {
+	switch k {
+	case protoreflect.StringKind, protoreflect.BytesKind, protoreflect.MessageKind, protoreflect.GroupKind:
+		return false
+	}
+	return true
+}
+
+func head(b []byte) []byte {
+	if len(b) > 8 {
+		return b[:8]
+	}
+	return b
+}
@@ -0,0 +1,199 @@
+// Package source loads schemas from wherever they are kept: linked into
+// the binary, compiled into a FileDescriptorSet, written as .proto files,
+// or served by a running server's reflection service. Commands take any of
+// them where they take a schema.
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/example/protobuf-compat/internal/auth"
+	"github.com/example/protobuf-compat/internal/grpcreflect"
+	"github.com/example/protobuf-compat/internal/schema"
+)
+
+// Source is where the files of a schema come from.
+type Source interface {
+	// Files loads the files of the schema.
+	Files(ctx context.Context) ([]protoreflect.FileDescriptor, error)
+	// String returns the source as given on the command line.
+	String() string
+}
+
+// Options configure how sources are loaded.
+type Options struct {
+	// Auth authenticates calls to reflection services; nil for none.
+	Auth auth.Provider
+	// ImportPaths are searched for the imports of .proto files, after the
+	// directory named or the directories of the files named.
+	ImportPaths []string
+	// Protoc is the compiler of .proto files (default "protoc" on the
+	// PATH).
+	Protoc string
+}
+
+// Parse returns the source spec names, one of:
+//
+//   - grpc://host:port or grpcs://host:port, a server's reflection service
+//   - .proto files, separated by commas, or a directory holding some,
+//     compiled with protoc
+//   - any other file, a FileDescriptorSet (protoc --descriptor_set_out)
+//   - a schema name as accepted by schema.Lookup, for the linked-in file
+//     declaring it
+func Parse(spec string, opts Options) (Source, error) {
+	if grpcreflect.IsTarget(spec) {
+		client, err := grpcreflect.New(spec, opts.Auth)
+		if err != nil {
+			return nil, err
+		}
+		return &reflection{spec: spec, client: client}, nil
+	}
+	if strings.HasSuffix(spec, ".proto") || strings.Contains(spec, ".proto,") {
+		return &protoFiles{spec: spec, files: strings.Split(spec, ","), opts: opts}, nil
+	}
+	fi, err := os.Stat(spec)
+	switch {
+	case err == nil && fi.IsDir():
+		return &protoFiles{spec: spec, dir: spec, opts: opts}, nil
+	case err == nil:
+		return descriptorSet(spec), nil
+	}
+	if _, lookupErr := schema.Lookup(spec); lookupErr != nil {
+		if errors.Is(err, fs.ErrNotExist) && strings.ContainsAny(spec, `/\`) {
+			return nil, err // a path, not a name
+		}
+		return nil, lookupErr
+	}
+	return linked(spec), nil
+}
+
+// Load loads the files of the source spec names.
+func Load(ctx context.Context, spec string, opts Options) ([]protoreflect.FileDescriptor, error) {
+	src, err := Parse(spec, opts)
+	if err != nil {
+		return nil, err
+	}
+	return src.Files(ctx)
+}
+
+// IsLinked reports whether src is a schema linked into the binary.
+func IsLinked(src Source) bool {
+	_, ok := src.(linked)
+	return ok
+}
+
+// linked is the name of a linked-in schema.
+type linked string
+
+func (l linked) Files(context.Context) ([]protoreflect.FileDescriptor, error) {
+	return schema.Version(string(l))
+}
+
+func (l linked) String() string { return string(l) }
+
+// descriptorSet is the path of a FileDescriptorSet.
+type descriptorSet string
+
+func (d descriptorSet) Files(context.Context) ([]protoreflect.FileDescriptor, error) {
+	return schema.Version(string(d))
+}
+
+func (d descriptorSet) String() string { return string(d) }
+
+// reflection is a server serving the reflection service.
+type reflection struct {
+	spec   string
+	client *grpcreflect.Client
+}
+
+func (r *reflection) Files(ctx context.Context) ([]protoreflect.FileDescriptor, error) {
+	files, err := r.client.Files(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", r.spec, err)
+	}
+	return files, nil
+}
+
+func (r *reflection) String() string { return r.spec }
+
+// protoFiles are .proto files, named or in a directory.
+type protoFiles struct {
+	spec  string
+	files []string
+	dir   string
+	opts  Options
+}
+
+// Files compiles the files with protoc into a temporary descriptor set,
+// which keeps comments for the rules reporting on documentation.
+func (p *protoFiles) Files(ctx context.Context) ([]protoreflect.FileDescriptor, error) {
+	files, importPaths := p.files, []string(nil)
+	if p.dir != "" {
+		var err error
+		if files, err = protoFilesIn(p.dir); err != nil {
+			return nil, err
+		}
+		importPaths = append(importPaths, p.dir)
+	} else {
+		for _, f := range files {
+			if dir := filepath.Dir(f); !slices.Contains(importPaths, dir) {
+				importPaths = append(importPaths, dir)
+			}
+		}
+	}
+	importPaths = append(importPaths, p.opts.ImportPaths...)
+
+	out, err := os.CreateTemp("", "protocompat-*.pb")
+	if err != nil {
+		return nil, err
+	}
+	out.Close()
+	defer os.Remove(out.Name())
+	args := []string{"--include_imports", "--include_source_info", "--descriptor_set_out=" + out.Name()}
+	for _, dir := range importPaths {
+		args = append(args, "--proto_path="+dir)
+	}
+	protoc := p.opts.Protoc
+	if protoc == "" {
+		protoc = "protoc"
+	}
+	cmd := exec.CommandContext(ctx, protoc, append(args, files...)...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %s", p.spec, msg)
+		}
+		return nil, fmt.Errorf("%s: %s: %w", p.spec, protoc, err)
+	}
+	return schema.Version(out.Name())
+}
+
+func (p *protoFiles) String() string { return p.spec }
+
+// protoFilesIn returns the .proto files under dir, sorted.
+func protoFilesIn(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && strings.HasSuffix(path, ".proto") {
+			files = append(files, path)
+		}
+		return err
+	})
+	if err == nil && len(files) == 0 {
+		err = fmt.Errorf("%s: no .proto files", dir)
+	}
+	sort.Strings(files)
+	return files, err
+}
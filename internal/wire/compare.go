@@ -0,0 +1,146 @@
+package wire
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// DifferenceKind classifies a Difference.
+type DifferenceKind int
+
+const (
+	FieldAdded DifferenceKind = iota
+	FieldRemoved
+	FieldChanged
+	FieldsReordered
+)
+
+// Difference is one way in which two encodings of a message differ.
+type Difference struct {
+	Kind DifferenceKind
+	// Path locates the field as dotted field numbers, with the occurrence
+	// index in brackets when the field occurs more than once, e.g. "3[1].2".
+	// It is empty for differences in the message itself.
+	Path string
+	// OldOffset and NewOffset are the offsets of the field in each payload,
+	// or -1 where it is absent.
+	OldOffset, NewOffset int
+	Detail               string
+}
+
+func (d Difference) String() string {
+	var at string
+	switch {
+	case d.Kind == FieldsReordered:
+	case d.OldOffset < 0:
+		at = fmt.Sprintf(" [new@%d]", d.NewOffset)
+	case d.NewOffset < 0:
+		at = fmt.Sprintf(" [old@%d]", d.OldOffset)
+	default:
+		at = fmt.Sprintf(" [old@%d new@%d]", d.OldOffset, d.NewOffset)
+	}
+	if d.Path == "" {
+		return d.Detail + at
+	}
+	return d.Path + ": " + d.Detail + at
+}
+
+// Compare reports how the fields of b differ from those of a, descending
+// into nested messages. It distinguishes reordered fields, changed wire
+// types (e.g. packed versus unpacked), non-minimal varints and changed or
+// missing values.
+func Compare(a, b []Field) []Difference {
+	var diffs []Difference
+	compareFields("", a, b, &diffs)
+	return diffs
+}
+
+func compareFields(path string, a, b []Field, diffs *[]Difference) {
+	orderA, byNumA := groupByNumber(a)
+	orderB, byNumB := groupByNumber(b)
+	if slices.Equal(sortedNumbers(orderA), sortedNumbers(orderB)) && !slices.Equal(orderA, orderB) {
+		*diffs = append(*diffs, Difference{Kind: FieldsReordered, Path: path, OldOffset: -1, NewOffset: -1,
+			Detail: fmt.Sprintf("fields reordered: %v -> %v", orderA, orderB)})
+	}
+
+	numbers := sortedNumbers(append(slices.Clone(orderA), orderB...))
+	for _, n := range slices.Compact(numbers) {
+		occA, occB := byNumA[n], byNumB[n]
+		prefix := fmt.Sprint(n)
+		if path != "" {
+			prefix = path + "." + prefix
+		}
+		for i := 0; i < max(len(occA), len(occB)); i++ {
+			p := prefix
+			if len(occA) > 1 || len(occB) > 1 {
+				p = fmt.Sprintf("%s[%d]", prefix, i)
+			}
+			switch {
+			case i >= len(occB):
+				*diffs = append(*diffs, Difference{Kind: FieldRemoved, Path: p, OldOffset: occA[i].Offset, NewOffset: -1,
+					Detail: "removed " + formatValue(occA[i])})
+			case i >= len(occA):
+				*diffs = append(*diffs, Difference{Kind: FieldAdded, Path: p, OldOffset: -1, NewOffset: occB[i].Offset,
+					Detail: "added " + formatValue(occB[i])})
+			default:
+				compareField(p, occA[i], occB[i], diffs)
+			}
+		}
+	}
+}
+
+func compareField(path string, a, b Field, diffs *[]Difference) {
+	changed := func(detail string) {
+		*diffs = append(*diffs, Difference{Kind: FieldChanged, Path: path, OldOffset: a.Offset, NewOffset: b.Offset, Detail: detail})
+	}
+	switch {
+	case a.Type != b.Type:
+		changed(fmt.Sprintf("wire type %s -> %s: %s -> %s", TypeName(a.Type), TypeName(b.Type), formatValue(a), formatValue(b)))
+	case bytes.Equal(a.Value, b.Value):
+	case a.Type == protowire.VarintType && a.Varint == b.Varint:
+		changed(fmt.Sprintf("varint %d re-encoded from %d to %d bytes", a.Varint, len(a.Value), len(b.Value)))
+	case len(a.Nested) > 0 && len(b.Nested) > 0 && !IsText(a.Value) && !IsText(b.Value):
+		compareFields(path, a.Nested, b.Nested, diffs)
+	default:
+		changed(fmt.Sprintf("%s -> %s", formatValue(a), formatValue(b)))
+	}
+}
+
+// formatValue renders a field value compactly for a Difference.
+func formatValue(f Field) string {
+	switch {
+	case f.Type == protowire.VarintType || f.Type == protowire.Fixed32Type || f.Type == protowire.Fixed64Type:
+		return fmt.Sprint(f.Varint)
+	case len(f.Value) == 0:
+		return `""`
+	case IsText(f.Value):
+		return fmt.Sprintf("%q", f.Value)
+	case len(f.Nested) > 0:
+		return fmt.Sprintf("message %X", f.Value)
+	default:
+		return fmt.Sprintf("%X", f.Value)
+	}
+}
+
+// groupByNumber returns the field numbers of fields in order of first
+// appearance, and the occurrences of each.
+func groupByNumber(fields []Field) ([]protowire.Number, map[protowire.Number][]Field) {
+	var order []protowire.Number
+	byNum := make(map[protowire.Number][]Field)
+	for _, f := range fields {
+		if byNum[f.Number] == nil {
+			order = append(order, f.Number)
+		}
+		byNum[f.Number] = append(byNum[f.Number], f)
+	}
+	return order, byNum
+}
+
+func sortedNumbers(numbers []protowire.Number) []protowire.Number {
+	s := slices.Clone(numbers)
+	slices.Sort(s)
+	return s
+}
@@ -0,0 +1,100 @@
+package wire
+
+import (
+	"fmt"
+	"slices"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// MaxCardinality bounds the number of distinct values tracked per field, so
+// that high-cardinality fields such as IDs do not hold a whole corpus in
+// memory.
+const MaxCardinality = 1000
+
+// FieldStats summarises one field across a corpus of payloads. Fields of
+// nested messages are tracked separately under their path.
+type FieldStats struct {
+	// Path is the field-number path from the top-level message, e.g. [5 1].
+	Path []protowire.Number
+	// Types lists the wire types seen, in the order first seen.
+	Types []protowire.Type
+
+	// Messages counts the payloads containing the field at least once, and
+	// Occurrences every occurrence of it.
+	Messages    int
+	Occurrences int
+
+	// MinSize and MaxSize bound the size in bytes of the field's values.
+	MinSize, MaxSize int
+
+	values map[string]bool
+	// lastMessage is the index of the last payload the field was seen in.
+	lastMessage int
+}
+
+// PathString returns Path in dotted form, e.g. "5.1".
+func (s *FieldStats) PathString() string {
+	return FormatPath(s.Path)
+}
+
+// Cardinality returns the number of distinct values seen and whether it
+// reached MaxCardinality, in which case there may be more.
+func (s *FieldStats) Cardinality() (n int, capped bool) {
+	return len(s.values), len(s.values) >= MaxCardinality
+}
+
+// Corpus accumulates field statistics over many payloads.
+type Corpus struct {
+	// Messages counts the payloads added.
+	Messages int
+
+	fields map[string]*FieldStats
+}
+
+// Add records the fields of one payload.
+func (c *Corpus) Add(fields []Field) {
+	if c.fields == nil {
+		c.fields = make(map[string]*FieldStats)
+	}
+	c.Messages++
+	c.add(nil, fields)
+}
+
+func (c *Corpus) add(path []protowire.Number, fields []Field) {
+	for _, f := range fields {
+		p := append(slices.Clip(path), f.Number)
+		key := fmt.Sprint(p)
+		s := c.fields[key]
+		if s == nil {
+			s = &FieldStats{Path: p, MinSize: len(f.Value), values: make(map[string]bool)}
+			c.fields[key] = s
+		}
+		if s.lastMessage != c.Messages {
+			s.lastMessage = c.Messages
+			s.Messages++
+		}
+		s.Occurrences++
+		if !slices.Contains(s.Types, f.Type) {
+			s.Types = append(s.Types, f.Type)
+		}
+		s.MinSize = min(s.MinSize, len(f.Value))
+		s.MaxSize = max(s.MaxSize, len(f.Value))
+		if len(s.values) < MaxCardinality {
+			s.values[string(f.Value)] = true
+		}
+		if len(f.Nested) > 0 && !IsText(f.Value) {
+			c.add(p, f.Nested)
+		}
+	}
+}
+
+// Fields returns the statistics of every field seen, ordered by path.
+func (c *Corpus) Fields() []*FieldStats {
+	stats := make([]*FieldStats, 0, len(c.fields))
+	for _, s := range c.fields {
+		stats = append(stats, s)
+	}
+	slices.SortFunc(stats, func(a, b *FieldStats) int { return slices.Compare(a.Path, b.Path) })
+	return stats
+}
@@ -0,0 +1,46 @@
+package wire
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+)
+
+// Frame is one message of a length-delimited stream.
+type Frame struct {
+	Index  int // 0-based position in the stream
+	Offset int // offset of the length prefix in the stream
+	Data   []byte
+}
+
+// ReadDelimited iterates over a stream of messages written back-to-back
+// with a varint length prefix each, as produced by protodelim.MarshalTo or
+// Java's writeDelimitedTo, and calls fn for every message until ctx is
+// done. Messages larger than maxSize bytes are rejected.
+func ReadDelimited(ctx context.Context, r io.Reader, maxSize int, fn func(Frame) error) error {
+	s := &streamParser{r: bufio.NewReader(r)}
+	for i := 0; ; i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		start := s.off
+		size, err := s.varint()
+		if err == io.EOF && s.off == start {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("message %d: %w", i, locate(start, 0, fmt.Errorf("bad length prefix: %w", noEOF(err))))
+		}
+		if size > uint64(maxSize) {
+			return fmt.Errorf("message %d at offset %d: size %d exceeds limit %d", i, start, size, maxSize)
+		}
+		data, err := s.read(int(size))
+		if err != nil {
+			return fmt.Errorf("message %d: %w", i, locate(start, 0, noEOF(err)))
+		}
+		if err := fn(Frame{Index: i, Offset: start, Data: data}); err != nil {
+			return err
+		}
+	}
+}
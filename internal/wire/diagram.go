@@ -0,0 +1,83 @@
+package wire
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DiagramFormats lists the formats InferDiagram can produce.
+var DiagramFormats = []string{"mermaid", "dot"}
+
+// InferDiagram renders the message structure inferred from samples, as by
+// InferProto, as a Mermaid class diagram or a Graphviz DOT graph: one box
+// per message listing its fields, repeated fields annotated with the most
+// occurrences seen in a sample, and an arrow to each nested message.
+func InferDiagram(format, name string, samples ...[]Field) (string, error) {
+	msg := inferMessage(name, samples, make(map[string]bool))
+	var b strings.Builder
+	switch format {
+	case "mermaid":
+		b.WriteString("classDiagram\n")
+		msg.writeMermaid(&b, name)
+	case "dot":
+		fmt.Fprintf(&b, "digraph %q {\n  node [shape=record, fontname=monospace];\n", name)
+		msg.writeDot(&b, name)
+		b.WriteString("}\n")
+	default:
+		return "", fmt.Errorf("unknown diagram format %q (want %s)", format, strings.Join(DiagramFormats, " or "))
+	}
+	return b.String(), nil
+}
+
+// memberText describes a field for a diagram box.
+func (f *inferredField) memberText() string {
+	s := fmt.Sprintf("%s field_%d = %d", f.typ, f.number, f.number)
+	if f.repeated {
+		s = fmt.Sprintf("repeated %s ×%d", s, f.count)
+	} else if strings.HasPrefix(f.typ, "map<") {
+		s = fmt.Sprintf("%s ×%d", s, f.count)
+	}
+	return s
+}
+
+// writeMermaid writes m and its nested messages. Nested message names such
+// as Field5 are only unique within their parent, so node IDs are built from
+// the path of names from the top-level message.
+func (m *inferredMessage) writeMermaid(b *strings.Builder, id string) {
+	mermaidID := strings.ReplaceAll(id, ".", "_")
+	fmt.Fprintf(b, "  class %s[\"%s\"] {\n", mermaidID, m.name)
+	for _, f := range m.fields {
+		// Mermaid writes generics with tildes.
+		text := strings.NewReplacer("<", "~", ">", "~").Replace(f.memberText())
+		fmt.Fprintf(b, "    %s\n", text)
+	}
+	b.WriteString("  }\n")
+	for _, f := range m.fields {
+		if f.nested == nil {
+			continue
+		}
+		childID := id + "." + f.nested.name
+		fmt.Fprintf(b, "  %s --> %s : field_%d\n", mermaidID, strings.ReplaceAll(childID, ".", "_"), f.number)
+		f.nested.writeMermaid(b, childID)
+	}
+}
+
+// dotEscaper escapes the characters that are special in record labels.
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "<", `\<`, ">", `\>`, "{", `\{`, "}", `\}`, "|", `\|`)
+
+// writeDot is the DOT counterpart of writeMermaid.
+func (m *inferredMessage) writeDot(b *strings.Builder, id string) {
+	label := dotEscaper.Replace(m.name)
+	for _, f := range m.fields {
+		label += "|" + dotEscaper.Replace(f.memberText()) + `\l`
+	}
+	fmt.Fprintf(b, "  %q [label=\"{%s}\"];\n", id, label)
+	for _, f := range m.fields {
+		if f.nested == nil {
+			continue
+		}
+		childID := id + "." + f.nested.name
+		fmt.Fprintf(b, "  %q -> %q [label=\"field_%d\"];\n", id, childID, f.number)
+		f.nested.writeDot(b, childID)
+	}
+}
@@ -0,0 +1,187 @@
+package wire
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Value is a field value ready to be written: its wire type and encoding.
+// For length-delimited values Bytes holds the contents, without the length
+// prefix.
+type Value struct {
+	Type  protowire.Type
+	Bytes []byte
+}
+
+// ParseValue parses a typed value written as KIND:TEXT, where KIND is one
+// of varint, sint (zigzag), bool, fixed32, fixed64, float, double, string,
+// or bytes / message (both hex).
+func ParseValue(spec string) (Value, error) {
+	kind, text, ok := strings.Cut(spec, ":")
+	if !ok {
+		return Value{}, fmt.Errorf("value %q: want KIND:VALUE, e.g. varint:42 or string:hello", spec)
+	}
+	var v Value
+	switch kind {
+	case "varint":
+		n, err := parseInteger(text)
+		if err != nil {
+			return Value{}, err
+		}
+		v = Value{protowire.VarintType, protowire.AppendVarint(nil, n)}
+	case "sint":
+		n, err := strconv.ParseInt(text, 0, 64)
+		if err != nil {
+			return Value{}, err
+		}
+		v = Value{protowire.VarintType, protowire.AppendVarint(nil, protowire.EncodeZigZag(n))}
+	case "bool":
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return Value{}, err
+		}
+		v = Value{protowire.VarintType, protowire.AppendVarint(nil, protowire.EncodeBool(b))}
+	case "fixed32":
+		n, err := parseInteger(text)
+		if err != nil {
+			return Value{}, err
+		}
+		v = Value{protowire.Fixed32Type, protowire.AppendFixed32(nil, uint32(n))}
+	case "fixed64":
+		n, err := parseInteger(text)
+		if err != nil {
+			return Value{}, err
+		}
+		v = Value{protowire.Fixed64Type, protowire.AppendFixed64(nil, n)}
+	case "float":
+		f, err := strconv.ParseFloat(text, 32)
+		if err != nil {
+			return Value{}, err
+		}
+		v = Value{protowire.Fixed32Type, protowire.AppendFixed32(nil, math.Float32bits(float32(f)))}
+	case "double":
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return Value{}, err
+		}
+		v = Value{protowire.Fixed64Type, protowire.AppendFixed64(nil, math.Float64bits(f))}
+	case "string":
+		v = Value{protowire.BytesType, []byte(text)}
+	case "bytes", "message":
+		b, err := hex.DecodeString(text)
+		if err != nil {
+			return Value{}, fmt.Errorf("value %q: %w", spec, err)
+		}
+		v = Value{protowire.BytesType, b}
+	default:
+		return Value{}, fmt.Errorf("value %q: unknown kind %q", spec, kind)
+	}
+	return v, nil
+}
+
+// parseInteger accepts unsigned and negative integers; negative numbers are
+// encoded in two's complement, as int32 and int64 fields are.
+func parseInteger(text string) (uint64, error) {
+	if strings.HasPrefix(text, "-") {
+		n, err := strconv.ParseInt(text, 0, 64)
+		return uint64(n), err
+	}
+	return strconv.ParseUint(text, 0, 64)
+}
+
+// ParsePath parses a dotted field-number path such as "5.1".
+func ParsePath(s string) ([]protowire.Number, error) {
+	var path []protowire.Number
+	for _, part := range strings.Split(s, ".") {
+		n, err := strconv.ParseUint(part, 10, 32)
+		if err != nil || !protowire.Number(n).IsValid() {
+			return nil, fmt.Errorf("bad field path %q", s)
+		}
+		path = append(path, protowire.Number(n))
+	}
+	return path, nil
+}
+
+// EditOp selects what an edit does to the field it addresses.
+type EditOp int
+
+const (
+	// Set replaces every occurrence of the field with a single new one at
+	// the position of the first, or appends it if the field is absent.
+	Set EditOp = iota
+	// Replace is like Set but fails if the field is absent.
+	Replace
+	// Delete removes every occurrence of the field.
+	Delete
+)
+
+// Edit applies op to the field at path within data and returns the new
+// encoding, leaving the bytes of every other field untouched. Intermediate
+// path elements must be embedded messages; Set creates missing ones. v is
+// ignored by Delete.
+func Edit(data []byte, path []protowire.Number, op EditOp, v Value) ([]byte, error) {
+	out, found, err := edit(data, path, op, v)
+	if err != nil {
+		return nil, err
+	}
+	if !found && op != Set {
+		return nil, fmt.Errorf("field %s not found", FormatPath(path))
+	}
+	return out, nil
+}
+
+func edit(data []byte, path []protowire.Number, op EditOp, v Value) ([]byte, bool, error) {
+	fields, err := Parse(data)
+	if err != nil {
+		return nil, false, err
+	}
+	var out []byte
+	found := false
+	for _, f := range fields {
+		if f.Number != path[0] {
+			out = append(out, data[f.Offset:f.End]...)
+			continue
+		}
+		if len(path) == 1 {
+			if op != Delete && !found {
+				out = appendValue(out, f.Number, v)
+			}
+			found = true
+			continue
+		}
+		if f.Type != protowire.BytesType || len(f.Value) > 0 && len(f.Nested) == 0 {
+			return nil, false, fmt.Errorf("offset %d: field %d does not hold a message", f.Offset, f.Number)
+		}
+		inner, ok, err := edit(f.Value, path[1:], op, v)
+		if err != nil {
+			return nil, false, fmt.Errorf("field %d: %w", f.Number, err)
+		}
+		found = found || ok
+		out = protowire.AppendTag(out, f.Number, protowire.BytesType)
+		out = protowire.AppendBytes(out, inner)
+	}
+	if !found && op == Set {
+		if len(path) == 1 {
+			out = appendValue(out, path[0], v)
+		} else {
+			inner, _, _ := edit(nil, path[1:], op, v)
+			out = protowire.AppendTag(out, path[0], protowire.BytesType)
+			out = protowire.AppendBytes(out, inner)
+		}
+		found = true
+	}
+	return out, found, nil
+}
+
+func appendValue(b []byte, n protowire.Number, v Value) []byte {
+	b = protowire.AppendTag(b, n, v.Type)
+	if v.Type == protowire.BytesType {
+		return protowire.AppendBytes(b, v.Bytes)
+	}
+	return append(b, v.Bytes...)
+}
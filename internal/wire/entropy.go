@@ -0,0 +1,93 @@
+package wire
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// minEntropyBytes is the shortest value whose entropy says anything: in
+// shorter values even packed integers rarely repeat a byte and look random.
+const minEntropyBytes = 128
+
+// randomRatio is the fraction of the highest possible entropy above which
+// bytes are taken for compressed or encrypted data. Packed integers and
+// C structs stay well below it because of their zero and sign bytes.
+const randomRatio = 0.87
+
+// magicNumbers identifies compressed formats by their leading bytes.
+var magicNumbers = []struct {
+	prefix []byte
+	name   string
+}{
+	{[]byte{0x1f, 0x8b}, "gzip"},
+	{[]byte{0x28, 0xb5, 0x2f, 0xfd}, "zstd"},
+	{[]byte{0x04, 0x22, 0x4d, 0x18}, "lz4"},
+	{[]byte("BZh"), "bzip2"},
+	{[]byte{0xfd, '7', 'z', 'X', 'Z', 0x00}, "xz"},
+	{[]byte("PK\x03\x04"), "zip"},
+}
+
+// BytesGuess describes what an opaque bytes value most likely holds.
+type BytesGuess struct {
+	// Entropy is the Shannon entropy in bits per byte, between 0 and 8.
+	Entropy float64
+	// Random reports that the bytes are indistinguishable from random
+	// ones, as compressed and encrypted data are.
+	Random bool
+	// Format names a compression format recognised by its magic number.
+	Format string
+}
+
+// String renders the guess with a hint for where to look next.
+func (g BytesGuess) String() string {
+	switch {
+	case g.Format != "":
+		return fmt.Sprintf("entropy %.2f bits/byte, %s-compressed data", g.Entropy, g.Format)
+	case g.Random:
+		return fmt.Sprintf("entropy %.2f bits/byte, likely compressed or encrypted data", g.Entropy)
+	default:
+		return fmt.Sprintf("entropy %.2f bits/byte, likely a binary struct or packed values", g.Entropy)
+	}
+}
+
+// Entropy returns the Shannon entropy of b in bits per byte.
+func Entropy(b []byte) float64 {
+	var counts [256]int
+	for _, c := range b {
+		counts[c]++
+	}
+	h := 0.0
+	for _, n := range counts {
+		if n > 0 {
+			p := float64(n) / float64(len(b))
+			h -= p * math.Log2(p)
+		}
+	}
+	return h
+}
+
+// GuessBytes classifies the value of a length-delimited field that is
+// neither text nor a message. It reports false for other fields, and for
+// values too short to judge unless they start with a magic number.
+func GuessBytes(f Field) (BytesGuess, bool) {
+	if f.Type != protowire.BytesType || len(f.Value) == 0 || IsText(f.Value) || len(f.Nested) > 0 {
+		return BytesGuess{}, false
+	}
+	g := BytesGuess{Entropy: Entropy(f.Value)}
+	for _, m := range magicNumbers {
+		if bytes.HasPrefix(f.Value, m.prefix) {
+			g.Format = m.name
+		}
+	}
+	if g.Format == "" && len(f.Value) < minEntropyBytes {
+		return BytesGuess{}, false
+	}
+	// n bytes can show at most log2(n) bits of entropy per byte, so short
+	// values are measured against that rather than against 8.
+	highest := math.Log2(float64(min(len(f.Value), 256)))
+	g.Random = g.Entropy >= randomRatio*highest
+	return g, true
+}
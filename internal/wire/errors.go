@@ -0,0 +1,91 @@
+package wire
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Malformed payloads fail with a *ParseError locating the problem, wrapping
+// one of these when it is among them, so that callers can tell bad input
+// from other failures, such as those reading it, with errors.As and
+// errors.Is.
+var (
+	// ErrTruncated reports input ending inside a field or length prefix,
+	// or a length running past the end of its enclosing message.
+	ErrTruncated = errors.New("unexpected end of data")
+	// ErrInvalidWireType reports a tag with wire type 6 or 7, or an end
+	// group without its start.
+	ErrInvalidWireType = errors.New("invalid wire type")
+	// ErrInvalidFieldNumber reports a tag with field number 0, or one above
+	// the largest valid number.
+	ErrInvalidFieldNumber = errors.New("invalid field number")
+	// ErrVarintOverflow reports a varint longer than ten bytes.
+	ErrVarintOverflow = errors.New("variable length integer overflow")
+	// ErrDepthExceeded reports messages or groups nested deeper than the
+	// parser's limit.
+	ErrDepthExceeded = errors.New("maximum nesting depth exceeded")
+)
+
+// ParseError is a malformed payload.
+type ParseError struct {
+	// Offset is that of the field, or length prefix, that could not be
+	// parsed.
+	Offset int
+	// Field is its number, 0 if its tag could not be read.
+	Field protowire.Number
+	// Err is why, wrapping one of the Err values of this package if any
+	// applies.
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	if e.Field != 0 {
+		return fmt.Sprintf("offset %d: field %d: %v", e.Offset, e.Field, e.Err)
+	}
+	return fmt.Sprintf("offset %d: %v", e.Offset, e.Err)
+}
+
+func (e *ParseError) Unwrap() error { return e.Err }
+
+// consumeError returns the error for code n, which a protowire Consume
+// function failed with.
+func consumeError(n int) error {
+	// protowire's codes, which it does not name.
+	switch n {
+	case -1:
+		return ErrTruncated
+	case -2:
+		return ErrInvalidFieldNumber
+	case -3:
+		return ErrVarintOverflow
+	case -5:
+		return fmt.Errorf("%w: mismatching end group marker", ErrInvalidWireType)
+	}
+	return protowire.ParseError(n)
+}
+
+// noEOF turns a clean EOF inside a field into truncation.
+func noEOF(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return ErrTruncated
+	}
+	return err
+}
+
+// locate returns err, which parsing the field at offset with number num
+// failed with, as a *ParseError if it is one of malformed input and is
+// not located yet; failures to read the input are only given the offset.
+func locate(offset int, num protowire.Number, err error) error {
+	var pe *ParseError
+	switch {
+	case errors.As(err, &pe):
+		return err
+	case errors.Is(err, ErrTruncated), errors.Is(err, ErrInvalidWireType), errors.Is(err, ErrInvalidFieldNumber),
+		errors.Is(err, ErrVarintOverflow), errors.Is(err, ErrDepthExceeded):
+		return &ParseError{Offset: offset, Field: num, Err: err}
+	}
+	return fmt.Errorf("offset %d: %w", offset, err)
+}
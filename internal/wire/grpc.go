@@ -0,0 +1,94 @@
+package wire
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// grpcHeaderLen is the size of the prefix gRPC puts before every message:
+// a compressed flag byte and a 4-byte big-endian length.
+const grpcHeaderLen = 5
+
+// MaxGRPCMessage bounds the size SplitGRPC decompresses a frame to, that of
+// the largest message gRPC implementations receive by default, so that a
+// small compressed frame cannot expand to exhaust memory.
+const MaxGRPCMessage = 4 << 20
+
+// GRPCFrame is one message of a gRPC-framed payload.
+type GRPCFrame struct {
+	Offset     int  // offset of the frame header in the payload
+	Compressed bool // the sender set the compressed flag
+	// Data holds the message, decompressed if Compressed.
+	Data []byte
+}
+
+// IsGRPCFramed reports whether data looks like one or more gRPC messages
+// with their 5-byte headers rather than a bare message. The check is safe
+// against false positives from bare messages: a flag byte of 0 or 1 would
+// be a tag for field number 0, which no valid message contains. Only the
+// headers are checked; compressed frames are not decompressed.
+func IsGRPCFramed(data []byte) bool {
+	if len(data) < grpcHeaderLen {
+		return false
+	}
+	_, err := splitGRPC(data, false)
+	return err == nil
+}
+
+// SplitGRPC splits data into the gRPC frames it consists of, which must
+// cover it exactly. Compressed frames are decompressed, up to
+// MaxGRPCMessage bytes; gzip, the only encoding every gRPC implementation
+// supports, is the one understood here.
+func SplitGRPC(data []byte) ([]GRPCFrame, error) {
+	return splitGRPC(data, true)
+}
+
+// splitGRPC is SplitGRPC, leaving compressed frames as they are unless
+// inflate is set.
+func splitGRPC(data []byte, inflate bool) ([]GRPCFrame, error) {
+	var frames []GRPCFrame
+	for off := 0; off < len(data); {
+		if len(data)-off < grpcHeaderLen {
+			return frames, &ParseError{Offset: off, Err: fmt.Errorf("gRPC frame header: %w", ErrTruncated)}
+		}
+		flag := data[off]
+		if flag > 1 {
+			return frames, &ParseError{Offset: off, Err: fmt.Errorf("invalid gRPC compressed flag %d", flag)}
+		}
+		size := binary.BigEndian.Uint32(data[off+1:])
+		start := off + grpcHeaderLen
+		if uint64(size) > uint64(len(data)-start) {
+			return frames, &ParseError{Offset: off, Err: fmt.Errorf("gRPC frame of %d bytes exceeds the remaining %d: %w", size, len(data)-start, ErrTruncated)}
+		}
+		f := GRPCFrame{Offset: off, Compressed: flag == 1, Data: data[start : start+int(size)]}
+		if f.Compressed && inflate {
+			inner, err := gunzip(f.Data)
+			if err != nil {
+				return frames, fmt.Errorf("offset %d: compressed gRPC frame: %w", off, err)
+			}
+			f.Data = inner
+		}
+		frames = append(frames, f)
+		off = start + int(size)
+	}
+	return frames, nil
+}
+
+func gunzip(data []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("not gzip, the grpc-encoding header names the algorithm: %w", err)
+	}
+	defer zr.Close()
+	data, err = io.ReadAll(io.LimitReader(zr, MaxGRPCMessage+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxGRPCMessage {
+		return nil, fmt.Errorf("decompresses to more than %d bytes", MaxGRPCMessage)
+	}
+	return data, nil
+}
@@ -0,0 +1,63 @@
+package wire
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"testing"
+)
+
+// grpcFrame returns msg with its gRPC header, gzip-compressed if compress.
+func grpcFrame(t *testing.T, msg []byte, compress bool) []byte {
+	t.Helper()
+	flag := byte(0)
+	if compress {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(msg); err != nil {
+			t.Fatal(err)
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		msg, flag = buf.Bytes(), 1
+	}
+	return append(binary.BigEndian.AppendUint32([]byte{flag}, uint32(len(msg))), msg...)
+}
+
+func TestSplitGRPC(t *testing.T) {
+	msg := mustHex(t, "0801")
+	data := append(grpcFrame(t, msg, false), grpcFrame(t, msg, true)...)
+	if !IsGRPCFramed(data) {
+		t.Fatal("IsGRPCFramed = false")
+	}
+	frames, err := SplitGRPC(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frames) != 2 || !bytes.Equal(frames[0].Data, msg) || !bytes.Equal(frames[1].Data, msg) || !frames[1].Compressed {
+		t.Errorf("frames = %+v", frames)
+	}
+}
+
+func TestSplitGRPCBomb(t *testing.T) {
+	data := grpcFrame(t, make([]byte, MaxGRPCMessage+1), true)
+	// Detection reads the headers only.
+	if !IsGRPCFramed(data) {
+		t.Error("IsGRPCFramed = false")
+	}
+	if _, err := SplitGRPC(data); err == nil {
+		t.Error("SplitGRPC decompressed past MaxGRPCMessage")
+	}
+	if _, err := SplitGRPC(grpcFrame(t, make([]byte, MaxGRPCMessage), true)); err != nil {
+		t.Errorf("SplitGRPC of MaxGRPCMessage bytes: %v", err)
+	}
+}
+
+func TestIsGRPCFramedBareMessage(t *testing.T) {
+	for _, s := range []string{"0801", "0a0568656c6c6f", "0801100218031804"} {
+		if IsGRPCFramed(mustHex(t, s)) {
+			t.Errorf("IsGRPCFramed(%s) = true", s)
+		}
+	}
+}
@@ -0,0 +1,333 @@
+package wire
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// wellKnownImports maps well-known type names to the file declaring them.
+var wellKnownImports = map[string]string{
+	"google.protobuf.Timestamp":   "google/protobuf/timestamp.proto",
+	"google.protobuf.Duration":    "google/protobuf/duration.proto",
+	"google.protobuf.Struct":      "google/protobuf/struct.proto",
+	"google.protobuf.Any":         "google/protobuf/any.proto",
+	"google.protobuf.StringValue": "google/protobuf/wrappers.proto",
+	"google.protobuf.BytesValue":  "google/protobuf/wrappers.proto",
+	"google.protobuf.Int64Value":  "google/protobuf/wrappers.proto",
+	"google.protobuf.DoubleValue": "google/protobuf/wrappers.proto",
+	"google.protobuf.FloatValue":  "google/protobuf/wrappers.proto",
+}
+
+// InferProto returns a best-guess proto3 definition of a message named
+// name that could have produced the given samples, each being the parsed
+// fields of one message instance. Field names are placeholders; types are
+// guesses annotated with comments where the bytes were ambiguous.
+func InferProto(pkg, name string, samples ...[]Field) string {
+	imports := make(map[string]bool)
+	msg := inferMessage(name, samples, imports)
+
+	var b strings.Builder
+	b.WriteString("syntax = \"proto3\";\n\n")
+	if pkg != "" {
+		fmt.Fprintf(&b, "package %s;\n\n", pkg)
+	}
+	if len(imports) > 0 {
+		paths := make([]string, 0, len(imports))
+		for p := range imports {
+			paths = append(paths, p)
+		}
+		sort.Strings(paths)
+		for _, p := range paths {
+			fmt.Fprintf(&b, "import %q;\n", p)
+		}
+		b.WriteString("\n")
+	}
+	msg.write(&b, "")
+	return b.String()
+}
+
+type inferredMessage struct {
+	name   string
+	fields []*inferredField
+}
+
+type inferredField struct {
+	number   protowire.Number
+	typ      string
+	repeated bool
+	// count is the largest number of occurrences in one sample.
+	count   int
+	comment string
+	nested  *inferredMessage
+}
+
+func inferMessage(name string, samples [][]Field, imports map[string]bool) *inferredMessage {
+	occurrences := make(map[protowire.Number][]Field)
+	repeated := make(map[protowire.Number]bool)
+	counts := make(map[protowire.Number]int)
+	for _, sample := range samples {
+		seen := make(map[protowire.Number]int)
+		for _, f := range sample {
+			occurrences[f.Number] = append(occurrences[f.Number], f)
+			seen[f.Number]++
+			if seen[f.Number] > 1 {
+				repeated[f.Number] = true
+			}
+			counts[f.Number] = max(counts[f.Number], seen[f.Number])
+		}
+	}
+	numbers := make([]protowire.Number, 0, len(occurrences))
+	for n := range occurrences {
+		numbers = append(numbers, n)
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+
+	msg := &inferredMessage{name: name}
+	for _, n := range numbers {
+		if entries, ok := sampleMapEntries(samples, n); ok && repeated[n] {
+			f := inferMap(n, entries, imports)
+			f.count = counts[n]
+			msg.fields = append(msg.fields, f)
+			continue
+		}
+		f := inferField(n, occurrences[n], imports)
+		f.repeated = repeated[n]
+		f.count = counts[n]
+		msg.fields = append(msg.fields, f)
+	}
+	return msg
+}
+
+// sampleMapEntries collects the map entries of field n across samples,
+// reporting false unless the field looks like a map in every sample.
+func sampleMapEntries(samples [][]Field, n protowire.Number) ([]MapEntry, bool) {
+	var all []MapEntry
+	for _, sample := range samples {
+		var occ []Field
+		for _, f := range sample {
+			if f.Number == n {
+				occ = append(occ, f)
+			}
+		}
+		if len(occ) == 0 {
+			continue
+		}
+		entries, ok := mapEntries(occ, 1)
+		if !ok {
+			return nil, false
+		}
+		all = append(all, entries...)
+	}
+	return all, len(all) > 0
+}
+
+func inferMap(n protowire.Number, entries []MapEntry, imports map[string]bool) *inferredField {
+	var keys, values []Field
+	for _, e := range entries {
+		if e.Key != nil {
+			keys = append(keys, *e.Key)
+		}
+		if e.Value != nil {
+			values = append(values, *e.Value)
+		}
+	}
+
+	// Map keys cannot be floating point, so fixed-width keys are integers.
+	keyType := "string"
+	if len(keys) > 0 {
+		switch keys[0].Type {
+		case protowire.VarintType:
+			keyType, _ = inferVarint(keys)
+		case protowire.Fixed32Type:
+			keyType = "fixed32"
+		case protowire.Fixed64Type:
+			keyType = "fixed64"
+		}
+	}
+
+	f := &inferredField{number: n, typ: "map<" + keyType + ", bytes>"}
+	if len(values) == 0 {
+		f.comment = "only default values seen"
+		return f
+	}
+	value := inferField(2, values, imports)
+	if value.nested != nil {
+		value.nested.name = fmt.Sprintf("Field%dValue", n)
+		value.typ = value.nested.name
+		f.nested = value.nested
+	}
+	f.typ = "map<" + keyType + ", " + value.typ + ">"
+	f.comment = value.comment
+	return f
+}
+
+func inferField(n protowire.Number, occ []Field, imports map[string]bool) *inferredField {
+	f := &inferredField{number: n}
+	typ := occ[0].Type
+	for _, o := range occ[1:] {
+		if o.Type != typ {
+			f.typ = "bytes"
+			f.comment = "conflicting wire types across occurrences"
+			return f
+		}
+	}
+
+	switch typ {
+	case protowire.VarintType:
+		f.typ, f.comment = inferVarint(occ)
+	case protowire.Fixed32Type:
+		f.typ, f.comment = inferFixed(occ, 32)
+	case protowire.Fixed64Type:
+		f.typ, f.comment = inferFixed(occ, 64)
+	case protowire.StartGroupType:
+		f.nested = nestedMessage(n, occ, imports)
+		f.typ = f.nested.name
+		f.comment = "encoded as a proto2 group"
+	case protowire.BytesType:
+		inferBytes(f, occ, imports)
+	}
+	return f
+}
+
+func inferVarint(occ []Field) (typ, comment string) {
+	allBool, fitsInt32, negative, times := true, true, false, true
+	for _, o := range occ {
+		v := o.Varint
+		allBool = allBool && v <= 1
+		fitsInt32 = fitsInt32 && v <= math.MaxInt32
+		negative = negative || v > math.MaxInt64
+		_, ok := VarintTime(v)
+		times = times && ok
+	}
+	switch {
+	case allBool:
+		return "bool", "or an enum/integer that only held 0 and 1"
+	case negative:
+		return "int64", "negative values seen"
+	case times:
+		return "int64", "values look like Unix timestamps"
+	case fitsInt32:
+		return "int32", "or an enum, uint32, sint32 (zigzag) ..."
+	default:
+		return "int64", ""
+	}
+}
+
+func inferFixed(occ []Field, bits int) (typ, comment string) {
+	plausibleFloat := true
+	for _, o := range occ {
+		var v float64
+		if bits == 32 {
+			v = float64(math.Float32frombits(uint32(o.Varint)))
+		} else {
+			v = math.Float64frombits(o.Varint)
+		}
+		plausibleFloat = plausibleFloat && isPlausibleFloat(v)
+	}
+	switch {
+	case plausibleFloat && bits == 32:
+		return "float", "or fixed32/sfixed32"
+	case plausibleFloat:
+		return "double", "or fixed64/sfixed64"
+	case bits == 32:
+		return "fixed32", "or sfixed32"
+	default:
+		return "fixed64", "or sfixed64"
+	}
+}
+
+// isPlausibleFloat reports whether v looks like a deliberately stored
+// floating-point value rather than an integer's bits reinterpreted.
+func isPlausibleFloat(v float64) bool {
+	if v == 0 {
+		return true
+	}
+	a := math.Abs(v)
+	return !math.IsNaN(v) && !math.IsInf(v, 0) && a >= 1e-9 && a <= 1e15
+}
+
+func inferBytes(f *inferredField, occ []Field, imports map[string]bool) {
+	allText, anyNested, allNested := true, false, true
+	var wkType string
+	wkConsistent := true
+	for _, o := range occ {
+		if len(o.Value) == 0 {
+			// Empty values fit any length-delimited type.
+			continue
+		}
+		allText = allText && IsText(o.Value)
+		nested := len(o.Nested) > 0 && !IsText(o.Value)
+		anyNested = anyNested || nested
+		allNested = allNested && nested
+		wk, ok := DetectWellKnown(o)
+		switch {
+		case !ok:
+			wkConsistent = false
+		case wkType == "":
+			wkType = wk.Type
+		case wkType != wk.Type:
+			wkConsistent = false
+		}
+	}
+
+	switch {
+	case wkType != "" && wkConsistent:
+		f.typ = wkType
+		imports[wellKnownImports[wkType]] = true
+	case allText:
+		f.typ = "string"
+		if !anyNested && allEmpty(occ) {
+			f.comment = "only empty values seen"
+		}
+	case allNested:
+		f.nested = nestedMessage(f.number, occ, imports)
+		f.typ = f.nested.name
+	default:
+		f.typ = "bytes"
+		if anyNested {
+			f.comment = "some values parse as messages"
+		}
+	}
+}
+
+func allEmpty(occ []Field) bool {
+	for _, o := range occ {
+		if len(o.Value) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func nestedMessage(n protowire.Number, occ []Field, imports map[string]bool) *inferredMessage {
+	samples := make([][]Field, 0, len(occ))
+	for _, o := range occ {
+		samples = append(samples, o.Nested)
+	}
+	return inferMessage(fmt.Sprintf("Field%d", n), samples, imports)
+}
+
+func (m *inferredMessage) write(b *strings.Builder, indent string) {
+	fmt.Fprintf(b, "%smessage %s {\n", indent, m.name)
+	for _, f := range m.fields {
+		if f.nested != nil {
+			f.nested.write(b, indent+"  ")
+		}
+	}
+	for _, f := range m.fields {
+		label := ""
+		if f.repeated {
+			label = "repeated "
+		}
+		fmt.Fprintf(b, "%s  %s%s field_%d = %d;", indent, label, f.typ, f.number, f.number)
+		if f.comment != "" {
+			fmt.Fprintf(b, "  // %s", f.comment)
+		}
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(b, "%s}\n", indent)
+}
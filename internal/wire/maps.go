@@ -0,0 +1,91 @@
+package wire
+
+import (
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// MapEntry is one key/value pair of a field that looks like a map. Key or
+// Value is nil when the entry omitted it, i.e. it holds the default value.
+type MapEntry struct {
+	Entry Field
+	Key   *Field
+	Value *Field
+}
+
+// DetectMap reports whether occ, the occurrences of one field number, look
+// like the entries of a map field: at least two embedded messages holding
+// only a field 1 key and a field 2 value, with scalar keys that are unique
+// and wire types that agree across entries.
+func DetectMap(occ []Field) ([]MapEntry, bool) {
+	return mapEntries(occ, 2)
+}
+
+// mapEntries is DetectMap with a configurable minimum number of entries.
+func mapEntries(occ []Field, minEntries int) ([]MapEntry, bool) {
+	if len(occ) < minEntries {
+		return nil, false
+	}
+	if allWellKnown(occ) {
+		// e.g. a repeated Timestamp, whose seconds/nanos also look like
+		// key/value pairs.
+		return nil, false
+	}
+	var keyType, valueType protowire.Type = -1, -1
+	seen := make(map[string]bool)
+	entries := make([]MapEntry, 0, len(occ))
+	for _, o := range occ {
+		if o.Type != protowire.BytesType || len(o.Value) > 0 && len(o.Nested) == 0 {
+			// Not an embedded message.
+			return nil, false
+		}
+		e := MapEntry{Entry: o}
+		for i := range o.Nested {
+			f := &o.Nested[i]
+			switch {
+			case f.Number == 1 && e.Key == nil:
+				e.Key = f
+			case f.Number == 2 && e.Value == nil:
+				e.Value = f
+			default:
+				return nil, false
+			}
+		}
+		if e.Key != nil {
+			if !sameType(&keyType, e.Key.Type) || e.Key.Type == protowire.StartGroupType ||
+				e.Key.Type == protowire.BytesType && !IsText(e.Key.Value) && len(e.Key.Value) > 0 {
+				return nil, false
+			}
+		}
+		if e.Value != nil && !sameType(&valueType, e.Value.Type) {
+			return nil, false
+		}
+		key := ""
+		if e.Key != nil {
+			key = string(e.Key.Value)
+		}
+		if seen[key] {
+			return nil, false
+		}
+		seen[key] = true
+		entries = append(entries, e)
+	}
+	return entries, true
+}
+
+// sameType records the first wire type seen in *want and reports whether t
+// agrees with it.
+func sameType(want *protowire.Type, t protowire.Type) bool {
+	if *want == -1 {
+		*want = t
+	}
+	return *want == t
+}
+
+func allWellKnown(occ []Field) bool {
+	for _, o := range occ {
+		if _, ok := DetectWellKnown(o); !ok {
+			return false
+		}
+	}
+	return true
+}
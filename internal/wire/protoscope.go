@@ -0,0 +1,66 @@
+package wire
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Protoscope renders fields in the text syntax of the protoscope tool
+// (github.com/protocolbuffers/protoscope), which can assemble the text back
+// into the same bytes. Strings are written as quoted text, values that parse
+// as messages as nested blocks and other bytes as hex literals. Overlong
+// varint values keep their length using long-form; well-known types get a
+// comment with their rendering.
+func Protoscope(fields []Field) string {
+	var b strings.Builder
+	writeProtoscope(&b, fields, "")
+	return b.String()
+}
+
+func writeProtoscope(b *strings.Builder, fields []Field, indent string) {
+	for _, f := range fields {
+		fmt.Fprintf(b, "%s%d: ", indent, f.Number)
+		switch f.Type {
+		case protowire.VarintType:
+			if extra := len(f.Value) - protowire.SizeVarint(f.Varint); extra > 0 {
+				fmt.Fprintf(b, "long-form:%d ", extra)
+			}
+			// Values above MaxInt64 are negative int64s; both spellings
+			// assemble to the same ten bytes.
+			if f.Varint > math.MaxInt64 {
+				fmt.Fprintf(b, "%d\n", int64(f.Varint))
+			} else {
+				fmt.Fprintf(b, "%d\n", f.Varint)
+			}
+		case protowire.Fixed32Type:
+			fmt.Fprintf(b, "%di32\n", f.Varint)
+		case protowire.Fixed64Type:
+			fmt.Fprintf(b, "%di64\n", f.Varint)
+		case protowire.StartGroupType:
+			b.WriteString("!{\n")
+			writeProtoscope(b, f.Nested, indent+"  ")
+			fmt.Fprintf(b, "%s}\n", indent)
+		case protowire.BytesType:
+			switch {
+			case len(f.Value) == 0:
+				b.WriteString("{}\n")
+			case IsText(f.Value):
+				fmt.Fprintf(b, "{%s}\n", strconv.Quote(string(f.Value)))
+			case len(f.Nested) > 0:
+				b.WriteString("{")
+				if wk, ok := DetectWellKnown(f); ok {
+					fmt.Fprintf(b, "  # %s %s", wk.Type, wk.Text)
+				}
+				b.WriteString("\n")
+				writeProtoscope(b, f.Nested, indent+"  ")
+				fmt.Fprintf(b, "%s}\n", indent)
+			default:
+				fmt.Fprintf(b, "{`%x`}\n", f.Value)
+			}
+		}
+	}
+}
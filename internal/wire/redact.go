@@ -0,0 +1,37 @@
+package wire
+
+import "google.golang.org/protobuf/encoding/protowire"
+
+// redactedText fills redacted strings, repeated or cut to the original length.
+const redactedText = "REDACTED"
+
+// Redact returns a copy of data with the contents of every string and bytes
+// field replaced by a placeholder of the same length: strings by repeated
+// "REDACTED", other bytes by zeros. Nested messages are redacted field by
+// field, so structure, lengths and offsets are preserved while numbers and
+// field layout stay available for analysis. data need only parse up to the
+// first error; the rest is left as is.
+func Redact(data []byte) []byte {
+	out := append([]byte(nil), data...)
+	fields, _ := Parse(data)
+	redact(out, fields)
+	return out
+}
+
+func redact(out []byte, fields []Field) {
+	for _, f := range fields {
+		switch {
+		case f.Type == protowire.StartGroupType:
+			redact(out, f.Nested)
+		case f.Type != protowire.BytesType:
+		case len(f.Nested) > 0 && !IsText(f.Value):
+			redact(out, f.Nested)
+		case IsText(f.Value):
+			for i := f.ValueOffset; i < f.End; i++ {
+				out[i] = redactedText[(i-f.ValueOffset)%len(redactedText)]
+			}
+		default:
+			clear(out[f.ValueOffset:f.End])
+		}
+	}
+}
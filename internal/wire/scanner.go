@@ -0,0 +1,148 @@
+package wire
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Token is a field as a Scanner reads it: a Field whose value is left as
+// is, nested messages and groups included.
+type Token struct {
+	Number protowire.Number
+	Type   protowire.Type
+
+	// Offset is the absolute offset of the tag, ValueOffset the offset of
+	// the value (after the tag and any length prefix), and End the offset
+	// one past the last byte of the field.
+	Offset      int
+	ValueOffset int
+	End         int
+
+	// Value holds the raw value bytes: the varint or fixed-width encoding,
+	// the contents of a length-delimited field, or the body of a group.
+	Value []byte
+	// Varint is the decoded value of varint, fixed32 and fixed64 fields.
+	Varint uint64
+}
+
+// Scanner reads the fields of a payload one at a time, in the manner of
+// bufio.Scanner, for callers to process them without building the tree
+// Parse does:
+//
+//	s := wire.NewScanner(data)
+//	for s.Next() {
+//		t := s.Token()
+//		...
+//	}
+//	if err := s.Err(); err != nil {
+//		...
+//	}
+//
+// Nested reads the fields of a token's value, for callers to descend into
+// the ones they know or guess to be messages.
+type Scanner struct {
+	data  []byte
+	base  int // offset of data in the payload
+	off   int // in data
+	token Token
+	err   error
+}
+
+// NewScanner returns a scanner of the fields of data.
+func NewScanner(data []byte) *Scanner {
+	return newScannerAt(data, 0)
+}
+
+// newScannerAt returns a scanner of data found at offset base of a larger
+// payload, so that token offsets refer to the larger payload.
+func newScannerAt(data []byte, base int) *Scanner {
+	return &Scanner{data: data, base: base}
+}
+
+// Next reads the next field, reporting whether there is one. It returns
+// false at the end of the data, or on malformed data, which Err then
+// reports.
+func (s *Scanner) Next() bool {
+	if s.err != nil || s.off >= len(s.data) {
+		return false
+	}
+	t, n, err := scanField(s.data[s.off:], s.base+s.off)
+	if err != nil {
+		s.err = err
+		return false
+	}
+	s.token = t
+	s.off += n
+	return true
+}
+
+// Token returns the field Next read.
+func (s *Scanner) Token() Token {
+	return s.token
+}
+
+// Err returns the *ParseError that stopped the scanner, or nil if it read
+// the data to the end or has not yet.
+func (s *Scanner) Err() error {
+	return s.err
+}
+
+// Nested returns a scanner of the fields of the value of the field Next
+// read, which must be length-delimited or a group, with offsets in the
+// same payload. A length-delimited value need not be a message; the
+// scanner fails on one that is not.
+func (s *Scanner) Nested() *Scanner {
+	return newScannerAt(s.token.Value, s.token.ValueOffset)
+}
+
+// scanField reads the field at the start of data, whose absolute offset is
+// base, and returns it with the number of bytes consumed.
+func scanField(data []byte, base int) (Token, int, error) {
+	num, typ, tagLen := protowire.ConsumeTag(data)
+	if tagLen < 0 {
+		return Token{}, 0, &ParseError{Offset: base, Err: fmt.Errorf("bad tag: %w", consumeError(tagLen))}
+	}
+	if num > protowire.MaxValidNumber {
+		return Token{}, 0, &ParseError{Offset: base, Err: fmt.Errorf("bad tag: %w", ErrInvalidFieldNumber)}
+	}
+	t := Token{Number: num, Type: typ, Offset: base, ValueOffset: base + tagLen}
+	rest := data[tagLen:]
+
+	var n int
+	switch typ {
+	case protowire.VarintType:
+		t.Varint, n = protowire.ConsumeVarint(rest)
+		if n >= 0 {
+			t.Value = rest[:n]
+		}
+	case protowire.Fixed32Type:
+		var v uint32
+		v, n = protowire.ConsumeFixed32(rest)
+		t.Varint = uint64(v)
+		if n >= 0 {
+			t.Value = rest[:n]
+		}
+	case protowire.Fixed64Type:
+		t.Varint, n = protowire.ConsumeFixed64(rest)
+		if n >= 0 {
+			t.Value = rest[:n]
+		}
+	case protowire.BytesType:
+		t.Value, n = protowire.ConsumeBytes(rest)
+		if n >= 0 {
+			t.ValueOffset = base + tagLen + n - len(t.Value)
+		}
+	case protowire.StartGroupType:
+		t.Value, n = protowire.ConsumeGroup(num, rest)
+	case protowire.EndGroupType:
+		return Token{}, 0, &ParseError{Offset: base, Field: num, Err: fmt.Errorf("%w: end group without its start", ErrInvalidWireType)}
+	default:
+		return Token{}, 0, &ParseError{Offset: base, Field: num, Err: fmt.Errorf("%w %d", ErrInvalidWireType, typ)}
+	}
+	if n < 0 {
+		return Token{}, 0, &ParseError{Offset: base, Field: num, Err: consumeError(n)}
+	}
+	t.End = base + tagLen + n
+	return t, tagLen + n, nil
+}
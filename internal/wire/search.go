@@ -0,0 +1,96 @@
+package wire
+
+import (
+	"bytes"
+	"math"
+	"slices"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Match is one place a searched-for value was found.
+type Match struct {
+	// Path is the field-number path of the field holding the value.
+	Path  []protowire.Number
+	Field Field
+	// Offset is the absolute offset of the matching bytes.
+	Offset int
+	// How says how the value matched, e.g. "text", "varint" or "zigzag".
+	How string
+}
+
+// SearchBytes finds pattern inside length-delimited values. A match is
+// reported against the innermost field containing it: a message field is
+// only reported when the pattern spans several of its nested fields.
+func SearchBytes(fields []Field, pattern []byte) []Match {
+	var matches []Match
+	searchBytes(nil, fields, pattern, &matches)
+	return matches
+}
+
+func searchBytes(path []protowire.Number, fields []Field, pattern []byte, matches *[]Match) {
+	for _, f := range fields {
+		p := append(slices.Clip(path), f.Number)
+		if f.Type != protowire.BytesType && f.Type != protowire.StartGroupType {
+			continue
+		}
+		if len(f.Nested) > 0 && !IsText(f.Value) {
+			before := len(*matches)
+			searchBytes(p, f.Nested, pattern, matches)
+			if len(*matches) > before || f.Type == protowire.StartGroupType {
+				continue
+			}
+		}
+		how := "bytes"
+		if IsText(f.Value) {
+			how = "text"
+		}
+		for off := 0; ; {
+			i := bytes.Index(f.Value[off:], pattern)
+			if i < 0 {
+				break
+			}
+			*matches = append(*matches, Match{Path: p, Field: f, Offset: f.ValueOffset + off + i, How: how})
+			off += i + 1
+		}
+	}
+}
+
+// SearchInt finds fields holding n as a varint (plain or zigzag-encoded),
+// fixed32 or fixed64 value.
+func SearchInt(fields []Field, n int64) []Match {
+	var matches []Match
+	searchInt(nil, fields, n, &matches)
+	return matches
+}
+
+func searchInt(path []protowire.Number, fields []Field, n int64, matches *[]Match) {
+	for _, f := range fields {
+		p := append(slices.Clip(path), f.Number)
+		how := ""
+		switch f.Type {
+		case protowire.VarintType:
+			switch {
+			case f.Varint == uint64(n):
+				how = "varint"
+			case protowire.DecodeZigZag(f.Varint) == n:
+				how = "zigzag"
+			}
+		case protowire.Fixed32Type:
+			// sfixed32 and fixed32 both fit in 32 bits.
+			if n >= math.MinInt32 && n <= math.MaxUint32 && uint32(f.Varint) == uint32(n) {
+				how = "fixed32"
+			}
+		case protowire.Fixed64Type:
+			if f.Varint == uint64(n) {
+				how = "fixed64"
+			}
+		}
+		if how != "" {
+			*matches = append(*matches, Match{Path: p, Field: f, Offset: f.ValueOffset, How: how})
+		}
+		if len(f.Nested) > 0 && (f.Type == protowire.StartGroupType || !IsText(f.Value)) {
+			searchInt(p, f.Nested, n, matches)
+		}
+	}
+}
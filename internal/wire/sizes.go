@@ -0,0 +1,71 @@
+package wire
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// FieldSize attributes payload bytes to one field path.
+type FieldSize struct {
+	Path        []protowire.Number
+	Occurrences int
+	// Bytes counts every byte of the field's occurrences, including the
+	// tag, any length prefix and, for messages, the nested fields.
+	// Overhead is the part taken by tags and length prefixes (and the end
+	// tags of groups).
+	Bytes    int
+	Overhead int
+}
+
+// Sizes attributes the bytes of fields to their field paths. Nested fields
+// are reported under their own paths as well as within their parents, so
+// only top-level paths add up to the payload size. The result is ordered
+// by decreasing size.
+func Sizes(fields []Field) []FieldSize {
+	sizes := make(map[string]*FieldSize)
+	addSizes(sizes, nil, fields)
+	result := make([]FieldSize, 0, len(sizes))
+	for _, s := range sizes {
+		result = append(result, *s)
+	}
+	slices.SortFunc(result, func(a, b FieldSize) int {
+		if c := cmp.Compare(b.Bytes, a.Bytes); c != 0 {
+			return c
+		}
+		return slices.Compare(a.Path, b.Path)
+	})
+	return result
+}
+
+func addSizes(sizes map[string]*FieldSize, path []protowire.Number, fields []Field) {
+	for _, f := range fields {
+		p := append(slices.Clip(path), f.Number)
+		key := fmt.Sprint(p)
+		s := sizes[key]
+		if s == nil {
+			s = &FieldSize{Path: p}
+			sizes[key] = s
+		}
+		s.Occurrences++
+		s.Bytes += f.End - f.Offset
+		s.Overhead += f.End - f.Offset - len(f.Value)
+		if len(f.Nested) > 0 && !IsText(f.Value) {
+			addSizes(sizes, p, f.Nested)
+		}
+	}
+}
+
+// FormatPath returns a field-number path in dotted form, e.g. "5.1".
+func FormatPath(path []protowire.Number) string {
+	var b []byte
+	for i, n := range path {
+		if i > 0 {
+			b = append(b, '.')
+		}
+		b = fmt.Append(b, n)
+	}
+	return string(b)
+}
@@ -0,0 +1,202 @@
+package wire
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// DefaultMaxValue is the default limit on length-delimited values that
+// ParseStream loads into memory.
+const DefaultMaxValue = 1 << 20
+
+// ParseStream parses wire-format data from r incrementally, calling fn for
+// each top-level field as soon as it has been read, so that payloads much
+// larger than memory can be analyzed.
+//
+// Length-delimited values up to maxValue bytes (DefaultMaxValue if <= 0)
+// are loaded and parsed for nested messages exactly like Parse does.
+// Larger values are skipped: the field's Value is nil, while ValueOffset
+// and End still describe where the value lies. Groups are read field by
+// field; their Value is always nil.
+//
+// Nesting is limited to DefaultMaxDepth levels, as for Parse.
+//
+// Parsing stops at the first error from r, from malformed data, or from fn,
+// or once ctx is done, between fields.
+func ParseStream(ctx context.Context, r io.Reader, maxValue int, fn func(Field) error) error {
+	return ParseOptions{}.ParseStream(ctx, r, maxValue, fn)
+}
+
+// ParseStream is like the package-level ParseStream, using o: nesting is
+// limited to o.MaxDepth levels, in groups and in the messages parsed from
+// length-delimited values alike.
+func (o ParseOptions) ParseStream(ctx context.Context, r io.Reader, maxValue int, fn func(Field) error) error {
+	if maxValue <= 0 {
+		maxValue = DefaultMaxValue
+	}
+	maxDepth := o.MaxDepth
+	if maxDepth <= 0 {
+		maxDepth = DefaultMaxDepth
+	}
+	s := &streamParser{r: bufio.NewReader(r), maxValue: maxValue, maxDepth: maxDepth}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		f, err := s.field()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if f.Type == protowire.EndGroupType {
+			return &ParseError{Offset: f.Offset, Field: f.Number, Err: fmt.Errorf("%w: end group without its start", ErrInvalidWireType)}
+		}
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+}
+
+type streamParser struct {
+	r        *bufio.Reader
+	off      int
+	maxValue int
+	maxDepth int
+	depth    int // of the group being read
+}
+
+// field reads the next field. It returns io.EOF only when the input ends
+// cleanly between fields, and returns end-group markers to the caller.
+func (s *streamParser) field() (Field, error) {
+	start := s.off
+	tag, err := s.varint()
+	if err == io.EOF && s.off == start {
+		return Field{}, io.EOF
+	}
+	if err != nil {
+		return Field{}, locate(start, 0, fmt.Errorf("bad tag: %w", noEOF(err)))
+	}
+	num, typ := protowire.DecodeTag(tag)
+	if num < protowire.MinValidNumber || num > protowire.MaxValidNumber {
+		return Field{}, &ParseError{Offset: start, Err: fmt.Errorf("bad tag: %w", ErrInvalidFieldNumber)}
+	}
+	f := Field{Number: num, Type: typ, Offset: start, ValueOffset: s.off}
+
+	switch typ {
+	case protowire.VarintType:
+		var raw [binary.MaxVarintLen64]byte
+		n := 0
+		f.Varint, err = s.varintInto(raw[:], &n)
+		f.Value = raw[:n:n]
+	case protowire.Fixed32Type:
+		if f.Value, err = s.read(4); err == nil {
+			f.Varint = uint64(binary.LittleEndian.Uint32(f.Value))
+		}
+	case protowire.Fixed64Type:
+		if f.Value, err = s.read(8); err == nil {
+			f.Varint = binary.LittleEndian.Uint64(f.Value)
+		}
+	case protowire.BytesType:
+		err = s.bytesValue(&f)
+	case protowire.StartGroupType:
+		err = s.group(&f)
+	case protowire.EndGroupType:
+	default:
+		return Field{}, &ParseError{Offset: start, Field: num, Err: fmt.Errorf("%w %d", ErrInvalidWireType, typ)}
+	}
+	if err != nil {
+		return Field{}, locate(start, num, noEOF(err))
+	}
+	f.End = s.off
+	return f, nil
+}
+
+func (s *streamParser) bytesValue(f *Field) error {
+	n, err := s.varint()
+	if err != nil {
+		return err
+	}
+	f.ValueOffset = s.off
+	if n > uint64(s.maxValue) {
+		skipped, err := s.r.Discard(int(min(n, uint64(1<<62))))
+		s.off += skipped
+		return err
+	}
+	if f.Value, err = s.read(int(n)); err != nil {
+		return err
+	}
+	nested, err := parser{maxDepth: s.maxDepth}.parse(f.Value, f.ValueOffset, s.depth+1)
+	switch {
+	case errors.Is(err, ErrDepthExceeded):
+		return err
+	case err == nil && len(nested) > 0:
+		f.Nested = nested
+	}
+	return nil
+}
+
+func (s *streamParser) group(f *Field) error {
+	s.depth++
+	defer func() { s.depth-- }()
+	if s.depth > s.maxDepth {
+		return &ParseError{Offset: f.Offset, Field: f.Number, Err: fmt.Errorf("%w (%d)", ErrDepthExceeded, s.maxDepth)}
+	}
+	for {
+		nested, err := s.field()
+		if err != nil {
+			return err
+		}
+		if nested.Type == protowire.EndGroupType {
+			if nested.Number != f.Number {
+				return &ParseError{Offset: nested.Offset, Field: nested.Number, Err: fmt.Errorf("%w: end group inside group %d", ErrInvalidWireType, f.Number)}
+			}
+			return nil
+		}
+		f.Nested = append(f.Nested, nested)
+	}
+}
+
+// varint reads one varint, rejecting encodings longer than ten bytes.
+func (s *streamParser) varint() (uint64, error) {
+	var raw [binary.MaxVarintLen64]byte
+	var n int
+	return s.varintInto(raw[:], &n)
+}
+
+// varintInto is varint that also copies the encoded bytes into raw and
+// stores their count in *n.
+func (s *streamParser) varintInto(raw []byte, n *int) (uint64, error) {
+	var v uint64
+	for i := 0; i < binary.MaxVarintLen64; i++ {
+		b, err := s.r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		s.off++
+		raw[i] = b
+		*n = i + 1
+		if i == binary.MaxVarintLen64-1 && b > 1 {
+			return 0, ErrVarintOverflow
+		}
+		v |= uint64(b&0x7f) << (7 * i)
+		if b < 0x80 {
+			return v, nil
+		}
+	}
+	return 0, ErrVarintOverflow
+}
+
+func (s *streamParser) read(n int) ([]byte, error) {
+	b := make([]byte, n)
+	read, err := io.ReadFull(s.r, b)
+	s.off += read
+	return b, err
+}
@@ -0,0 +1,164 @@
+package wire
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Plausible range for timestamps found in real payloads: 2000-01-01 up to
+// (but excluding) 2100-01-01, in Unix seconds.
+const (
+	minEpochSeconds = 946684800
+	maxEpochSeconds = 4102444800
+)
+
+// TimeGuess is a value that looks like a point in time.
+type TimeGuess struct {
+	Time time.Time
+	// Unit describes the encoding the value was recognised as, e.g.
+	// "unix seconds" or "google.protobuf.Timestamp".
+	Unit string
+}
+
+// Format renders the guess in the Times format, RFC3339 in UTC by
+// default, followed by the recognised unit.
+func (g TimeGuess) Format() string {
+	return Times.Format(g.Time) + " (" + g.Unit + ")"
+}
+
+// TimeFormat is how times found in payloads are rendered.
+type TimeFormat struct {
+	// Layout is a time layout, or one of "unix", "unix-ms", "unix-us" and
+	// "unix-ns" for the seconds, milliseconds, microseconds or nanoseconds
+	// since the Unix epoch.
+	Layout string
+	// Location is the time zone times are shown in.
+	Location *time.Location
+}
+
+// Times is the format of the times rendered by TimeGuess.Format and
+// DetectWellKnown. Tools offering a choice replace it.
+var Times = TimeFormat{Layout: time.RFC3339Nano, Location: time.UTC}
+
+// ParseTimeFormat returns the TimeFormat of format, "rfc3339", one of the
+// Unix epoch layouts or a time layout such as "2006-01-02 15:04:05 MST",
+// in the zone tz, "UTC", "Local" or an IANA name such as "Europe/Paris".
+func ParseTimeFormat(format, tz string) (TimeFormat, error) {
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return TimeFormat{}, fmt.Errorf("unknown time zone %q", tz)
+	}
+	switch format {
+	case "rfc3339":
+		format = time.RFC3339Nano
+	case "unix", "unix-ms", "unix-us", "unix-ns":
+	default:
+		// A layout without any of the reference time's elements renders
+		// every time the same.
+		if ref := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC); ref.Format(format) == ref.Add(time.Hour*25+time.Minute).Format(format) {
+			return TimeFormat{}, fmt.Errorf("time format %q is neither rfc3339, unix, unix-ms, unix-us, unix-ns nor a time layout", format)
+		}
+	}
+	return TimeFormat{Layout: format, Location: loc}, nil
+}
+
+// Format renders t in the format.
+func (tf TimeFormat) Format(t time.Time) string {
+	switch tf.Layout {
+	case "unix":
+		return strconv.FormatInt(t.Unix(), 10)
+	case "unix-ms":
+		return strconv.FormatInt(t.UnixMilli(), 10)
+	case "unix-us":
+		return strconv.FormatInt(t.UnixMicro(), 10)
+	case "unix-ns":
+		return strconv.FormatInt(t.UnixNano(), 10)
+	}
+	return t.In(tf.Location).Format(tf.Layout)
+}
+
+// JSON renders t in the format as a JSON value: a number for the Unix
+// epoch layouts, a string for time layouts.
+func (tf TimeFormat) JSON(t time.Time) string {
+	s := tf.Format(t)
+	switch tf.Layout {
+	case "unix", "unix-ms", "unix-us", "unix-ns":
+		return s
+	}
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+var epochUnits = []struct {
+	name   string
+	scale  uint64
+	toTime func(v int64) time.Time
+}{
+	{"unix seconds", 1, func(v int64) time.Time { return time.Unix(v, 0) }},
+	{"unix milliseconds", 1e3, time.UnixMilli},
+	{"unix microseconds", 1e6, time.UnixMicro},
+	{"unix nanoseconds", 1e9, func(v int64) time.Time { return time.Unix(0, v) }},
+}
+
+// VarintTime reports whether v falls in the plausible epoch range when read
+// as Unix seconds, milliseconds, microseconds or nanoseconds.
+func VarintTime(v uint64) (TimeGuess, bool) {
+	for _, u := range epochUnits {
+		if v >= minEpochSeconds*u.scale && v < maxEpochSeconds*u.scale {
+			return TimeGuess{Time: u.toTime(int64(v)), Unit: u.name}, true
+		}
+	}
+	return TimeGuess{}, false
+}
+
+// MessageTime reports whether fields have the shape of an embedded
+// google.protobuf.Timestamp: a field 1 varint holding plausible Unix seconds
+// and an optional field 2 varint holding nanoseconds, and nothing else.
+func MessageTime(fields []Field) (TimeGuess, bool) {
+	var secs, nanos *Field
+	for i := range fields {
+		f := &fields[i]
+		if f.Type != protowire.VarintType {
+			return TimeGuess{}, false
+		}
+		switch {
+		case f.Number == 1 && secs == nil:
+			secs = f
+		case f.Number == 2 && nanos == nil:
+			nanos = f
+		default:
+			return TimeGuess{}, false
+		}
+	}
+	if secs == nil || secs.Varint < minEpochSeconds || secs.Varint >= maxEpochSeconds {
+		return TimeGuess{}, false
+	}
+	var ns int64
+	if nanos != nil {
+		if nanos.Varint >= 1e9 {
+			return TimeGuess{}, false
+		}
+		ns = int64(nanos.Varint)
+	}
+	return TimeGuess{
+		Time: time.Unix(int64(secs.Varint), ns),
+		Unit: "google.protobuf.Timestamp",
+	}, true
+}
+
+// FieldTime applies the timestamp heuristics appropriate to f's wire type.
+func FieldTime(f Field) (TimeGuess, bool) {
+	switch f.Type {
+	case protowire.VarintType:
+		return VarintTime(f.Varint)
+	case protowire.BytesType:
+		if len(f.Nested) > 0 && !IsText(f.Value) {
+			return MessageTime(f.Nested)
+		}
+	}
+	return TimeGuess{}, false
+}
@@ -0,0 +1,27 @@
+package wire
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeFormatJSON(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skip(err)
+	}
+	when := time.Date(2024, 12, 20, 14, 45, 11, 937e6, time.UTC)
+	for _, tt := range []struct {
+		format TimeFormat
+		want   string
+	}{
+		{TimeFormat{Layout: time.RFC3339Nano, Location: time.UTC}, `"2024-12-20T14:45:11.937Z"`},
+		{TimeFormat{Layout: time.RFC3339Nano, Location: tokyo}, `"2024-12-20T23:45:11.937+09:00"`},
+		{TimeFormat{Layout: "unix", Location: tokyo}, "1734705911"},
+		{TimeFormat{Layout: "unix-ms", Location: time.UTC}, "1734705911937"},
+	} {
+		if got := tt.format.JSON(when); got != tt.want {
+			t.Errorf("%s in %s: JSON = %s, want %s", tt.format.Layout, tt.format.Location, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package wire
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// TypeResolver finds message and extension types by name, URL or number.
+// It is the interface protojson and proto.UnmarshalOptions resolve with,
+// and schema.TypeResolver too.
+type TypeResolver interface {
+	protoregistry.MessageTypeResolver
+	protoregistry.ExtensionTypeResolver
+}
+
+// AnyResolver resolves the type URLs of google.protobuf.Any values so that
+// their payloads can be decoded. It defaults to the linked-in types; tools
+// that load descriptor sets replace it.
+var AnyResolver TypeResolver = protoregistry.GlobalTypes
+
+// WellKnown is an embedded message recognised as a google.protobuf
+// well-known type.
+type WellKnown struct {
+	// Type is the fully-qualified type name, e.g. "google.protobuf.Duration".
+	Type string
+	// Text is the canonical rendering: the type's JSON mapping where one
+	// exists ("3.5s" duration, unwrapped scalar), except for times, which are
+	// rendered in the Times format as TimeFormat.JSON renders them.
+	Text string
+}
+
+// wellKnownShapes lists the candidate types in order of preference. Each
+// candidate whose shape matches is confirmed by unmarshaling the bytes into
+// the real type, which must leave no unknown fields behind.
+var wellKnownShapes = []struct {
+	match func(fields []Field) bool
+	new   func() proto.Message
+}{
+	{isTimestampShape, func() proto.Message { return new(timestamppb.Timestamp) }},
+	{isDurationShape, func() proto.Message { return new(durationpb.Duration) }},
+	{isStructShape, func() proto.Message { return new(structpb.Struct) }},
+	{wrapperShape(protowire.BytesType, true), func() proto.Message { return new(wrapperspb.StringValue) }},
+	{wrapperShape(protowire.BytesType, false), func() proto.Message { return new(wrapperspb.BytesValue) }},
+	{wrapperShape(protowire.VarintType, false), func() proto.Message { return new(wrapperspb.Int64Value) }},
+	{wrapperShape(protowire.Fixed64Type, false), func() proto.Message { return new(wrapperspb.DoubleValue) }},
+	{wrapperShape(protowire.Fixed32Type, false), func() proto.Message { return new(wrapperspb.FloatValue) }},
+}
+
+// DetectWellKnown reports whether a length-delimited field has the shape of
+// a well-known type and, if so, renders it canonically.
+func DetectWellKnown(f Field) (WellKnown, bool) {
+	if f.Type != protowire.BytesType || len(f.Nested) == 0 || IsText(f.Value) {
+		return WellKnown{}, false
+	}
+	if wk, ok := detectAny(f.Nested); ok {
+		return wk, true
+	}
+	for _, c := range wellKnownShapes {
+		if !c.match(f.Nested) {
+			continue
+		}
+		msg := c.new()
+		if err := proto.Unmarshal(f.Value, msg); err != nil || hasUnknown(msg.ProtoReflect()) {
+			continue
+		}
+		text, err := protojson.Marshal(msg)
+		if err != nil {
+			// Out-of-range or inconsistent values, e.g. mixed-sign durations.
+			continue
+		}
+		if ts, ok := msg.(*timestamppb.Timestamp); ok {
+			text = []byte(Times.JSON(ts.AsTime()))
+		}
+		return WellKnown{Type: string(msg.ProtoReflect().Descriptor().FullName()), Text: string(text)}, true
+	}
+	return WellKnown{}, false
+}
+
+func isTimestampShape(fields []Field) bool {
+	_, ok := MessageTime(fields)
+	return ok
+}
+
+// isDurationShape matches seconds and nanos varints. A lone seconds field
+// is indistinguishable from an Int64Value and is left to that candidate.
+func isDurationShape(fields []Field) bool {
+	var haveNanos bool
+	for _, f := range fields {
+		if f.Type != protowire.VarintType || f.Number != 1 && f.Number != 2 {
+			return false
+		}
+		haveNanos = haveNanos || f.Number == 2
+	}
+	return haveNanos
+}
+
+// isStructShape matches a Struct: only field 1 entries, each a map entry
+// with a string key and an embedded google.protobuf.Value.
+func isStructShape(fields []Field) bool {
+	for _, f := range fields {
+		if f.Number != 1 || f.Type != protowire.BytesType || len(f.Nested) == 0 {
+			return false
+		}
+		var key bool
+		for _, e := range f.Nested {
+			switch {
+			case e.Number == 1 && e.Type == protowire.BytesType:
+				key = true
+			case e.Number == 2 && e.Type == protowire.BytesType:
+			default:
+				return false
+			}
+		}
+		if !key {
+			return false
+		}
+	}
+	return true
+}
+
+// wrapperShape matches a single field 1 of the given wire type. For
+// length-delimited values, text selects StringValue over BytesValue; bytes
+// that parse as a message are left alone.
+func wrapperShape(typ protowire.Type, text bool) func([]Field) bool {
+	return func(fields []Field) bool {
+		if len(fields) != 1 || fields[0].Number != 1 || fields[0].Type != typ {
+			return false
+		}
+		if typ == protowire.BytesType {
+			f := fields[0]
+			return IsText(f.Value) == text && (text || len(f.Nested) == 0)
+		}
+		return true
+	}
+}
+
+// detectAny matches a type URL in field 1 and an optional value in field 2.
+// The value is decoded when AnyResolver knows its type and left opaque
+// otherwise.
+func detectAny(fields []Field) (WellKnown, bool) {
+	var typeURL string
+	var value []byte
+	for _, f := range fields {
+		switch {
+		case f.Number == 1 && f.Type == protowire.BytesType && typeURL == "" && isTypeURL(f.Value):
+			typeURL = string(f.Value)
+		case f.Number == 2 && f.Type == protowire.BytesType && value == nil:
+			value = f.Value
+		default:
+			return WellKnown{}, false
+		}
+	}
+	if typeURL == "" {
+		return WellKnown{}, false
+	}
+	return WellKnown{Type: "google.protobuf.Any", Text: describeAny(typeURL, value)}, true
+}
+
+// describeAny renders an Any's value as JSON if its type can be resolved.
+func describeAny(typeURL string, value []byte) string {
+	opaque := fmt.Sprintf("%s (%d-byte value)", typeURL, len(value))
+	mt, err := AnyResolver.FindMessageByURL(typeURL)
+	if err != nil {
+		return opaque
+	}
+	msg := mt.New().Interface()
+	if err := (proto.UnmarshalOptions{Resolver: AnyResolver}).Unmarshal(value, msg); err != nil {
+		return fmt.Sprintf("%s (%d-byte value does not decode: %v)", typeURL, len(value), err)
+	}
+	text, err := protojson.MarshalOptions{Resolver: AnyResolver}.Marshal(msg)
+	if err != nil {
+		return opaque
+	}
+	if hasUnknown(msg.ProtoReflect()) {
+		return fmt.Sprintf("%s %s (plus unknown fields)", typeURL, text)
+	}
+	return fmt.Sprintf("%s %s", typeURL, text)
+}
+
+// isTypeURL reports whether b looks like "host/path/pkg.Message".
+func isTypeURL(b []byte) bool {
+	if !IsText(b) {
+		return false
+	}
+	s := string(b)
+	for i := len(s) - 1; i >= 0; i-- {
+		if s[i] == '/' {
+			return i < len(s)-1 && protoreflect.FullName(s[i+1:]).IsValid()
+		}
+	}
+	return false
+}
+
+// hasUnknown reports whether m or any message nested in it kept unknown
+// fields, which means the bytes were not really of m's type.
+func hasUnknown(m protoreflect.Message) bool {
+	if len(m.GetUnknown()) > 0 {
+		return true
+	}
+	found := false
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.IsMap():
+			if fd.MapValue().Message() != nil {
+				v.Map().Range(func(_ protoreflect.MapKey, mv protoreflect.Value) bool {
+					found = hasUnknown(mv.Message())
+					return !found
+				})
+			}
+		case fd.IsList() && fd.Message() != nil:
+			for i := 0; i < v.List().Len() && !found; i++ {
+				found = hasUnknown(v.List().Get(i).Message())
+			}
+		case fd.Message() != nil:
+			found = hasUnknown(v.Message())
+		}
+		return !found
+	})
+	return found
+}
@@ -0,0 +1,150 @@
+// Package wire parses protobuf wire-format payloads without a schema.
+//
+// Parse splits a payload into fields, recording byte offsets and raw values,
+// and speculatively parses length-delimited values as nested messages. The
+// heuristics in this package then guess what a field most likely holds.
+package wire
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Field is one field occurrence in a wire-format payload.
+type Field struct {
+	Number protowire.Number
+	Type   protowire.Type
+
+	// Offset is the absolute offset of the tag, ValueOffset the offset of the
+	// value (after the tag and any length prefix), and End the offset one
+	// past the last byte of the field.
+	Offset      int
+	ValueOffset int
+	End         int
+
+	// Value holds the raw value bytes: the varint or fixed-width encoding,
+	// the contents of a length-delimited field, or the body of a group.
+	Value []byte
+	// Varint is the decoded value of varint, fixed32 and fixed64 fields.
+	Varint uint64
+
+	// Nested holds the fields of a group, or of a length-delimited value
+	// that parses cleanly as a message.
+	Nested []Field
+}
+
+// DefaultMaxDepth is the default limit on how deeply messages and groups
+// may nest. Real schemas rarely go beyond a dozen levels; deeper nesting
+// is either hostile or random bytes being taken for messages.
+const DefaultMaxDepth = 100
+
+// ParseOptions configures Parse. The zero value gives the defaults.
+type ParseOptions struct {
+	// MaxDepth bounds the nesting of messages and groups (DefaultMaxDepth
+	// if <= 0). Parsing fails with ErrDepthExceeded beyond it, also when the
+	// nested message was only speculatively parsed from a bytes value.
+	MaxDepth int
+}
+
+// Parse parses data as a sequence of wire-format fields. On malformed input
+// it returns the fields parsed so far together with a *ParseError.
+func Parse(data []byte) ([]Field, error) {
+	return ParseOptions{}.Parse(data)
+}
+
+// ParseAt is like Parse for data found at offset base of a larger payload,
+// so that reported offsets refer to the larger payload.
+func ParseAt(data []byte, base int) ([]Field, error) {
+	return ParseOptions{}.ParseAt(data, base)
+}
+
+// Parse is like the package-level Parse, using o.
+func (o ParseOptions) Parse(data []byte) ([]Field, error) {
+	return o.ParseAt(data, 0)
+}
+
+// ParseAt is like the package-level ParseAt, using o.
+func (o ParseOptions) ParseAt(data []byte, base int) ([]Field, error) {
+	maxDepth := o.MaxDepth
+	if maxDepth <= 0 {
+		maxDepth = DefaultMaxDepth
+	}
+	return parser{maxDepth: maxDepth}.parse(data, base, 0)
+}
+
+type parser struct {
+	maxDepth int
+}
+
+// parse parses the fields of a message nested depth levels deep.
+func (p parser) parse(data []byte, base, depth int) ([]Field, error) {
+	if depth > p.maxDepth {
+		return nil, &ParseError{Offset: base, Err: fmt.Errorf("%w (%d)", ErrDepthExceeded, p.maxDepth)}
+	}
+	var fields []Field
+	s := newScannerAt(data, base)
+	for s.Next() {
+		t := s.Token()
+		f := Field{
+			Number: t.Number, Type: t.Type,
+			Offset: t.Offset, ValueOffset: t.ValueOffset, End: t.End,
+			Value: t.Value, Varint: t.Varint,
+		}
+		switch t.Type {
+		case protowire.BytesType:
+			// Only maybe a message: other failures mean it is not one.
+			nested, err := p.parse(t.Value, t.ValueOffset, depth+1)
+			switch {
+			case errors.Is(err, ErrDepthExceeded):
+				return fields, err
+			case err == nil && len(nested) > 0:
+				f.Nested = nested
+			}
+		case protowire.StartGroupType:
+			nested, err := p.parse(t.Value, t.ValueOffset, depth+1)
+			if err != nil {
+				return fields, err
+			}
+			f.Nested = nested
+		}
+		fields = append(fields, f)
+	}
+	return fields, s.Err()
+}
+
+// TypeName returns the short protoscope-style name of a wire type.
+func TypeName(t protowire.Type) string {
+	switch t {
+	case protowire.VarintType:
+		return "varint"
+	case protowire.Fixed64Type:
+		return "i64"
+	case protowire.BytesType:
+		return "len"
+	case protowire.StartGroupType:
+		return "sgroup"
+	case protowire.EndGroupType:
+		return "egroup"
+	case protowire.Fixed32Type:
+		return "i32"
+	default:
+		return fmt.Sprintf("wiretype%d", t)
+	}
+}
+
+// IsText reports whether b is non-empty valid UTF-8 made of printable
+// characters, i.e. whether a length-delimited value is most likely a string.
+func IsText(b []byte) bool {
+	if len(b) == 0 || !utf8.Valid(b) {
+		return false
+	}
+	for _, r := range string(b) {
+		if r < 0x20 && r != '\n' && r != '\r' && r != '\t' || r == 0x7f || r == utf8.RuneError {
+			return false
+		}
+	}
+	return true
+}
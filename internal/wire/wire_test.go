@@ -0,0 +1,131 @@
+package wire
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// malformed are payloads Parse and ParseStream must reject with a
+// *ParseError locating the problem.
+var malformed = []struct {
+	name     string
+	hex      string
+	maxDepth int
+	err      error
+	offset   int
+	field    protowire.Number
+}{
+	{name: "wire type 6", hex: "0e", err: ErrInvalidWireType, field: 1},
+	{name: "wire type 7", hex: "0f", err: ErrInvalidWireType, field: 1},
+	{name: "wire type 7 after a field", hex: "0801" + "17", err: ErrInvalidWireType, offset: 2, field: 2},
+	{name: "stray end group", hex: "0c", err: ErrInvalidWireType, field: 1},
+	{name: "end group of another group", hex: "0b" + "14", err: ErrInvalidWireType, field: 1},
+	{name: "unterminated group", hex: "0b" + "0801", err: ErrTruncated, field: 1},
+	{name: "truncated tag", hex: "80", err: ErrTruncated},
+	{name: "truncated varint", hex: "08ff", err: ErrTruncated, field: 1},
+	{name: "truncated fixed32", hex: "0d010203", err: ErrTruncated, field: 1},
+	{name: "truncated fixed64", hex: "0901020304050607", err: ErrTruncated, field: 1},
+	{name: "truncated length", hex: "0a", err: ErrTruncated, field: 1},
+	{name: "length past the end", hex: "0a05" + "6869", err: ErrTruncated, field: 1},
+	{name: "huge length", hex: "0affffffffffffffff7f" + "00", err: ErrTruncated, field: 1},
+	{name: "varint overflow", hex: "08" + strings.Repeat("ff", 10) + "01", err: ErrVarintOverflow, field: 1},
+	{name: "field number 0", hex: "0000", err: ErrInvalidFieldNumber},
+	{name: "field number too large", hex: "8080808010" + "00", err: ErrInvalidFieldNumber},
+	{name: "groups too deep", hex: "0b0b0b0c0c0c", maxDepth: 2, err: ErrDepthExceeded, offset: 3},
+	{name: "messages too deep", hex: "0a06" + "0a04" + "0a02" + "0801", maxDepth: 2, err: ErrDepthExceeded, offset: 6},
+}
+
+func TestParseMalformed(t *testing.T) {
+	for _, tt := range malformed {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseOptions{MaxDepth: tt.maxDepth}.Parse(mustHex(t, tt.hex))
+			checkParseError(t, err, tt.err, tt.offset, tt.field)
+		})
+	}
+}
+
+func TestParseStreamMalformed(t *testing.T) {
+	for _, tt := range malformed {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bytes.NewReader(mustHex(t, tt.hex))
+			err := ParseOptions{MaxDepth: tt.maxDepth}.ParseStream(context.Background(), r, 0, func(Field) error { return nil })
+			// Reading field by field, the stream parser may locate the
+			// problem elsewhere than Parse, e.g. at a group's end marker
+			// rather than its start.
+			var pe *ParseError
+			if !errors.As(err, &pe) || !errors.Is(err, tt.err) {
+				t.Errorf("err = %v, want a *ParseError wrapping %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestParseDepthLimit(t *testing.T) {
+	data := mustHex(t, "0b0b0b0c0c0c")
+	if _, err := (ParseOptions{MaxDepth: 3}).Parse(data); err != nil {
+		t.Errorf("MaxDepth 3: %v", err)
+	}
+	if _, err := (ParseOptions{MaxDepth: 2}).Parse(data); !errors.Is(err, ErrDepthExceeded) {
+		t.Errorf("MaxDepth 2: err = %v, want %v", err, ErrDepthExceeded)
+	}
+	deep := bytes.Repeat([]byte{0x0b}, DefaultMaxDepth+1)
+	deep = append(deep, bytes.Repeat([]byte{0x0c}, DefaultMaxDepth+1)...)
+	if _, err := Parse(deep); !errors.Is(err, ErrDepthExceeded) {
+		t.Errorf("default limit: err = %v, want %v", err, ErrDepthExceeded)
+	}
+}
+
+func TestParseKeepsFieldsBeforeError(t *testing.T) {
+	fields, err := Parse(mustHex(t, "0801"+"120168"+"0e"))
+	if err == nil {
+		t.Fatal("no error")
+	}
+	if len(fields) != 2 || fields[0].Number != 1 || fields[1].Number != 2 {
+		t.Errorf("fields = %+v, want fields 1 and 2", fields)
+	}
+}
+
+func TestParseBytesNotMessage(t *testing.T) {
+	// A length-delimited value that is not a message is kept as bytes,
+	// whatever makes it fail to parse as one.
+	for _, value := range []string{"0e", "0c", "0a05", "08" + strings.Repeat("ff", 10) + "01"} {
+		data := mustHex(t, "0a"+hex.EncodeToString([]byte{byte(len(value) / 2)})+value)
+		fields, err := Parse(data)
+		if err != nil {
+			t.Errorf("%s: %v", value, err)
+			continue
+		}
+		if len(fields) != 1 || fields[0].Nested != nil {
+			t.Errorf("%s: fields = %+v, want one field without nested fields", value, fields)
+		}
+	}
+}
+
+func checkParseError(t *testing.T, err, want error, offset int, field protowire.Number) {
+	t.Helper()
+	var pe *ParseError
+	if !errors.As(err, &pe) {
+		t.Fatalf("err = %v, want a *ParseError", err)
+	}
+	if !errors.Is(err, want) {
+		t.Errorf("err = %v, want %v", err, want)
+	}
+	if pe.Offset != offset || pe.Field != field {
+		t.Errorf("located at offset %d field %d, want offset %d field %d", pe.Offset, pe.Field, offset, field)
+	}
+}
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
@@ -0,0 +1,70 @@
+package compat
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Baseline holds acknowledged findings, keyed by rule and path, so that a
+// schema with a messy history can adopt the checker and be held to it from
+// then on without first fixing every legacy finding.
+type Baseline map[[2]string]bool
+
+// ReadBaseline reads a baseline file: one "RULE PATH" pair per line, with
+// blank lines and "#" comments ignored, as WriteBaseline writes it.
+func ReadBaseline(path string) (Baseline, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	b := make(Baseline)
+	sc := bufio.NewScanner(f)
+	for line := 1; sc.Scan(); line++ {
+		text, _, _ := strings.Cut(sc.Text(), "#")
+		fields := strings.Fields(text)
+		switch len(fields) {
+		case 0:
+			continue
+		case 2:
+		default:
+			return nil, fmt.Errorf("%s:%d: want RULE PATH", path, line)
+		}
+		b[[2]string{fields[0], fields[1]}] = true
+	}
+	return b, sc.Err()
+}
+
+// WriteBaseline writes the findings of r in the format of ReadBaseline,
+// each followed by its message as a comment.
+func WriteBaseline(w io.Writer, r *Report) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "# Acknowledged compat findings: RULE PATH")
+	seen := make(map[[2]string]bool)
+	for _, f := range r.Findings {
+		key := [2]string{f.Rule, f.Path}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		fmt.Fprintf(bw, "%s %s  # %s\n", f.Rule, f.Path, f.Message)
+	}
+	return bw.Flush()
+}
+
+// Suppress removes the findings of r that b acknowledges, returning how
+// many it removed.
+func (b Baseline) Suppress(r *Report) int {
+	kept := r.Findings[:0]
+	for _, f := range r.Findings {
+		if !b[[2]string{f.Rule, f.Path}] {
+			kept = append(kept, f)
+		}
+	}
+	n := len(r.Findings) - len(kept)
+	r.Findings = kept
+	return n
+}
@@ -0,0 +1,47 @@
+package compat
+
+import (
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// cardinality checks fields changing between singular and repeated. A
+// repeated field is written as one record per element, or as one packed
+// record, so a repeated reader takes a singular value as a list of one,
+// while a singular reader keeps only the last element of a list, merges
+// message elements into one, and cannot read a packed list at all.
+func (c *checker) cardinality(name string, old, new protoreflect.MessageDescriptor) {
+	for i := 0; i < old.Fields().Len(); i++ {
+		of := old.Fields().Get(i)
+		nf := new.Fields().ByNumber(of.Number())
+		if nf == nil || of.IsMap() || nf.IsMap() || of.IsList() == nf.IsList() {
+			continue // map changes are FIELD_TYPE_CHANGED
+		}
+		path := name + "." + string(of.Name())
+		if nf.IsList() {
+			sev, reads := singularReads(nf)
+			c.addFor(Forward, FieldCardinalityChanged, sev, path,
+				"field %d became repeated; new readers take old values as a list of one, but old readers %s",
+				of.Number(), reads)
+		} else {
+			sev, reads := singularReads(of)
+			c.addFor(Backward, FieldCardinalityChanged, sev, path,
+				"field %d is no longer repeated; old readers take new values as a list of one, but new readers %s",
+				of.Number(), reads)
+		}
+		c.add(JSONTypeChanged, Breaking, path,
+			"field %d changed between a single value and an array in JSON, which protojson readers of either version reject",
+			of.Number())
+	}
+}
+
+// singularReads says how severe it is for a singular reader to meet the
+// lists written as list, and what the reader makes of them.
+func singularReads(list protoreflect.FieldDescriptor) (Severity, string) {
+	switch {
+	case list.IsPacked():
+		return Breaking, "cannot read the packed list and keep it only as unknown fields, seeing the default"
+	case list.Message() != nil:
+		return Warning, "merge the elements of a list into one message"
+	}
+	return Warning, "keep only the last element of a list"
+}
@@ -0,0 +1,92 @@
+package compat
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Check compares two versions of a schema under policy p, for programs
+// that gate the publication of a schema: it returns the findings affecting
+// the readers p protects and, if any of them are breaking, an
+// *IncompatibleError listing those.
+//
+//	report, err := compat.Check(published, candidate, compat.PolicyBackward)
+//	var incompatible *compat.IncompatibleError
+//	if errors.As(err, &incompatible) {
+//		// reject the candidate
+//	}
+//
+// CheckHistory checks a version against several earlier ones, as the
+// transitive policies require, and with renames.
+func Check(old, new []protoreflect.FileDescriptor, p Policy) (*Report, error) {
+	if len(old) == 0 || len(new) == 0 {
+		return nil, fmt.Errorf("compat: no files to compare")
+	}
+	report := p.Apply(Compare(old, new, nil))
+	return report, report.Err(p)
+}
+
+// CheckDescriptorSets is Check for versions given as FileDescriptorSets,
+// as written by protoc --descriptor_set_out --include_imports or served
+// by schema registries. It fails if either set does not resolve.
+func CheckDescriptorSets(old, new *descriptorpb.FileDescriptorSet, p Policy) (*Report, error) {
+	oldFiles, err := Files(old)
+	if err != nil {
+		return nil, fmt.Errorf("compat: old version: %w", err)
+	}
+	newFiles, err := Files(new)
+	if err != nil {
+		return nil, fmt.Errorf("compat: new version: %w", err)
+	}
+	return Check(oldFiles, newFiles, p)
+}
+
+// Files resolves the files of a FileDescriptorSet, which must include the
+// files they import, for Compare and Check.
+func Files(set *descriptorpb.FileDescriptorSet) ([]protoreflect.FileDescriptor, error) {
+	reg, err := protodesc.NewFiles(set)
+	if err != nil {
+		return nil, err
+	}
+	var out []protoreflect.FileDescriptor
+	reg.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		out = append(out, fd)
+		return true
+	})
+	return out, nil
+}
+
+// IncompatibleError is the error of Check when a version breaks readers
+// that its policy protects.
+type IncompatibleError struct {
+	Policy   Policy
+	Findings []Finding // the breaking ones
+}
+
+func (e *IncompatibleError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "compat: %d breaking changes under policy %s: %s", len(e.Findings), e.Policy, e.Findings[0])
+	if len(e.Findings) > 1 {
+		fmt.Fprintf(&b, " (and %d more)", len(e.Findings)-1)
+	}
+	return b.String()
+}
+
+// Err returns an *IncompatibleError listing the breaking findings of r,
+// found under policy p, or nil if there are none.
+func (r *Report) Err(p Policy) error {
+	var breaking []Finding
+	for _, f := range r.Findings {
+		if f.Severity >= Breaking {
+			breaking = append(breaking, f)
+		}
+	}
+	if len(breaking) == 0 {
+		return nil
+	}
+	return &IncompatibleError{Policy: p, Findings: breaking}
+}
@@ -0,0 +1,80 @@
+package compat
+
+import (
+	"errors"
+	"testing"
+
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestCheck(t *testing.T) {
+	const (
+		optional = `message_type { name: "Order" ` + orderID + ` field { name: "qty" number: 2 label: LABEL_OPTIONAL type: TYPE_INT32 json_name: "qty" } }`
+		required = `message_type { name: "Order" ` + orderID + ` field { name: "qty" number: 2 label: LABEL_REQUIRED type: TYPE_INT32 json_name: "qty" } }`
+	)
+	// Making qty required breaks new readers of old payloads, which may
+	// lack it; making it optional breaks old readers of new ones.
+	for _, tt := range []struct {
+		name     string
+		old, new string
+		policy   Policy
+		wantErr  bool
+	}{
+		{"required NONE", optional, required, PolicyNone, false},
+		{"required BACKWARD", optional, required, PolicyBackward, true},
+		{"required FORWARD", optional, required, PolicyForward, false},
+		{"required FULL", optional, required, PolicyFull, true},
+		{"optional NONE", required, optional, PolicyNone, false},
+		{"optional BACKWARD", required, optional, PolicyBackward, false},
+		{"optional FORWARD", required, optional, PolicyForward, true},
+		{"optional FULL", required, optional, PolicyFull, true},
+		{"unchanged FULL", optional, optional, PolicyFull, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := Check(proto2(t, tt.old), proto2(t, tt.new), tt.policy)
+			var incompatible *IncompatibleError
+			switch {
+			case tt.wantErr && !errors.As(err, &incompatible):
+				t.Fatalf("err = %v, want an *IncompatibleError", err)
+			case !tt.wantErr && err != nil:
+				t.Fatalf("err = %v", err)
+			case tt.wantErr && (incompatible.Policy != tt.policy || len(incompatible.Findings) == 0):
+				t.Errorf("err = %+v", incompatible)
+			}
+			for _, f := range report.Findings {
+				if f.Affects&tt.policy.Affects == 0 {
+					t.Errorf("finding %s affects %s, outside policy %s", f, f.Affects, tt.policy)
+				}
+			}
+		})
+	}
+	if _, err := Check(nil, proto2(t, optional), PolicyFull); err == nil {
+		t.Error("no old files: no error")
+	}
+}
+
+func TestCheckDescriptorSets(t *testing.T) {
+	set := func(files []protoreflect.FileDescriptor) *descriptorpb.FileDescriptorSet {
+		s := &descriptorpb.FileDescriptorSet{}
+		for _, fd := range files {
+			s.File = append(s.File, protodesc.ToFileDescriptorProto(fd))
+		}
+		return s
+	}
+	old := set(proto3(t, `message_type { name: "Order" `+orderID+` } message_type { name: "Item" }`))
+	new := set(proto3(t, `message_type { name: "Order" `+orderID+` }`))
+	if _, err := CheckDescriptorSets(old, old, PolicyFull); err != nil {
+		t.Errorf("same set: %v", err)
+	}
+	var incompatible *IncompatibleError
+	if _, err := CheckDescriptorSets(old, new, PolicyBackward); !errors.As(err, &incompatible) {
+		t.Errorf("Item removed: err = %v, want an *IncompatibleError", err)
+	}
+	// Imports must be included.
+	unresolved := set(proto3(t, `dependency: "google/protobuf/timestamp.proto" message_type { name: "Order" }`))
+	if _, err := CheckDescriptorSets(unresolved, new, PolicyFull); err == nil {
+		t.Error("set missing an import: no error")
+	}
+}
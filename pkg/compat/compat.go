@@ -0,0 +1,732 @@
+// Package compat compares two versions of a protobuf schema and reports the
+// changes that break, or risk breaking, programs built from one version
+// exchanging payloads with programs built from the other.
+package compat
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Severity ranks findings.
+type Severity int
+
+const (
+	// Info findings are compatible changes worth knowing about.
+	Info Severity = iota
+	// Warning findings decode, but can lose or misinterpret data.
+	Warning
+	// Breaking findings make payloads fail to decode or decode wrongly.
+	Breaking
+)
+
+var severityNames = [...]string{Info: "info", Warning: "warning", Breaking: "breaking"}
+
+// ParseSeverity parses a severity name as printed by String.
+func ParseSeverity(name string) (Severity, error) {
+	for s, n := range severityNames {
+		if n == name {
+			return Severity(s), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown severity %q (want info, warning or breaking)", name)
+}
+
+// MarshalText renders s by name, as in JSON reports.
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s Severity) String() string {
+	if int(s) < len(severityNames) {
+		return severityNames[s]
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// Direction says which readers a finding affects.
+type Direction uint8
+
+const (
+	// Backward: new readers of payloads written by the old version.
+	Backward Direction = 1 << iota
+	// Forward: old readers of payloads written by the new version.
+	Forward
+	// Both directions.
+	Both = Backward | Forward
+)
+
+var directionNames = map[Direction]string{Backward: "backward", Forward: "forward", Both: "both"}
+
+// MarshalText renders d by name, as in JSON reports.
+func (d Direction) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d Direction) String() string {
+	if name, ok := directionNames[d]; ok {
+		return name
+	}
+	return fmt.Sprintf("Direction(%d)", uint8(d))
+}
+
+// Rules reported by Compare.
+const (
+	MessageRemoved     = "MESSAGE_REMOVED"
+	MessageAdded       = "MESSAGE_ADDED"
+	MessageRenamed     = "MESSAGE_RENAMED"
+	MessageMoved       = "MESSAGE_MOVED"
+	FieldRemoved       = "FIELD_REMOVED"
+	FieldAdded         = "FIELD_ADDED"
+	FieldTypeChanged   = "FIELD_TYPE_CHANGED"
+	FieldNumberChanged = "FIELD_NUMBER_CHANGED"
+	FieldRenamed       = "FIELD_RENAMED"
+	FieldNotReserved   = "FIELD_NOT_RESERVED"
+	ReservedReused     = "RESERVED_REUSED"
+	ReservedRemoved    = "RESERVED_REMOVED"
+	JSONNameChanged    = "JSON_NAME_CHANGED"
+	JSONNameConflict   = "JSON_NAME_CONFLICT"
+	JSONTypeChanged    = "JSON_TYPE_CHANGED"
+
+	OneofMembershipChanged = "ONEOF_MEMBERSHIP_CHANGED"
+	OneofMemberRemoved     = "ONEOF_MEMBER_REMOVED"
+	OneofMemberAdded       = "ONEOF_MEMBER_ADDED"
+
+	RequiredFieldAdded   = "REQUIRED_FIELD_ADDED"
+	RequiredFieldRemoved = "REQUIRED_FIELD_REMOVED"
+	RequiredChanged      = "REQUIRED_CHANGED"
+
+	DefaultChanged  = "DEFAULT_CHANGED"
+	PresenceChanged = "PRESENCE_CHANGED"
+	PackedChanged   = "PACKED_CHANGED"
+
+	FieldCardinalityChanged = "FIELD_CARDINALITY_CHANGED"
+	IntEnumChanged          = "INT_ENUM_CHANGED"
+
+	FieldDeprecated      = "FIELD_DEPRECATED"
+	FieldUndeprecated    = "FIELD_UNDEPRECATED"
+	FieldBehaviorChanged = "FIELD_BEHAVIOR_CHANGED"
+
+	EnumRemoved            = "ENUM_REMOVED"
+	EnumAdded              = "ENUM_ADDED"
+	EnumRenamed            = "ENUM_RENAMED"
+	EnumValueRemoved       = "ENUM_VALUE_REMOVED"
+	EnumValueAdded         = "ENUM_VALUE_ADDED"
+	EnumValueNumberChanged = "ENUM_VALUE_NUMBER_CHANGED"
+	EnumValueRenamed       = "ENUM_VALUE_RENAMED"
+	EnumZeroValueChanged   = "ENUM_ZERO_VALUE_CHANGED"
+	EnumAliasChanged       = "ENUM_ALIAS_CHANGED"
+
+	ServiceRemoved      = "SERVICE_REMOVED"
+	ServiceAdded        = "SERVICE_ADDED"
+	ServiceRenamed      = "SERVICE_RENAMED"
+	RPCRemoved          = "RPC_REMOVED"
+	RPCAdded            = "RPC_ADDED"
+	RPCTypeChanged      = "RPC_TYPE_CHANGED"
+	RPCStreamingChanged = "RPC_STREAMING_CHANGED"
+)
+
+// Rules lists the rules Compare applies, for tooling and documentation.
+var Rules = []string{
+	MessageRemoved,
+	MessageAdded,
+	MessageRenamed,
+	MessageMoved,
+	FieldRemoved,
+	FieldAdded,
+	FieldTypeChanged,
+	FieldNumberChanged,
+	FieldRenamed,
+	FieldNotReserved,
+	ReservedReused,
+	ReservedRemoved,
+	JSONNameChanged,
+	JSONNameConflict,
+	JSONTypeChanged,
+	OneofMembershipChanged,
+	OneofMemberRemoved,
+	OneofMemberAdded,
+	RequiredFieldAdded,
+	RequiredFieldRemoved,
+	RequiredChanged,
+	DefaultChanged,
+	PresenceChanged,
+	PackedChanged,
+	FieldCardinalityChanged,
+	IntEnumChanged,
+	FieldDeprecated,
+	FieldUndeprecated,
+	FieldBehaviorChanged,
+	EnumRemoved,
+	EnumAdded,
+	EnumRenamed,
+	EnumValueRemoved,
+	EnumValueAdded,
+	EnumValueNumberChanged,
+	EnumValueRenamed,
+	EnumZeroValueChanged,
+	EnumAliasChanged,
+	ServiceRemoved,
+	ServiceAdded,
+	ServiceRenamed,
+	RPCRemoved,
+	RPCAdded,
+	RPCTypeChanged,
+	RPCStreamingChanged,
+}
+
+// Category groups rules by the encodings they concern.
+type Category string
+
+const (
+	// Wire rules concern the binary format, and usually JSON as well.
+	Wire Category = "wire"
+	// JSONOnly rules concern changes that are safe on the wire but break
+	// protojson payloads, which name fields and enum values.
+	JSONOnly Category = "json"
+)
+
+// jsonOnlyRules are the rules of the JSONOnly category.
+var jsonOnlyRules = map[string]bool{
+	JSONNameChanged:  true,
+	JSONNameConflict: true,
+	JSONTypeChanged:  true,
+	EnumValueRenamed: true,
+}
+
+// apiRules are the rules of the API category.
+var apiRules = map[string]bool{
+	FieldDeprecated:      true,
+	FieldUndeprecated:    true,
+	FieldBehaviorChanged: true,
+	ServiceRemoved:       true,
+	ServiceAdded:         true,
+	ServiceRenamed:       true,
+	RPCRemoved:           true,
+	RPCAdded:             true,
+	RPCTypeChanged:       true,
+	RPCStreamingChanged:  true,
+	DocumentationRemoved: true,
+}
+
+// RuleCategory returns the category of rule.
+func RuleCategory(rule string) Category {
+	customMu.Lock()
+	defer customMu.Unlock()
+	switch {
+	case jsonOnlyRules[rule]:
+		return JSONOnly
+	case apiRules[rule]:
+		return API
+	case customRules[rule] != nil:
+		return Custom
+	}
+	return Wire
+}
+
+// Finding is one change between the schema versions.
+type Finding struct {
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	// Path names the changed element relative to its package, e.g.
+	// "InfrastructureExecution.started_at", in the old schema unless the
+	// element was added.
+	Path     string    `json:"path"`
+	Message  string    `json:"message"`
+	Affects  Direction `json:"affects"`
+	Category Category  `json:"category"`
+	// File and Line locate the element in its .proto source, in the new
+	// schema if it is still there, when the descriptors carry source info.
+	File string `json:"file,omitempty"`
+	Line int    `json:"line,omitempty"`
+}
+
+func (f Finding) String() string {
+	s := fmt.Sprintf("%s %s: %s", f.Rule, f.Path, f.Message)
+	if f.File != "" {
+		s = fmt.Sprintf("%s:%d: %s", f.File, f.Line, s)
+	}
+	return s
+}
+
+// Report holds the findings of Compare, grouped by message, then by enum,
+// then by service, in name order.
+type Report struct {
+	Findings []Finding
+}
+
+// Count returns the number of findings of severity s.
+func (r *Report) Count(s Severity) int {
+	n := 0
+	for _, f := range r.Findings {
+		if f.Severity == s {
+			n++
+		}
+	}
+	return n
+}
+
+// Affecting returns the findings of r that affect readers in direction d.
+func (r *Report) Affecting(d Direction) *Report {
+	out := &Report{}
+	for _, f := range r.Findings {
+		if f.Affects&d != 0 {
+			out.Findings = append(out.Findings, f)
+		}
+	}
+	return out
+}
+
+// InCategory returns the findings of r in category cat.
+func (r *Report) InCategory(cat Category) *Report {
+	out := &Report{}
+	for _, f := range r.Findings {
+		if f.Category == cat {
+			out.Findings = append(out.Findings, f)
+		}
+	}
+	return out
+}
+
+// Worst returns the highest severity among the findings, or -1 if there
+// are none.
+func (r *Report) Worst() Severity {
+	worst := Severity(-1)
+	for _, f := range r.Findings {
+		worst = max(worst, f.Severity)
+	}
+	return worst
+}
+
+// AtLeast returns the number of findings of severity s or above.
+func (r *Report) AtLeast(s Severity) int {
+	n := 0
+	for _, f := range r.Findings {
+		if f.Severity >= s {
+			n++
+		}
+	}
+	return n
+}
+
+// SetSeverity changes the severity of the findings whose rule matches
+// pattern, a rule name or a path.Match pattern such as "JSON_*".
+func (r *Report) SetSeverity(pattern string, s Severity) error {
+	return r.each(pattern, func(f *Finding) bool {
+		f.Severity = s
+		return true
+	})
+}
+
+// Drop removes the findings whose rule matches pattern, as for SetSeverity.
+func (r *Report) Drop(pattern string) error {
+	return r.each(pattern, func(*Finding) bool { return false })
+}
+
+// each calls fn for the findings whose rule matches pattern, keeping those
+// for which it returns true.
+func (r *Report) each(pattern string, fn func(*Finding) bool) error {
+	known := false
+	for _, rule := range Rules {
+		ok, err := path.Match(pattern, rule)
+		if err != nil {
+			return fmt.Errorf("bad rule pattern %q: %w", pattern, err)
+		}
+		known = known || ok
+	}
+	if !known {
+		return fmt.Errorf("rule pattern %q matches no rule", pattern)
+	}
+	kept := r.Findings[:0]
+	for _, f := range r.Findings {
+		if ok, _ := path.Match(pattern, f.Rule); ok && !fn(&f) {
+			continue
+		}
+		kept = append(kept, f)
+	}
+	r.Findings = kept
+	return nil
+}
+
+// Compare compares the messages, enums and services declared by two
+// versions of a schema and reports every change, whichever readers it
+// affects. Types are matched by their name within their package, so that
+// example.v1.Foo is compared with example.v2.Foo, or by their full name
+// where another package compared has a type of that name too, and fields
+// by number.
+// Types renamed on purpose are matched as renames declares, which may be
+// nil, rather than reported removed and added; messages that moved to
+// another parent without a declared rename are matched by their fields.
+func Compare(old, new []protoreflect.FileDescriptor, renames Renames) *Report {
+	c := &checker{report: &Report{}, renames: renames, names: newNamer(old, new)}
+	oldMsgs, newMsgs := messages(old, c.names.name), messages(new, c.names.name)
+	oldEnums, newEnums := enums(old, c.names.name), enums(new, c.names.name)
+	oldSvcs, newSvcs := services(old, c.names.name), services(new, c.names.name)
+	c.newNames = make(map[string]string)
+	for name, md := range newMsgs {
+		c.newNames[string(md.FullName())] = name
+	}
+	for name, ed := range newEnums {
+		c.newNames[string(ed.FullName())] = name
+	}
+	for name, sd := range newSvcs {
+		c.newNames[string(sd.FullName())] = name
+	}
+	c.moves(oldMsgs, newMsgs)
+	matched := make(map[string]bool)
+	for _, name := range sortedNames(oldMsgs) {
+		target := c.target(oldMsgs[name])
+		nm, ok := newMsgs[target]
+		if !ok {
+			c.add(MessageRemoved, Breaking, name, "message removed; payloads of this type, including Any values, can no longer be decoded")
+			continue
+		}
+		matched[target] = true
+		switch {
+		case c.moved[name]:
+			c.add(MessageMoved, Warning, name, "%s", c.movedMessage(oldMsgs[name], nm))
+		case c.renamedItself(oldMsgs[name]):
+			c.add(MessageRenamed, Warning, name,
+				"renamed to %s; binary and JSON payloads are unaffected, but Any values name the type and no longer resolve", target)
+		}
+		c.message(name, oldMsgs[name], nm)
+		c.custom(target, oldMsgs[name], nm)
+	}
+	for _, name := range sortedNames(newMsgs) {
+		if !matched[name] {
+			c.add(MessageAdded, Info, name, "message added")
+			c.custom(name, nil, newMsgs[name])
+		}
+	}
+
+	matched = make(map[string]bool)
+	for _, name := range sortedNames(oldEnums) {
+		target := c.target(oldEnums[name])
+		ne, ok := newEnums[target]
+		if !ok {
+			c.add(EnumRemoved, Breaking, name, "enum removed")
+			continue
+		}
+		matched[target] = true
+		if c.renamedItself(oldEnums[name]) {
+			c.add(EnumRenamed, Info, name, "renamed to %s; payloads carry no enum type names", target)
+		}
+		c.enum(name, oldEnums[name], ne)
+	}
+	for _, name := range sortedNames(newEnums) {
+		if !matched[name] {
+			c.add(EnumAdded, Info, name, "enum added")
+		}
+	}
+
+	matched = make(map[string]bool)
+	for _, name := range sortedNames(oldSvcs) {
+		target := c.target(oldSvcs[name])
+		ns, ok := newSvcs[target]
+		if !ok {
+			c.addFor(Backward, ServiceRemoved, Breaking, name, "service removed; new servers answer calls from old clients with UNIMPLEMENTED")
+			continue
+		}
+		matched[target] = true
+		c.service(name, oldSvcs[name], ns)
+	}
+	for _, name := range sortedNames(newSvcs) {
+		if !matched[name] {
+			c.addFor(Forward, ServiceAdded, Info, name, "service added")
+		}
+	}
+	locate(c.report.Findings, old, new, c.names.name)
+	return c.report
+}
+
+type checker struct {
+	report  *Report
+	renames Renames
+	names   namer
+	// moved holds the old names of messages matched by their fields.
+	moved map[string]bool
+	// newNames maps the full names of the new version's types to their
+	// local names, to resolve renames given in full.
+	newNames map[string]string
+}
+
+// add reports a finding affecting readers in both directions.
+func (c *checker) add(rule string, sev Severity, path, format string, args ...any) {
+	c.addFor(Both, rule, sev, path, format, args...)
+}
+
+func (c *checker) addFor(d Direction, rule string, sev Severity, path, format string, args ...any) {
+	c.report.Findings = append(c.report.Findings, Finding{
+		Rule:     rule,
+		Severity: sev,
+		Path:     path,
+		Message:  fmt.Sprintf(format, args...),
+		Affects:  d,
+		Category: RuleCategory(rule),
+	})
+}
+
+func (c *checker) message(name string, old, new protoreflect.MessageDescriptor) {
+	for i := 0; i < old.Fields().Len(); i++ {
+		of := old.Fields().Get(i)
+		path := name + "." + string(of.Name())
+		byName := new.Fields().ByName(of.Name())
+		if byName != nil && byName.Number() != of.Number() {
+			c.add(FieldNumberChanged, Breaking, path,
+				"number changed from %d to %d; each version reads the value where the other never writes it",
+				of.Number(), byName.Number())
+		}
+		nf := new.Fields().ByNumber(of.Number())
+		if nf == nil {
+			if byName == nil {
+				// A reserved number is the sanctioned way to remove a field.
+				sev := Warning
+				if new.ReservedRanges().Has(of.Number()) {
+					sev = Info
+				}
+				c.add(FieldRemoved, sev, path,
+					"field %d removed; new readers keep its value only as unknown fields, old readers see its default",
+					of.Number())
+			}
+			continue
+		}
+		if nf.Name() != of.Name() {
+			c.renamed(path, old, new, of, nf)
+		}
+		if typeNameAs(of, c.target) != typeNameAs(nf, c.names.name) {
+			sev, d, msg := typeChange(of, nf)
+			if !c.intEnum(path, of, nf) || sev > Info {
+				c.addFor(d, FieldTypeChanged, sev, path, "%s", msg)
+			}
+			if sev < Breaking {
+				c.jsonType(path, of, nf)
+			}
+		}
+	}
+	for i := 0; i < new.Fields().Len(); i++ {
+		nf := new.Fields().Get(i)
+		if old.Fields().ByNumber(nf.Number()) != nil || old.Fields().ByName(nf.Name()) != nil {
+			continue
+		}
+		if old.ReservedRanges().Has(nf.Number()) || nf.Cardinality() == protoreflect.Required {
+			continue // RESERVED_REUSED or REQUIRED_FIELD_ADDED
+		}
+		c.add(FieldAdded, Info, name+"."+string(nf.Name()), "field %d added (%s)", nf.Number(), typeName(nf))
+	}
+	c.reserved(name, old, new)
+	c.jsonNames(name, old, new)
+	c.oneofs(name, old, new)
+	c.required(name, old, new)
+	c.defaults(name, old, new)
+	c.packed(name, old, new)
+	c.cardinality(name, old, new)
+	c.fieldOptions(name, old, new)
+}
+
+// renamed reports that field of of the old message is nf in the new one.
+// Binary payloads do not carry names, so this only breaks them when names
+// and numbers were shuffled, but a new field reusing a number looks the same.
+func (c *checker) renamed(path string, old, new protoreflect.MessageDescriptor, of, nf protoreflect.FieldDescriptor) {
+	if prev := old.Fields().ByName(nf.Name()); prev != nil {
+		c.add(FieldRenamed, Breaking, path,
+			"field %d is now %s, which was field %d; values written as %s by old producers are read as %s",
+			of.Number(), nf.Name(), prev.Number(), of.Name(), nf.Name())
+		return
+	}
+	if new.Fields().ByName(of.Name()) != nil {
+		// Reported as FIELD_NUMBER_CHANGED; say where its number went.
+		c.add(FieldRenamed, Breaking, path,
+			"field %d now belongs to %s; values written as %s by old producers are read as %s",
+			of.Number(), nf.Name(), of.Name(), nf.Name())
+		return
+	}
+	c.add(FieldRenamed, Warning, path,
+		"field %d renamed to %s; binary payloads are unaffected, but if %s is a new field reusing the number, old %s values are read as %s",
+		of.Number(), nf.Name(), nf.Name(), of.Name(), nf.Name())
+}
+
+// messages indexes the messages of files, nested ones included, by the
+// names name gives them. Map entry messages are left out; map fields are
+// compared by key and value type.
+func messages(files []protoreflect.FileDescriptor, name func(protoreflect.Descriptor) string) map[string]protoreflect.MessageDescriptor {
+	out := make(map[string]protoreflect.MessageDescriptor)
+	eachMessage(files, func(md protoreflect.MessageDescriptor) { out[name(md)] = md })
+	return out
+}
+
+// eachMessage calls fn for the messages of files, nested ones included,
+// in declaration order, leaving out map entry messages.
+func eachMessage(files []protoreflect.FileDescriptor, fn func(protoreflect.MessageDescriptor)) {
+	var walk func(protoreflect.MessageDescriptors)
+	walk = func(msgs protoreflect.MessageDescriptors) {
+		for i := 0; i < msgs.Len(); i++ {
+			md := msgs.Get(i)
+			if md.IsMapEntry() {
+				continue
+			}
+			fn(md)
+			walk(md.Messages())
+		}
+	}
+	for _, fd := range files {
+		walk(fd.Messages())
+	}
+}
+
+// FindMessage returns the message of files called name, either in full or
+// within its package. A name within its package must be that of a single
+// message of files.
+func FindMessage(files []protoreflect.FileDescriptor, name string) (protoreflect.MessageDescriptor, error) {
+	var found []protoreflect.MessageDescriptor
+	eachMessage(files, func(md protoreflect.MessageDescriptor) {
+		if string(md.FullName()) == name || localName(md) == name {
+			found = append(found, md)
+		}
+	})
+	for _, md := range found {
+		if string(md.FullName()) == name {
+			return md, nil
+		}
+	}
+	switch len(found) {
+	case 0:
+		return nil, fmt.Errorf("no message %s in schema", name)
+	case 1:
+		return found[0], nil
+	}
+	return nil, fmt.Errorf("message name %s is ambiguous: %s and %s match; give it in full", name, found[0].FullName(), found[1].FullName())
+}
+
+// namer names the types of two versions of a schema as Compare matches
+// them: within their package, unless top-level types of another package
+// compared share the name, as google.protobuf.Timestamp does with a
+// Timestamp of the schema's own in descriptor sets built with
+// --include_imports. Those, and the types nested in them, are named in
+// full.
+type namer struct {
+	// paired holds the packages taken to be one package renamed: the
+	// only package of the old version missing from the new one and the
+	// only one of the new version missing from the old one. Their types
+	// are compared with each other, and keep their local names.
+	paired map[protoreflect.FullName]bool
+	// ambiguous holds the names that top-level types of several packages
+	// share, the pair counting as one.
+	ambiguous map[string]bool
+}
+
+func newNamer(old, new []protoreflect.FileDescriptor) namer {
+	n := namer{paired: make(map[protoreflect.FullName]bool), ambiguous: make(map[string]bool)}
+	oldPkgs, newPkgs := packages(old), packages(new)
+	var oldOnly, newOnly []protoreflect.FullName
+	for pkg := range oldPkgs {
+		if !newPkgs[pkg] {
+			oldOnly = append(oldOnly, pkg)
+		}
+	}
+	for pkg := range newPkgs {
+		if !oldPkgs[pkg] {
+			newOnly = append(newOnly, pkg)
+		}
+	}
+	if len(oldOnly) == 1 && len(newOnly) == 1 {
+		n.paired[oldOnly[0]], n.paired[newOnly[0]] = true, true
+	}
+
+	spaces := make(map[string]map[protoreflect.FullName]bool)
+	add := func(d protoreflect.Descriptor) {
+		space := d.ParentFile().Package()
+		if n.paired[space] {
+			space = ""
+		}
+		top := string(d.Name())
+		if spaces[top] == nil {
+			spaces[top] = make(map[protoreflect.FullName]bool)
+		}
+		spaces[top][space] = true
+		if len(spaces[top]) > 1 {
+			n.ambiguous[top] = true
+		}
+	}
+	for _, files := range [][]protoreflect.FileDescriptor{old, new} {
+		for _, fd := range files {
+			for i := 0; i < fd.Messages().Len(); i++ {
+				add(fd.Messages().Get(i))
+			}
+			for i := 0; i < fd.Enums().Len(); i++ {
+				add(fd.Enums().Get(i))
+			}
+			for i := 0; i < fd.Services().Len(); i++ {
+				add(fd.Services().Get(i))
+			}
+		}
+	}
+	return n
+}
+
+// name returns the name of d: local to its package unless ambiguous.
+func (n namer) name(d protoreflect.Descriptor) string {
+	local := localName(d)
+	top, _, _ := strings.Cut(local, ".")
+	if n.paired[d.ParentFile().Package()] || !n.ambiguous[top] {
+		return local
+	}
+	return string(d.FullName())
+}
+
+// packages returns the packages of files.
+func packages(files []protoreflect.FileDescriptor) map[protoreflect.FullName]bool {
+	out := make(map[protoreflect.FullName]bool)
+	for _, fd := range files {
+		out[fd.Package()] = true
+	}
+	return out
+}
+
+// localName returns the name of d within its package.
+func localName(d protoreflect.Descriptor) string {
+	pkg := string(d.ParentFile().Package())
+	if pkg == "" {
+		return string(d.FullName())
+	}
+	return strings.TrimPrefix(string(d.FullName()), pkg+".")
+}
+
+// typeName describes the type of a field, naming message and enum types of
+// the field's own package by their local name so that types keep their
+// identity across package versions.
+func typeName(fd protoreflect.FieldDescriptor) string {
+	return typeNameAs(fd, localName)
+}
+
+// typeNameAs is typeName with local names given by name.
+func typeNameAs(fd protoreflect.FieldDescriptor, name func(protoreflect.Descriptor) string) string {
+	if fd.IsMap() {
+		return fmt.Sprintf("map<%s, %s>", typeNameAs(fd.MapKey(), name), typeNameAs(fd.MapValue(), name))
+	}
+	var d protoreflect.Descriptor
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		d = fd.Message()
+	case protoreflect.EnumKind:
+		d = fd.Enum()
+	default:
+		return fd.Kind().String()
+	}
+	if d.ParentFile().Package() == fd.ParentFile().Package() {
+		return name(d)
+	}
+	return string(d.FullName())
+}
+
+func sortedNames[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,280 @@
+package compat
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// file builds a file descriptor from the text format of a
+// FileDescriptorProto, resolving imports among the linked-in files.
+func file(t *testing.T, text string) protoreflect.FileDescriptor {
+	t.Helper()
+	fdp := &descriptorpb.FileDescriptorProto{}
+	if err := prototext.Unmarshal([]byte(text), fdp); err != nil {
+		t.Fatal(err)
+	}
+	fd, err := protodesc.NewFile(fdp, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fd
+}
+
+// findings returns the findings of r as "RULE path" strings.
+func findings(r *Report) []string {
+	var out []string
+	for _, f := range r.Findings {
+		out = append(out, fmt.Sprintf("%s %s", f.Rule, f.Path))
+	}
+	return out
+}
+
+// timestampSchema is a package holding a Timestamp of its own next to a
+// field of the well-known google.protobuf.Timestamp, with the seconds of
+// its own Timestamp of type secondsType.
+func timestampSchema(t *testing.T, pkg, secondsType string) protoreflect.FileDescriptor {
+	return file(t, fmt.Sprintf(`
+		name: "%[1]s/event.proto" package: "%[1]s" syntax: "proto3"
+		dependency: "google/protobuf/timestamp.proto"
+		message_type {
+			name: "Timestamp"
+			field { name: "seconds" number: 1 label: LABEL_OPTIONAL type: %[2]s json_name: "seconds" }
+		}
+		message_type {
+			name: "Event"
+			field { name: "at" number: 1 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".%[1]s.Timestamp" json_name: "at" }
+			field { name: "created" number: 2 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".google.protobuf.Timestamp" json_name: "created" }
+		}`, pkg, secondsType))
+}
+
+func TestCompareNameCollision(t *testing.T) {
+	wkt := timestamppb.File_google_protobuf_timestamp_proto
+	for _, tt := range []struct {
+		name     string
+		old, new []protoreflect.FileDescriptor
+		want     []string
+	}{{
+		// Descriptor sets built with --include_imports hold the
+		// well-known Timestamp next to the schema's own.
+		name: "versioned packages",
+		old:  []protoreflect.FileDescriptor{wkt, timestampSchema(t, "example.v1", "TYPE_INT64")},
+		new:  []protoreflect.FileDescriptor{wkt, timestampSchema(t, "example.v2", "TYPE_STRING")},
+		want: []string{FieldTypeChanged + " Timestamp.seconds"},
+	}, {
+		name: "same package",
+		old:  []protoreflect.FileDescriptor{timestampSchema(t, "example.v1", "TYPE_INT64"), wkt},
+		new:  []protoreflect.FileDescriptor{timestampSchema(t, "example.v1", "TYPE_STRING"), wkt},
+		want: []string{FieldTypeChanged + " example.v1.Timestamp.seconds"},
+	}} {
+		t.Run(tt.name, func(t *testing.T) {
+			// Repeat, as index order used to decide which type won.
+			for range 10 {
+				got := findings(Compare(tt.old, tt.new, nil))
+				if !slices.Equal(got, tt.want) {
+					t.Fatalf("findings = %q, want %q", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestFindMessage(t *testing.T) {
+	files := []protoreflect.FileDescriptor{timestampSchema(t, "example.v1", "TYPE_INT64"), timestamppb.File_google_protobuf_timestamp_proto}
+	for _, name := range []string{"Event", "example.v1.Event", "example.v1.Timestamp", "google.protobuf.Timestamp"} {
+		md, err := FindMessage(files, name)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if got := string(md.FullName()); got != name && localName(md) != name {
+			t.Errorf("%s: found %s", name, got)
+		}
+	}
+	if _, err := FindMessage(files, "Timestamp"); err == nil {
+		t.Error("ambiguous Timestamp: no error")
+	}
+	if _, err := FindMessage(files, "Missing"); err == nil {
+		t.Error("Missing: no error")
+	}
+}
+
+// proto3 returns a proto3 file of package example.v1 declaring body, in
+// the text format of a FileDescriptorProto.
+func proto3(t *testing.T, body string) []protoreflect.FileDescriptor {
+	t.Helper()
+	return []protoreflect.FileDescriptor{file(t, `name: "example.proto" package: "example.v1" syntax: "proto3" `+body)}
+}
+
+// proto2 is proto3 for a proto2 file.
+func proto2(t *testing.T, body string) []protoreflect.FileDescriptor {
+	t.Helper()
+	return []protoreflect.FileDescriptor{file(t, `name: "example.proto" package: "example.v1" syntax: "proto2" `+body)}
+}
+
+const (
+	orderID   = `field { name: "id" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "id" }`
+	orderQty  = `field { name: "qty" number: 2 label: LABEL_OPTIONAL type: TYPE_INT32 json_name: "qty" }`
+	statusDef = `enum_type { name: "Status" value { name: "STATUS_UNSPECIFIED" number: 0 } value { name: "STATUS_OPEN" number: 1 } }`
+)
+
+func TestCompareRules(t *testing.T) {
+	msg := func(name string, fields ...string) string {
+		return fmt.Sprintf(`message_type { name: %q %s }`, name, strings.Join(fields, " "))
+	}
+	svc := func(methods ...string) string {
+		return fmt.Sprintf(`service { name: "Orders" %s }`, strings.Join(methods, " "))
+	}
+	const get = `method { name: "Get" input_type: ".example.v1.Order" output_type: ".example.v1.Order" }`
+	for _, tt := range []struct {
+		name     string
+		old, new string
+		proto2   bool
+		renames  Renames
+		want     []string // "RULE path severity direction"
+	}{{
+		name: "no change",
+		old:  msg("Order", orderID, orderQty),
+		new:  msg("Order", orderID, orderQty),
+	}, {
+		name: "message removed",
+		old:  msg("Order", orderID) + msg("Item"),
+		new:  msg("Order", orderID),
+		want: []string{"MESSAGE_REMOVED Item breaking both"},
+	}, {
+		name: "message added",
+		old:  msg("Order", orderID),
+		new:  msg("Order", orderID) + msg("Item"),
+		want: []string{"MESSAGE_ADDED Item info both"},
+	}, {
+		name:    "message renamed",
+		old:     msg("Order", orderID),
+		new:     msg("PurchaseOrder", orderID),
+		renames: Renames{"Order": "PurchaseOrder"},
+		want:    []string{"MESSAGE_RENAMED Order warning both"},
+	}, {
+		name: "message moved",
+		old:  msg("Order", orderID, `nested_type { name: "Line" `+orderQty+` }`),
+		new:  msg("Order", orderID) + msg("Line", orderQty),
+		want: []string{"MESSAGE_MOVED Order.Line warning both"},
+	}, {
+		name: "field removed",
+		old:  msg("Order", orderID, orderQty),
+		new:  msg("Order", orderID),
+		want: []string{"FIELD_REMOVED Order.qty warning both", "FIELD_NOT_RESERVED Order.qty warning both"},
+	}, {
+		name: "field removed and reserved",
+		old:  msg("Order", orderID, orderQty),
+		new:  msg("Order", orderID, `reserved_range { start: 2 end: 3 } reserved_name: "qty"`),
+		want: []string{"FIELD_REMOVED Order.qty info both"},
+	}, {
+		name: "field added",
+		old:  msg("Order", orderID),
+		new:  msg("Order", orderID, orderQty),
+		want: []string{"FIELD_ADDED Order.qty info both"},
+	}, {
+		name: "field type changed",
+		old:  msg("Order", orderID, orderQty),
+		new:  msg("Order", orderID, `field { name: "qty" number: 2 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "qty" }`),
+		want: []string{"FIELD_TYPE_CHANGED Order.qty breaking both"},
+	}, {
+		name: "field renamed",
+		old:  msg("Order", orderID, orderQty),
+		new:  msg("Order", orderID, `field { name: "quantity" number: 2 label: LABEL_OPTIONAL type: TYPE_INT32 json_name: "quantity" }`),
+		want: []string{"FIELD_RENAMED Order.qty warning both", "JSON_NAME_CHANGED Order.qty warning both"},
+	}, {
+		name: "field number changed",
+		old:  msg("Order", orderID, orderQty),
+		new:  msg("Order", orderID, `field { name: "qty" number: 3 label: LABEL_OPTIONAL type: TYPE_INT32 json_name: "qty" }`),
+		want: []string{"FIELD_NUMBER_CHANGED Order.qty breaking both"},
+	}, {
+		name: "reserved number reused",
+		old:  msg("Order", orderID, `reserved_range { start: 2 end: 3 }`),
+		new:  msg("Order", orderID, orderQty),
+		want: []string{"RESERVED_REUSED Order.qty breaking both", "RESERVED_REMOVED Order warning both"},
+	}, {
+		name: "field made repeated",
+		old:  msg("Order", orderID, orderQty),
+		new:  msg("Order", orderID, `field { name: "qty" number: 2 label: LABEL_REPEATED type: TYPE_INT32 json_name: "qty" }`),
+		want: []string{"FIELD_CARDINALITY_CHANGED Order.qty breaking forward", "JSON_TYPE_CHANGED Order.qty breaking both"},
+	}, {
+		name: "field moved into a oneof",
+		old:  msg("Order", orderID, orderQty),
+		new:  msg("Order", orderID, `field { name: "qty" number: 2 label: LABEL_OPTIONAL type: TYPE_INT32 json_name: "qty" oneof_index: 0 } oneof_decl { name: "amount" }`),
+		want: []string{"ONEOF_MEMBERSHIP_CHANGED Order.qty warning backward"},
+	}, {
+		name:   "required field added",
+		proto2: true,
+		old:    msg("Order", orderID),
+		new:    msg("Order", orderID, `field { name: "qty" number: 2 label: LABEL_REQUIRED type: TYPE_INT32 json_name: "qty" }`),
+		want:   []string{"REQUIRED_FIELD_ADDED Order.qty breaking backward"},
+	}, {
+		name: "enum value added",
+		old:  statusDef,
+		new:  `enum_type { name: "Status" value { name: "STATUS_UNSPECIFIED" number: 0 } value { name: "STATUS_OPEN" number: 1 } value { name: "STATUS_SHIPPED" number: 2 } }`,
+		want: []string{"ENUM_VALUE_ADDED Status.STATUS_SHIPPED info forward"},
+	}, {
+		name: "enum value removed",
+		old:  statusDef,
+		new:  `enum_type { name: "Status" value { name: "STATUS_UNSPECIFIED" number: 0 } }`,
+		want: []string{"ENUM_VALUE_REMOVED Status.STATUS_OPEN warning backward"},
+	}, {
+		name: "enum value renamed",
+		old:  statusDef,
+		new:  `enum_type { name: "Status" value { name: "STATUS_UNSPECIFIED" number: 0 } value { name: "STATUS_ACTIVE" number: 1 } }`,
+		want: []string{"ENUM_VALUE_RENAMED Status.STATUS_OPEN warning both"},
+	}, {
+		name: "enum removed",
+		old:  msg("Order", orderID) + statusDef,
+		new:  msg("Order", orderID),
+		want: []string{"ENUM_REMOVED Status breaking both"},
+	}, {
+		name: "service removed",
+		old:  msg("Order", orderID) + svc(get),
+		new:  msg("Order", orderID),
+		want: []string{"SERVICE_REMOVED Orders breaking backward"},
+	}, {
+		name: "RPC removed",
+		old:  msg("Order", orderID) + svc(get),
+		new:  msg("Order", orderID) + svc(),
+		want: []string{"RPC_REMOVED Orders.Get breaking backward"},
+	}, {
+		name: "RPC added",
+		old:  msg("Order", orderID) + svc(),
+		new:  msg("Order", orderID) + svc(get),
+		want: []string{"RPC_ADDED Orders.Get info forward"},
+	}, {
+		name: "RPC type changed",
+		old:  msg("Order", orderID) + msg("Item") + svc(get),
+		new:  msg("Order", orderID) + msg("Item") + svc(`method { name: "Get" input_type: ".example.v1.Order" output_type: ".example.v1.Item" }`),
+		want: []string{"RPC_TYPE_CHANGED Orders.Get breaking both"},
+	}, {
+		name: "RPC made streaming",
+		old:  msg("Order", orderID) + svc(get),
+		new:  msg("Order", orderID) + svc(`method { name: "Get" input_type: ".example.v1.Order" output_type: ".example.v1.Order" server_streaming: true }`),
+		want: []string{"RPC_STREAMING_CHANGED Orders.Get breaking both"},
+	}} {
+		t.Run(tt.name, func(t *testing.T) {
+			build := proto3
+			if tt.proto2 {
+				build = proto2
+			}
+			report := Compare(build(t, tt.old), build(t, tt.new), tt.renames)
+			var got []string
+			for _, f := range report.Findings {
+				got = append(got, fmt.Sprintf("%s %s %s %s", f.Rule, f.Path, f.Severity, f.Affects))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("findings = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
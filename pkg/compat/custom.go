@@ -0,0 +1,79 @@
+package compat
+
+import (
+	"fmt"
+	"sync"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Custom rules belong to their own category.
+const Custom Category = "custom"
+
+// RuleFunc is a rule an organization adds to Compare. It is called for each
+// message of the new version, with path its name within its package and
+// old its old version, or nil if the message was added, and calls report
+// for each finding. Findings affect readers in both directions.
+//
+// A rule requiring comments on new fields, for instance:
+//
+//	compat.Register("ACME_FIELD_COMMENT", func(path string, old, new protoreflect.MessageDescriptor, report compat.ReportFunc) {
+//		for i := 0; i < new.Fields().Len(); i++ {
+//			f := new.Fields().Get(i)
+//			if old != nil && old.Fields().ByNumber(f.Number()) != nil {
+//				continue
+//			}
+//			if loc := f.ParentFile().SourceLocations().ByDescriptor(f); loc.LeadingComments == "" {
+//				report(compat.Warning, path+"."+string(f.Name()), "new field %d has no comment", f.Number())
+//			}
+//		}
+//	})
+type RuleFunc func(path string, old, new protoreflect.MessageDescriptor, report ReportFunc)
+
+// ReportFunc reports a finding of a custom rule.
+type ReportFunc func(sev Severity, path, format string, args ...any)
+
+var (
+	customMu    sync.Mutex
+	customRules = map[string]RuleFunc{}
+	customNames []string // in registration order
+)
+
+// Register adds a rule to Compare under name, which is listed in Rules and
+// can be matched by -severity patterns like the built-in rule names. It is
+// meant to be called from an init function in a file added to the build,
+// and panics if name is already taken.
+func Register(name string, fn RuleFunc) {
+	customMu.Lock()
+	defer customMu.Unlock()
+	for _, rule := range Rules {
+		if rule == name {
+			panic("compat: rule " + name + " registered twice")
+		}
+	}
+	customRules[name] = fn
+	customNames = append(customNames, name)
+	Rules = append(Rules, name)
+}
+
+// custom runs the registered rules on a message of the new version.
+func (c *checker) custom(path string, old, new protoreflect.MessageDescriptor) {
+	customMu.Lock()
+	defer customMu.Unlock()
+	for _, name := range customNames {
+		category := Custom
+		if apiRules[name] {
+			category = API // opt-in built-in rules
+		}
+		customRules[name](path, old, new, func(sev Severity, path, format string, args ...any) {
+			c.report.Findings = append(c.report.Findings, Finding{
+				Rule:     name,
+				Severity: sev,
+				Path:     path,
+				Message:  fmt.Sprintf(format, args...),
+				Affects:  Both,
+				Category: category,
+			})
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package compat
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// defaults checks what readers make of absent fields. The wire stays
+// compatible when a default changes, but a field left unset means one
+// thing to readers of one version and another to the other, silently.
+func (c *checker) defaults(name string, old, new protoreflect.MessageDescriptor) {
+	for i := 0; i < old.Fields().Len(); i++ {
+		of := old.Fields().Get(i)
+		nf := new.Fields().ByNumber(of.Number())
+		if nf == nil || of.Kind() != nf.Kind() || of.IsList() || nf.IsList() || of.Message() != nil {
+			continue // removals and type changes are reported by other rules
+		}
+		path := name + "." + string(of.Name())
+		od, nd := defaultString(of), defaultString(nf)
+		// Enum defaults are compared by number, as renaming values is
+		// reported by the enum rules.
+		if !of.Default().Equal(nf.Default()) {
+			c.add(DefaultChanged, Warning, path,
+				"default of field %d changed from %s to %s; readers of each version fill in their own when the field is absent",
+				of.Number(), od, nd)
+		}
+
+		switch {
+		case (realOneof(of) == nil) != (realOneof(nf) == nil):
+			// ONEOF_MEMBERSHIP_CHANGED covers the presence oneofs bring.
+		case of.HasPresence() && !nf.HasPresence():
+			msg := fmt.Sprintf("field %d lost presence", of.Number())
+			if proto3Optional(of) {
+				msg = fmt.Sprintf("field %d is no longer optional; generated code drops its hazzers (has_%s(), has%s(), HasField) and Go fields change from pointers to values, so callers using them stop compiling", of.Number(), of.Name(), camel(of.Name()))
+			}
+			msg += "; new readers cannot tell an unset field from one set to " + zeroString(nf) + ", and new producers omit zero values"
+			if od != zeroString(of) {
+				msg += ", which old readers take as " + od
+			}
+			c.add(PresenceChanged, Warning, path, "%s", msg)
+		case !of.HasPresence() && nf.HasPresence():
+			msg := fmt.Sprintf("field %d gained presence", of.Number())
+			if proto3Optional(nf) {
+				msg = fmt.Sprintf("field %d became optional; generated code gains hazzers and Go fields change from values to pointers, which callers assigning them must follow", of.Number())
+			}
+			sev, msg := Info, msg+"; old producers omit zero values, so new readers see them as unset"
+			if nd != zeroString(nf) {
+				sev, msg = Warning, msg+" and read "+nd
+			}
+			c.addFor(Backward, PresenceChanged, sev, path, "%s", msg)
+		}
+	}
+}
+
+// proto3Optional reports whether fd is a proto3 field declared optional,
+// which gives it explicit presence.
+func proto3Optional(fd protoreflect.FieldDescriptor) bool {
+	return fd.Syntax() == protoreflect.Proto3 && fd.HasOptionalKeyword()
+}
+
+// camel renders a field name as generated Java and Go accessors do,
+// e.g. "order_id" as "OrderId".
+func camel(name protoreflect.Name) string {
+	var b strings.Builder
+	for _, part := range strings.Split(string(name), "_") {
+		if part != "" {
+			b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	return b.String()
+}
+
+// defaultString renders the value readers assume for fd when it is absent:
+// its explicit proto2 default, the first enum value for proto2 enums, or
+// the zero value.
+func defaultString(fd protoreflect.FieldDescriptor) string {
+	if fd.Kind() == protoreflect.EnumKind {
+		return enumString(fd, fd.Default().Enum())
+	}
+	return formatDefault(fd, fd.Default())
+}
+
+// zeroString renders the zero value of fd's type, as formatted by
+// defaultString.
+func zeroString(fd protoreflect.FieldDescriptor) string {
+	switch fd.Kind() {
+	case protoreflect.EnumKind:
+		return enumString(fd, 0)
+	case protoreflect.BoolKind:
+		return "false"
+	case protoreflect.StringKind, protoreflect.BytesKind:
+		return `""`
+	}
+	return "0"
+}
+
+func enumString(fd protoreflect.FieldDescriptor, n protoreflect.EnumNumber) string {
+	if ev := fd.Enum().Values().ByNumber(n); ev != nil {
+		return fmt.Sprintf("%s (%d)", ev.Name(), n)
+	}
+	return fmt.Sprint(n)
+}
+
+func formatDefault(fd protoreflect.FieldDescriptor, v protoreflect.Value) string {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return fmt.Sprintf("%q", v.String())
+	case protoreflect.BytesKind:
+		return fmt.Sprintf("%q", v.Bytes())
+	}
+	return fmt.Sprint(v.Interface())
+}
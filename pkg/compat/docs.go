@@ -0,0 +1,112 @@
+package compat
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// DocumentationRemoved is the name of DocumentationRule.
+const DocumentationRemoved = "DOCUMENTATION_REMOVED"
+
+// DocumentationRule reports comments and annotations of messages and
+// fields that the old version has and the new one lost, which refactors
+// drop by accident along with the contract they document. It changes
+// nothing on the wire, so it is opt-in:
+//
+//	compat.Register(compat.DocumentationRemoved, compat.DocumentationRule)
+//
+// Comments are only seen in descriptor sets built with source info
+// (protoc --include_source_info); new versions without any are skipped.
+// Options other rules report on, such as deprecation, are left out.
+func DocumentationRule(path string, old, new protoreflect.MessageDescriptor, report ReportFunc) {
+	if old == nil || new.ParentFile().SourceLocations().Len() == 0 {
+		return
+	}
+	if comment(old) != "" && comment(new) == "" {
+		report(Warning, path, "comment removed; it read %q", summary(comment(old)))
+	}
+	for i := 0; i < old.Fields().Len(); i++ {
+		of := old.Fields().Get(i)
+		nf := new.Fields().ByNumber(of.Number())
+		if nf == nil {
+			continue
+		}
+		fpath := path + "." + string(nf.Name())
+		if comment(of) != "" && comment(nf) == "" {
+			report(Warning, fpath, "comment of field %d removed; it read %q", of.Number(), summary(comment(of)))
+		}
+		na := annotations(nf)
+		for _, a := range sortedNames(annotations(of)) {
+			if !na[a] {
+				report(Warning, fpath, "annotation %s of field %d removed", a, of.Number())
+			}
+		}
+	}
+}
+
+// comment returns the leading or trailing comment of d, if any.
+func comment(d protoreflect.Descriptor) string {
+	loc := d.ParentFile().SourceLocations().ByDescriptor(d)
+	if c := strings.TrimSpace(loc.LeadingComments); c != "" {
+		return c
+	}
+	return strings.TrimSpace(loc.TrailingComments)
+}
+
+// summary returns the first line of comment c, shortened for a finding.
+func summary(c string) string {
+	line, _, _ := strings.Cut(c, "\n")
+	if len(line) > 60 {
+		line = line[:57] + "..."
+	}
+	return line
+}
+
+// coveredOptions are the field options other rules report on: packed,
+// deprecated and google.api.field_behavior.
+var coveredOptions = map[protowire.Number]bool{2: true, 3: true, fieldBehaviorNumber: true}
+
+// annotations names the options set on fd, such as validation rules or
+// jstype, by extension name when it is linked in and by number
+// otherwise.
+func annotations(fd protoreflect.FieldDescriptor) map[string]bool {
+	opts, _ := fd.Options().(*descriptorpb.FieldOptions)
+	if opts == nil {
+		return nil
+	}
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(opts)
+	if err != nil {
+		return nil
+	}
+	fields := opts.ProtoReflect().Descriptor().Fields()
+	out := make(map[string]bool)
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			break
+		}
+		b = b[n:]
+		if n = protowire.ConsumeFieldValue(num, typ, b); n < 0 {
+			break
+		}
+		b = b[n:]
+		switch {
+		case coveredOptions[num]:
+		case fields.ByNumber(num) != nil:
+			out[string(fields.ByNumber(num).Name())] = true
+		default:
+			name := fmt.Sprintf("(extension %d)", num)
+			if xt, err := protoregistry.GlobalTypes.FindExtensionByNumber(opts.ProtoReflect().Descriptor().FullName(), num); err == nil {
+				name = "(" + string(xt.TypeDescriptor().FullName()) + ")"
+			}
+			out[name] = true
+		}
+	}
+	return out
+}
@@ -0,0 +1,130 @@
+package compat
+
+import (
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// enums indexes the enums of files, nested ones included, by the names
+// name gives them.
+func enums(files []protoreflect.FileDescriptor, name func(protoreflect.Descriptor) string) map[string]protoreflect.EnumDescriptor {
+	out := make(map[string]protoreflect.EnumDescriptor)
+	eachEnum(files, func(ed protoreflect.EnumDescriptor) { out[name(ed)] = ed })
+	return out
+}
+
+// eachEnum calls fn for the enums of files, nested ones included.
+func eachEnum(files []protoreflect.FileDescriptor, fn func(protoreflect.EnumDescriptor)) {
+	add := func(eds protoreflect.EnumDescriptors) {
+		for i := 0; i < eds.Len(); i++ {
+			fn(eds.Get(i))
+		}
+	}
+	var walk func(protoreflect.MessageDescriptors)
+	walk = func(msgs protoreflect.MessageDescriptors) {
+		for i := 0; i < msgs.Len(); i++ {
+			add(msgs.Get(i).Enums())
+			walk(msgs.Get(i).Messages())
+		}
+	}
+	for _, fd := range files {
+		add(fd.Enums())
+		walk(fd.Messages())
+	}
+}
+
+// enum compares two versions of an enum. Values are matched by number,
+// as binary payloads carry numbers, and by name, as JSON payloads do.
+func (c *checker) enum(name string, old, new protoreflect.EnumDescriptor) {
+	ov, nv := old.Values(), new.Values()
+
+	// The first value is the default of unset fields; proto3 requires it
+	// to be zero, proto2 does not.
+	if oz, nz := ov.Get(0), nv.Get(0); oz.Number() != nz.Number() {
+		c.add(EnumZeroValueChanged, Breaking, name,
+			"default value changed from %s = %d to %s = %d; unset fields mean something else to each version",
+			oz.Name(), oz.Number(), nz.Name(), nz.Number())
+	}
+
+	for i := 0; i < ov.Len(); i++ {
+		o := ov.Get(i)
+		path := name + "." + string(o.Name())
+		if n := nv.ByName(o.Name()); n != nil && n.Number() != o.Number() {
+			c.add(EnumValueNumberChanged, Breaking, path,
+				"number changed from %d to %d; each version reads the other's %s as a different value; keep the number and add a new value instead",
+				o.Number(), n.Number(), o.Name())
+			continue
+		}
+		if nv.ByName(o.Name()) != nil {
+			continue
+		}
+		if names := valueNames(nv, o.Number()); names != nil {
+			c.add(EnumValueRenamed, Warning, path,
+				"value %d renamed to %s; binary payloads are unaffected, but JSON payloads name the value; keep %s as an alias (allow_alias) during the migration",
+				o.Number(), strings.Join(names, ", "), o.Name())
+			continue
+		}
+		if new.ReservedRanges().Has(o.Number()) {
+			c.addFor(Backward, EnumValueRemoved, Info, path, "value %d removed and reserved", o.Number())
+			continue
+		}
+		c.addFor(Backward, EnumValueRemoved, Warning, path,
+			"value %d removed; new readers see it as an unknown value (proto3) or drop it into unknown fields (proto2); reserve the number or keep the value marked deprecated",
+			o.Number())
+	}
+	for i := 0; i < nv.Len(); i++ {
+		n := nv.Get(i)
+		if ov.ByName(n.Name()) != nil || valueNames(ov, n.Number()) != nil {
+			continue
+		}
+		if old.ReservedRanges().Has(n.Number()) {
+			c.add(ReservedReused, Breaking, name+"."+string(n.Name()),
+				"value %d uses a number the old version reserves; payloads still carrying the retired value are read as %s",
+				n.Number(), n.Name())
+			continue
+		}
+		c.addFor(Forward, EnumValueAdded, Info, name+"."+string(n.Name()),
+			"value %d added; old readers see it as an unknown value (proto3) or drop it into unknown fields (proto2)", n.Number())
+	}
+
+	oa, na := allowsAlias(old), allowsAlias(new)
+	switch {
+	case oa && !na && hasAliases(old):
+		c.add(EnumAliasChanged, Warning, name, "allow_alias removed; alias names of the old version no longer parse in JSON payloads")
+	case oa && !na:
+		c.add(EnumAliasChanged, Info, name, "allow_alias removed")
+	case !oa && na:
+		c.add(EnumAliasChanged, Info, name, "allow_alias added; JSON writers of the new version may name a number differently than old readers expect")
+	}
+}
+
+// valueNames returns the names of the values numbered n.
+func valueNames(values protoreflect.EnumValueDescriptors, n protoreflect.EnumNumber) []string {
+	var names []string
+	for i := 0; i < values.Len(); i++ {
+		if v := values.Get(i); v.Number() == n {
+			names = append(names, string(v.Name()))
+		}
+	}
+	return names
+}
+
+func allowsAlias(ed protoreflect.EnumDescriptor) bool {
+	opts, _ := ed.Options().(*descriptorpb.EnumOptions)
+	return opts.GetAllowAlias()
+}
+
+// hasAliases reports whether some number of ed has several names.
+func hasAliases(ed protoreflect.EnumDescriptor) bool {
+	seen := make(map[protoreflect.EnumNumber]bool)
+	for i := 0; i < ed.Values().Len(); i++ {
+		n := ed.Values().Get(i).Number()
+		if seen[n] {
+			return true
+		}
+		seen[n] = true
+	}
+	return false
+}
@@ -0,0 +1,216 @@
+package compat
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+
+	"github.com/example/protobuf-compat/internal/schema"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// maxFuzzDepth bounds how deeply random messages nest, so that recursive
+// types terminate.
+const maxFuzzDepth = 4
+
+// RoundTripFailure is a random message that did not survive a round trip
+// through the old version.
+type RoundTripFailure struct {
+	Index   int    // position in the generated sequence
+	Payload []byte // the message as written by the new version
+	Err     error  // set if a step failed outright
+	Changes []schema.Change
+}
+
+// FuzzRoundTrip generates n random messages of type newMD and sends each
+// through a program built from oldMD: decode, re-encode, then decode with
+// newMD again. A message survives if it comes back unchanged; with
+// discardUnknown the old reader drops unknown fields, and the loss of the
+// fields oldMD does not declare is expected. The same seed generates the
+// same messages.
+func FuzzRoundTrip(oldMD, newMD protoreflect.MessageDescriptor, n int, seed uint64, discardUnknown bool) []RoundTripFailure {
+	rng := rand.New(rand.NewPCG(seed, seed))
+	var failures []RoundTripFailure
+	for i := 0; i < n; i++ {
+		orig := randomMessage(rng, newMD, 0)
+		payload, err := proto.Marshal(orig)
+		if err != nil {
+			failures = append(failures, RoundTripFailure{Index: i, Err: fmt.Errorf("marshal: %w", err)})
+			continue
+		}
+		back, err := roundTrip(payload, oldMD, newMD, discardUnknown)
+		if err != nil {
+			failures = append(failures, RoundTripFailure{Index: i, Payload: payload, Err: err})
+			continue
+		}
+		var want proto.Message = orig
+		if discardUnknown {
+			want = proto.Clone(orig)
+			dropUndeclared(want.ProtoReflect(), oldMD)
+		}
+		if !proto.Equal(want, back) {
+			failures = append(failures, RoundTripFailure{Index: i, Payload: payload, Changes: schema.Diff(want, back)})
+		}
+	}
+	return failures
+}
+
+func roundTrip(payload []byte, oldMD, newMD protoreflect.MessageDescriptor, discardUnknown bool) (proto.Message, error) {
+	mid := dynamicpb.NewMessage(oldMD)
+	if err := (proto.UnmarshalOptions{DiscardUnknown: discardUnknown}).Unmarshal(payload, mid); err != nil {
+		return nil, fmt.Errorf("old version decode: %w", err)
+	}
+	again, err := proto.Marshal(mid)
+	if err != nil {
+		return nil, fmt.Errorf("old version encode: %w", err)
+	}
+	back := dynamicpb.NewMessage(newMD)
+	if err := proto.Unmarshal(again, back); err != nil {
+		return nil, fmt.Errorf("new version decode: %w", err)
+	}
+	return back, nil
+}
+
+// dropUndeclared clears the fields of m, nested messages included, that
+// old does not declare.
+func dropUndeclared(m protoreflect.Message, old protoreflect.MessageDescriptor) {
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		ofd := old.Fields().ByNumber(fd.Number())
+		switch {
+		case ofd == nil:
+			m.Clear(fd)
+		case fd.Message() == nil || ofd.Message() == nil:
+		case fd.IsMap():
+			if ofd.IsMap() && ofd.MapValue().Message() != nil {
+				v.Map().Range(func(_ protoreflect.MapKey, e protoreflect.Value) bool {
+					dropUndeclared(e.Message(), ofd.MapValue().Message())
+					return true
+				})
+			}
+		case fd.IsList():
+			for i := 0; i < v.List().Len(); i++ {
+				dropUndeclared(v.List().Get(i).Message(), ofd.Message())
+			}
+		default:
+			dropUndeclared(v.Message(), ofd.Message())
+		}
+		return true
+	})
+}
+
+// randomMessage returns a message of type md with random fields set.
+func randomMessage(rng *rand.Rand, md protoreflect.MessageDescriptor, depth int) *dynamicpb.Message {
+	m := dynamicpb.NewMessage(md)
+	for i := 0; i < md.Fields().Len(); i++ {
+		fd := md.Fields().Get(i)
+		isMessage := fd.Message() != nil && !fd.IsMap()
+		if fd.Cardinality() != protoreflect.Required && (rng.IntN(3) == 0 || isMessage && depth >= maxFuzzDepth) {
+			continue
+		}
+		if od := fd.ContainingOneof(); od != nil && !od.IsSynthetic() && m.WhichOneof(od) != nil {
+			continue
+		}
+		switch {
+		case fd.IsMap():
+			mp := m.Mutable(fd).Map()
+			for n := rng.IntN(4); n > 0; n-- {
+				mp.Set(randomValue(rng, fd.MapKey(), depth).MapKey(), randomValue(rng, fd.MapValue(), depth))
+			}
+		case fd.IsList():
+			list := m.Mutable(fd).List()
+			for n := 1 + rng.IntN(3); n > 0; n-- {
+				list.Append(randomValue(rng, fd, depth))
+			}
+		default:
+			m.Set(fd, randomValue(rng, fd, depth))
+		}
+	}
+	return m
+}
+
+func randomValue(rng *rand.Rand, fd protoreflect.FieldDescriptor, depth int) protoreflect.Value {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return protoreflect.ValueOfBool(rng.IntN(2) == 1)
+	case protoreflect.EnumKind:
+		values := fd.Enum().Values()
+		return protoreflect.ValueOfEnum(values.Get(rng.IntN(values.Len())).Number())
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return protoreflect.ValueOfInt32(int32(randomBits(rng, 32)))
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return protoreflect.ValueOfInt64(int64(randomBits(rng, 64)))
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return protoreflect.ValueOfUint32(uint32(randomBits(rng, 32)))
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return protoreflect.ValueOfUint64(randomBits(rng, 64))
+	case protoreflect.FloatKind:
+		return protoreflect.ValueOfFloat32(float32(rng.NormFloat64() * 1e3))
+	case protoreflect.DoubleKind:
+		return protoreflect.ValueOfFloat64(rng.NormFloat64() * 1e6)
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(randomString(rng))
+	case protoreflect.BytesKind:
+		b := make([]byte, rng.IntN(16))
+		for i := range b {
+			b[i] = byte(rng.UintN(256))
+		}
+		return protoreflect.ValueOfBytes(b)
+	default: // message, group
+		if m := wellKnownMessage(rng, fd.Message()); m != nil {
+			return protoreflect.ValueOfMessage(m)
+		}
+		return protoreflect.ValueOfMessage(randomMessage(rng, fd.Message(), depth+1))
+	}
+}
+
+// wellKnownMessage returns a random valid value of a well-known type whose
+// JSON mapping constrains its fields, or nil for other types. Values that
+// are hard to generate validly, such as Any, are left empty.
+func wellKnownMessage(rng *rand.Rand, md protoreflect.MessageDescriptor) *dynamicpb.Message {
+	m := dynamicpb.NewMessage(md)
+	fields := md.Fields()
+	switch md.FullName() {
+	case "google.protobuf.Timestamp":
+		m.Set(fields.ByName("seconds"), protoreflect.ValueOfInt64(rng.Int64N(253402300800))) // until 9999
+		m.Set(fields.ByName("nanos"), protoreflect.ValueOfInt32(rng.Int32N(1e9)))
+	case "google.protobuf.Duration":
+		m.Set(fields.ByName("seconds"), protoreflect.ValueOfInt64(rng.Int64N(315576000000)))
+		m.Set(fields.ByName("nanos"), protoreflect.ValueOfInt32(rng.Int32N(1e9)))
+	case "google.protobuf.Value":
+		m.Set(fields.ByName("null_value"), protoreflect.ValueOfEnum(0))
+	case "google.protobuf.Any", "google.protobuf.FieldMask", "google.protobuf.Struct", "google.protobuf.ListValue":
+	default:
+		return nil
+	}
+	return m
+}
+
+// randomBits returns small numbers, extremes and everything between with
+// similar odds, as the interesting values of a field of that many bits
+// are at the edges.
+func randomBits(rng *rand.Rand, bits int) uint64 {
+	mask := uint64(math.MaxUint64) >> (64 - bits)
+	switch rng.IntN(4) {
+	case 0:
+		return rng.Uint64N(128)
+	case 1:
+		return mask - rng.Uint64N(128) // negative when signed
+	case 2:
+		return mask >> 1 // the largest signed value
+	}
+	return rng.Uint64() & mask
+}
+
+// fuzzRunes are the characters random strings are drawn from, a few of
+// them outside ASCII.
+var fuzzRunes = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789 -_./:éß漢字😀")
+
+func randomString(rng *rand.Rand) string {
+	r := make([]rune, rng.IntN(12))
+	for i := range r {
+		r[i] = fuzzRunes[rng.IntN(len(fuzzRunes))]
+	}
+	return string(r)
+}
@@ -0,0 +1,172 @@
+package compat
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Golden is a payload recorded with one version of a schema, in both
+// encodings, along with what it decoded to at the time.
+type Golden struct {
+	Binary []byte
+	JSON   []byte
+	// Want renders the decoded message one field per line, keyed by field
+	// number, so that renaming fields does not change it but reading their
+	// values differently does.
+	Want string
+}
+
+// RecordGolden generates n random messages of type md, as FuzzRoundTrip
+// does, and returns them as golden payloads.
+func RecordGolden(md protoreflect.MessageDescriptor, n int, seed uint64) ([]Golden, error) {
+	rng := rand.New(rand.NewPCG(seed, seed))
+	out := make([]Golden, n)
+	for i := range out {
+		m := randomMessage(rng, md, 0)
+		var err error
+		if out[i].Binary, err = (proto.MarshalOptions{Deterministic: true}).Marshal(m); err != nil {
+			return nil, err
+		}
+		if out[i].JSON, err = (protojson.MarshalOptions{Multiline: true}).Marshal(m); err != nil {
+			return nil, err
+		}
+		out[i].Want = Render(m)
+	}
+	return out, nil
+}
+
+// Replay decodes both encodings of g as md and returns a description of
+// each way the result differs from what was recorded.
+func (g Golden) Replay(md protoreflect.MessageDescriptor) []string {
+	var problems []string
+	decoded := func(enc string, unmarshal func(proto.Message) error) {
+		m := dynamicpb.NewMessage(md)
+		if err := unmarshal(m); err != nil {
+			problems = append(problems, fmt.Sprintf("%s payload no longer decodes: %v", enc, err))
+			return
+		}
+		for _, d := range diffLines(g.Want, Render(m)) {
+			problems = append(problems, fmt.Sprintf("%s payload decodes differently: %s", enc, d))
+		}
+	}
+	decoded("binary", func(m proto.Message) error { return proto.Unmarshal(g.Binary, m) })
+	decoded("JSON", func(m proto.Message) error { return protojson.Unmarshal(g.JSON, m) })
+	return problems
+}
+
+// diffLines lists the lines only in want as "- line" and those only in got
+// as "+ line".
+func diffLines(want, got string) []string {
+	count := make(map[string]int)
+	for _, l := range strings.Split(want, "\n") {
+		count[l]++
+	}
+	for _, l := range strings.Split(got, "\n") {
+		count[l]--
+	}
+	var out []string
+	for l, n := range count {
+		switch {
+		case n > 0:
+			out = append(out, "- "+l)
+		case n < 0:
+			out = append(out, "+ "+l)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i][2:] != out[j][2:] {
+			return out[i][2:] < out[j][2:]
+		}
+		return out[i][0] > out[j][0] // "-" before "+"
+	})
+	return out
+}
+
+// Render describes m one field per line, as "3.1: 42" for field 1 of the
+// message in field 3, in field number order. Unknown fields are rendered
+// as raw bytes, since no schema says what they hold.
+func Render(m protoreflect.Message) string {
+	var lines []string
+	render(m, "", &lines)
+	return strings.Join(lines, "\n")
+}
+
+func render(m protoreflect.Message, prefix string, lines *[]string) {
+	var fields []protoreflect.FieldDescriptor
+	m.Range(func(fd protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+		fields = append(fields, fd)
+		return true
+	})
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Number() < fields[j].Number() })
+	for _, fd := range fields {
+		path := prefix + strconv.Itoa(int(fd.Number()))
+		v := m.Get(fd)
+		switch {
+		case fd.IsList():
+			for i := 0; i < v.List().Len(); i++ {
+				renderValue(fd, v.List().Get(i), fmt.Sprintf("%s[%d]", path, i), lines)
+			}
+		case fd.IsMap():
+			var keys []protoreflect.MapKey
+			v.Map().Range(func(k protoreflect.MapKey, _ protoreflect.Value) bool {
+				keys = append(keys, k)
+				return true
+			})
+			sortKeys(keys)
+			for _, k := range keys {
+				renderValue(fd.MapValue(), v.Map().Get(k), fmt.Sprintf("%s[%s]", path, keyString(k)), lines)
+			}
+		default:
+			renderValue(fd, v, path, lines)
+		}
+	}
+	if unknown := m.GetUnknown(); len(unknown) > 0 {
+		*lines = append(*lines, fmt.Sprintf("%s?: %s", prefix, hex.EncodeToString(unknown)))
+	}
+}
+
+func renderValue(fd protoreflect.FieldDescriptor, v protoreflect.Value, path string, lines *[]string) {
+	var s string
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		prefix := path + "."
+		if path == "" {
+			prefix = ""
+		}
+		n := len(*lines)
+		render(v.Message(), prefix, lines)
+		if len(*lines) > n {
+			return
+		}
+		s = "{}"
+	case protoreflect.EnumKind:
+		s = strconv.Itoa(int(v.Enum()))
+	case protoreflect.StringKind:
+		s = strconv.Quote(v.String())
+	case protoreflect.BytesKind:
+		s = hex.EncodeToString(v.Bytes())
+	default:
+		s = fmt.Sprint(v.Interface())
+	}
+	*lines = append(*lines, path+": "+s)
+}
+
+func sortKeys(keys []protoreflect.MapKey) {
+	sort.Slice(keys, func(i, j int) bool { return keyString(keys[i]) < keyString(keys[j]) })
+}
+
+func keyString(k protoreflect.MapKey) string {
+	if s, ok := k.Interface().(string); ok {
+		return strconv.Quote(s)
+	}
+	return fmt.Sprint(k.Interface())
+}
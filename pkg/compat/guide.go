@@ -0,0 +1,84 @@
+package compat
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Rollout returns the order in which to deploy producers and consumers of
+// the new version, judged by the directions of the findings at warning or
+// above.
+func (r *Report) Rollout() string {
+	var d Direction
+	for _, f := range r.Findings {
+		if f.Severity >= Warning {
+			d |= f.Affects
+		}
+	}
+	switch d {
+	case 0:
+		return "Producers and consumers can be upgraded in any order."
+	case Forward:
+		return "Upgrade consumers first, then producers: old consumers cannot safely read payloads of the new version."
+	case Backward:
+		return "Upgrade producers first, then consumers, and migrate or expire stored payloads of the old version: new consumers cannot safely read them."
+	}
+	return "No deployment order is safe: neither version reads the other's payloads safely. " +
+		"Split the change into steps that are each safe in one direction, such as adding a field before removing the one it replaces."
+}
+
+// WriteMarkdown writes r as a migration guide from version old to new:
+// a summary, the rollout order, and each change with its classification
+// and the readers it affects.
+func (r *Report) WriteMarkdown(w io.Writer, old, new string) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# Migrating from %s to %s\n\n", old, new)
+	fmt.Fprintf(bw, "%d breaking, %d warnings, %d info.\n\n", r.Count(Breaking), r.Count(Warning), r.Count(Info))
+
+	fmt.Fprintf(bw, "## Rollout\n\n%s\n\n", r.Rollout())
+
+	fmt.Fprintf(bw, "## Changes\n\n")
+	if len(r.Findings) == 0 {
+		fmt.Fprintf(bw, "No changes.\n")
+		return bw.Flush()
+	}
+	for _, sev := range []Severity{Breaking, Warning, Info} {
+		if r.Count(sev) == 0 {
+			continue
+		}
+		fmt.Fprintf(bw, "### %s\n\n", guideHeadings[sev])
+		fmt.Fprintf(bw, "| Element | Rule | Affects | Details |\n|---|---|---|---|\n")
+		for _, f := range r.Findings {
+			if f.Severity != sev {
+				continue
+			}
+			element := "`" + f.Path + "`"
+			if f.File != "" {
+				element += fmt.Sprintf(" (%s:%d)", f.File, f.Line)
+			}
+			fmt.Fprintf(bw, "| %s | %s | %s | %s |\n", element, f.Rule, guideReaders[f.Affects], markdownCell(f.Message))
+		}
+		fmt.Fprintln(bw)
+	}
+	return bw.Flush()
+}
+
+var guideHeadings = map[Severity]string{
+	Breaking: "Breaking",
+	Warning:  "Needs care",
+	Info:     "Compatible",
+}
+
+// guideReaders describes the readers a finding affects.
+var guideReaders = map[Direction]string{
+	Backward: "new consumers of old payloads",
+	Forward:  "old consumers of new payloads",
+	Both:     "both",
+}
+
+// markdownCell escapes s for a table cell.
+func markdownCell(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
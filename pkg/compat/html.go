@@ -0,0 +1,225 @@
+package compat
+
+import (
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Comparison is one report of a schema change, along with the versions it
+// compares.
+type Comparison struct {
+	Old, New           string // names of the versions
+	OldFiles, NewFiles []protoreflect.FileDescriptor
+	Report             *Report
+}
+
+// WriteHTML writes comparisons as a standalone HTML page to attach to a
+// schema change proposal. Each comparison has a summary and rollout order,
+// then a collapsible section per message, enum or service with findings,
+// showing them with severity badges and, for messages, the fields before
+// and after the change side by side.
+func WriteHTML(w io.Writer, title string, comparisons ...Comparison) error {
+	page := htmlPage{Title: title}
+	for _, c := range comparisons {
+		page.Comparisons = append(page.Comparisons, htmlComparison(c))
+	}
+	return htmlTemplate.Execute(w, page)
+}
+
+type htmlPage struct {
+	Title       string
+	Comparisons []htmlReport
+}
+
+type htmlReport struct {
+	Old, New                 string
+	Breaking, Warnings, Info int
+	Rollout                  string
+	Sections                 []htmlSection
+}
+
+// htmlSection holds the findings of one message, enum or service.
+type htmlSection struct {
+	Kind, Name string
+	Open       bool // has findings at warning or above
+	Findings   []Finding
+	Fields     []htmlField
+}
+
+// htmlField is a row of a message's before and after table.
+type htmlField struct {
+	Number        protoreflect.FieldNumber
+	Before, After string
+	Changed       bool
+}
+
+func htmlComparison(c Comparison) htmlReport {
+	out := htmlReport{
+		Old:      c.Old,
+		New:      c.New,
+		Breaking: c.Report.Count(Breaking),
+		Warnings: c.Report.Count(Warning),
+		Info:     c.Report.Count(Info),
+		Rollout:  c.Report.Rollout(),
+	}
+	name := newNamer(c.OldFiles, c.NewFiles).name
+	oldIdx, newIdx := elements(c.OldFiles, name), elements(c.NewFiles, name)
+	index := make(map[string]int)
+	for _, f := range c.Report.Findings {
+		name, d := owner(f.Path, newIdx, oldIdx)
+		i, ok := index[name]
+		if !ok {
+			i = len(out.Sections)
+			index[name] = i
+			out.Sections = append(out.Sections, htmlSection{Kind: kindName(d), Name: name})
+		}
+		s := &out.Sections[i]
+		s.Findings = append(s.Findings, f)
+		s.Open = s.Open || f.Severity >= Warning
+	}
+	for i := range out.Sections {
+		s := &out.Sections[i]
+		om, _ := oldIdx[s.Name].(protoreflect.MessageDescriptor)
+		nm, _ := newIdx[s.Name].(protoreflect.MessageDescriptor)
+		s.Fields = fieldRows(om, nm)
+	}
+	return out
+}
+
+// owner returns the message, enum or service a finding path belongs to:
+// the longest prefix of path naming one in either version.
+func owner(path string, idx ...map[string]protoreflect.Descriptor) (string, protoreflect.Descriptor) {
+	for name := path; ; {
+		for _, m := range idx {
+			switch d := m[name].(type) {
+			case protoreflect.MessageDescriptor, protoreflect.EnumDescriptor, protoreflect.ServiceDescriptor:
+				return name, d
+			}
+		}
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			return path, nil
+		}
+		name = name[:i]
+	}
+}
+
+func kindName(d protoreflect.Descriptor) string {
+	switch d.(type) {
+	case protoreflect.MessageDescriptor:
+		return "message"
+	case protoreflect.EnumDescriptor:
+		return "enum"
+	case protoreflect.ServiceDescriptor:
+		return "service"
+	}
+	return "element"
+}
+
+// fieldRows lines up the fields of a message in two versions, either of
+// which may be nil, by number.
+func fieldRows(old, new protoreflect.MessageDescriptor) []htmlField {
+	rows := make(map[protoreflect.FieldNumber]*htmlField)
+	row := func(n protoreflect.FieldNumber) *htmlField {
+		if rows[n] == nil {
+			rows[n] = &htmlField{Number: n}
+		}
+		return rows[n]
+	}
+	if old != nil {
+		for i := 0; i < old.Fields().Len(); i++ {
+			fd := old.Fields().Get(i)
+			row(fd.Number()).Before = fieldDecl(fd)
+		}
+	}
+	if new != nil {
+		for i := 0; i < new.Fields().Len(); i++ {
+			fd := new.Fields().Get(i)
+			row(fd.Number()).After = fieldDecl(fd)
+		}
+	}
+	out := make([]htmlField, 0, len(rows))
+	for _, r := range rows {
+		r.Changed = r.Before != r.After
+		out = append(out, *r)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Number < out[j].Number })
+	return out
+}
+
+// fieldDecl renders fd roughly as declared in a .proto file.
+func fieldDecl(fd protoreflect.FieldDescriptor) string {
+	var label string
+	switch {
+	case fd.IsMap():
+	case fd.IsList():
+		label = "repeated "
+	case fd.Cardinality() == protoreflect.Required:
+		label = "required "
+	case fd.HasOptionalKeyword():
+		label = "optional "
+	}
+	decl := label + typeName(fd) + " " + string(fd.Name())
+	if od := realOneof(fd); od != nil {
+		decl = "oneof " + string(od.Name()) + " { " + decl + " }"
+	}
+	return decl
+}
+
+var htmlBadges = map[Severity]string{Breaking: "breaking", Warning: "warning", Info: "info"}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"badge":   func(s Severity) string { return htmlBadges[s] },
+	"readers": func(d Direction) string { return guideReaders[d] },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2em auto; max-width: 72em; color: #1f2328; }
+code, td.decl { font-family: ui-monospace, monospace; }
+details { border: 1px solid #d0d7de; border-radius: 6px; margin: .5em 0; padding: .5em 1em; }
+summary { cursor: pointer; font-weight: 600; }
+table { border-collapse: collapse; margin: .5em 0; width: 100%; }
+th, td { border: 1px solid #d0d7de; padding: .25em .5em; text-align: left; vertical-align: top; }
+tr.changed td.decl { background: #fff8c5; }
+.badge { border-radius: 1em; color: #fff; font-size: .8em; padding: .1em .6em; white-space: nowrap; }
+.breaking { background: #cf222e; }
+.warning { background: #bf8700; }
+.info { background: #0969da; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{range .Comparisons}}
+<h2>{{.Old}} → {{.New}}</h2>
+<p>
+<span class="badge breaking">{{.Breaking}} breaking</span>
+<span class="badge warning">{{.Warnings}} warnings</span>
+<span class="badge info">{{.Info}} info</span>
+</p>
+<p>{{.Rollout}}</p>
+{{range .Sections}}
+<details{{if .Open}} open{{end}}>
+<summary>{{.Kind}} <code>{{.Name}}</code>{{range .Findings}} <span class="badge {{badge .Severity}}">{{.Rule}}</span>{{end}}</summary>
+<table>
+<tr><th>Severity</th><th>Rule</th><th>Element</th><th>Affects</th><th>Details</th></tr>
+{{range .Findings}}<tr><td><span class="badge {{badge .Severity}}">{{badge .Severity}}</span></td><td>{{.Rule}}</td><td><code>{{.Path}}</code>{{if .File}}<br>{{.File}}:{{.Line}}{{end}}</td><td>{{readers .Affects}}</td><td>{{.Message}}</td></tr>
+{{end}}</table>
+{{if .Fields}}<table>
+<tr><th>#</th><th>Before</th><th>After</th></tr>
+{{range .Fields}}<tr{{if .Changed}} class="changed"{{end}}><td>{{.Number}}</td><td class="decl">{{.Before}}</td><td class="decl">{{.After}}</td></tr>
+{{end}}</table>{{end}}
+</details>
+{{else}}
+<p>No changes.</p>
+{{end}}
+{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,107 @@
+package compat
+
+import "google.golang.org/protobuf/reflect/protoreflect"
+
+// jsonNames checks that protojson payloads map to the same fields in both
+// versions. Binary payloads identify fields by number, JSON ones by name:
+// protojson writes the JSON name and reads either it or the field name.
+func (c *checker) jsonNames(name string, old, new protoreflect.MessageDescriptor) {
+	for i := 0; i < old.Fields().Len(); i++ {
+		of := old.Fields().Get(i)
+		nf := new.Fields().ByNumber(of.Number())
+		if nf == nil || (acceptsJSON(nf, of.JSONName()) && acceptsJSON(of, nf.JSONName())) {
+			continue
+		}
+		c.add(JSONNameChanged, Warning, name+"."+string(of.Name()),
+			"JSON key of field %d changed from %q to %q; protojson readers of one version reject the other's key, or drop it with DiscardUnknown",
+			of.Number(), of.JSONName(), nf.JSONName())
+	}
+
+	for i := 0; i < new.Fields().Len(); i++ {
+		nf := new.Fields().Get(i)
+		path := name + "." + string(nf.Name())
+		for j := 0; j < old.Fields().Len(); j++ {
+			of := old.Fields().Get(j)
+			if of.Number() == nf.Number() || of.Name() == nf.Name() || !acceptsJSON(nf, of.JSONName()) {
+				continue
+			}
+			c.add(JSONNameConflict, Warning, path,
+				"JSON key %q was field %d (%s) in the old version; old JSON payloads put its value into field %d",
+				of.JSONName(), of.Number(), of.Name(), nf.Number())
+		}
+		for j := i + 1; j < new.Fields().Len(); j++ {
+			other := new.Fields().Get(j)
+			for _, key := range []string{nf.JSONName(), string(nf.Name())} {
+				if acceptsJSON(other, key) {
+					c.add(JSONNameConflict, Breaking, path,
+						"fields %d (%s) and %d (%s) both map to JSON key %q; protojson cannot tell them apart",
+						nf.Number(), nf.Name(), other.Number(), other.Name(), key)
+					break
+				}
+			}
+		}
+	}
+}
+
+// acceptsJSON reports whether protojson reads key as field fd.
+func acceptsJSON(fd protoreflect.FieldDescriptor, key string) bool {
+	return key == fd.JSONName() || key == string(fd.Name())
+}
+
+// jsonType checks a type change that binary payloads survive for a change
+// of JSON representation.
+func (c *checker) jsonType(path string, of, nf protoreflect.FieldDescriptor) {
+	oj, nj := jsonForm(of), jsonForm(nf)
+	switch {
+	case oj == nj:
+	case pair(oj, nj, jsonNumber, jsonInt64):
+		c.add(JSONTypeChanged, Warning, path,
+			"JSON form of field %d changed from %s to %s; protojson reads either, but other JSON consumers see a different type",
+			of.Number(), oj, nj)
+	case nj == jsonEnum && (oj == jsonNumber || oj == jsonInt64):
+		c.addFor(Forward, JSONTypeChanged, Breaking, path,
+			"JSON form of field %d changed from %s to %s; old protojson readers reject the value names new producers write",
+			of.Number(), oj, nj)
+	case oj == jsonEnum && (nj == jsonNumber || nj == jsonInt64):
+		c.addFor(Backward, JSONTypeChanged, Breaking, path,
+			"JSON form of field %d changed from %s to %s; new protojson readers reject the value names old producers wrote",
+			of.Number(), oj, nj)
+	default:
+		c.add(JSONTypeChanged, Breaking, path,
+			"JSON form of field %d changed from %s to %s; protojson readers of each version reject or misread the other's values",
+			of.Number(), oj, nj)
+	}
+}
+
+// JSON representations of field values in protojson.
+const (
+	jsonNumber = "number"
+	jsonInt64  = "integer string"
+	jsonBool   = "boolean"
+	jsonString = "string"
+	jsonBytes  = "base64 string"
+	jsonEnum   = "enum value name"
+	jsonObject = "object"
+)
+
+func jsonForm(fd protoreflect.FieldDescriptor) string {
+	if fd.IsMap() {
+		return jsonObject
+	}
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return jsonBool
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Uint64Kind,
+		protoreflect.Fixed64Kind, protoreflect.Sfixed64Kind:
+		return jsonInt64
+	case protoreflect.StringKind:
+		return jsonString
+	case protoreflect.BytesKind:
+		return jsonBytes
+	case protoreflect.EnumKind:
+		return jsonEnum
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return jsonObject
+	}
+	return jsonNumber
+}
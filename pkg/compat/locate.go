@@ -0,0 +1,55 @@
+package compat
+
+import "google.golang.org/protobuf/reflect/protoreflect"
+
+// locate fills in the source position of findings whose element has one
+// in the descriptors, looking in the new version first. Descriptor sets
+// carry positions only when built with source info
+// (protoc --include_source_info); linked-in Go types never do.
+func locate(findings []Finding, old, new []protoreflect.FileDescriptor, name func(protoreflect.Descriptor) string) {
+	newIdx, oldIdx := elements(new, name), elements(old, name)
+	for i := range findings {
+		f := &findings[i]
+		for _, idx := range []map[string]protoreflect.Descriptor{newIdx, oldIdx} {
+			d, ok := idx[f.Path]
+			if !ok {
+				continue
+			}
+			loc := d.ParentFile().SourceLocations().ByDescriptor(d)
+			if loc.Path == nil {
+				continue
+			}
+			f.File, f.Line = d.ParentFile().Path(), loc.StartLine+1
+			break
+		}
+	}
+}
+
+// elements indexes the messages, fields, oneofs, enums, enum values,
+// services and methods of files by the paths findings use, with types
+// named by name.
+func elements(files []protoreflect.FileDescriptor, typeName func(protoreflect.Descriptor) string) map[string]protoreflect.Descriptor {
+	out := make(map[string]protoreflect.Descriptor)
+	for name, md := range messages(files, typeName) {
+		out[name] = md
+		for i := 0; i < md.Fields().Len(); i++ {
+			out[name+"."+string(md.Fields().Get(i).Name())] = md.Fields().Get(i)
+		}
+		for i := 0; i < md.Oneofs().Len(); i++ {
+			out[name+"."+string(md.Oneofs().Get(i).Name())] = md.Oneofs().Get(i)
+		}
+	}
+	for name, ed := range enums(files, typeName) {
+		out[name] = ed
+		for i := 0; i < ed.Values().Len(); i++ {
+			out[name+"."+string(ed.Values().Get(i).Name())] = ed.Values().Get(i)
+		}
+	}
+	for name, sd := range services(files, typeName) {
+		out[name] = sd
+		for i := 0; i < sd.Methods().Len(); i++ {
+			out[name+"."+string(sd.Methods().Get(i).Name())] = sd.Methods().Get(i)
+		}
+	}
+	return out
+}
@@ -0,0 +1,110 @@
+package compat
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// moves matches messages missing from the new version to added messages
+// of the same shape, so that a nested message hoisted to the top level, or
+// moved between parents, is compared with its new self instead of reported
+// removed and added. Moves are recorded alongside the declared renames,
+// which take precedence, and so carry the types nested in a moved message
+// with it.
+func (c *checker) moves(oldMsgs, newMsgs map[string]protoreflect.MessageDescriptor) {
+	renames := make(Renames, len(c.renames))
+	for from, to := range c.renames {
+		renames[from] = to
+	}
+	c.renames, c.moved = renames, make(map[string]bool)
+
+	claimed := make(map[string]bool)
+	for _, md := range oldMsgs {
+		claimed[c.target(md)] = true
+	}
+	// Parents sort before the types nested in them, whose targets follow
+	// once their parent has moved.
+	for _, name := range sortedNames(oldMsgs) {
+		if t := c.target(oldMsgs[name]); newMsgs[t] != nil {
+			claimed[t] = true
+			continue
+		}
+		if to := sameShape(name, oldMsgs[name], newMsgs, claimed); to != "" {
+			c.renames[name] = to
+			c.moved[name] = true
+			claimed[to] = true
+		}
+	}
+}
+
+// sameShape returns the unclaimed message of msgs, under another parent
+// than oldName, that old most likely became: one of the same name whose
+// fields are mostly the same, or one of another name with exactly the same
+// fields. It returns "" if there is no such message or more than one.
+func sameShape(oldName string, old protoreflect.MessageDescriptor, msgs map[string]protoreflect.MessageDescriptor, claimed map[string]bool) string {
+	best, bestScore, tied := "", 0.0, false
+	for _, name := range sortedNames(msgs) {
+		md := msgs[name]
+		if claimed[name] {
+			continue
+		}
+		score := shapeScore(old, md)
+		switch {
+		case score < 0.5, parentName(name) == parentName(oldName):
+			continue // renames within a parent need declaring
+		case md.Name() != old.Name() && score < 1:
+			continue
+		}
+		switch {
+		case score > bestScore:
+			best, bestScore, tied = name, score, false
+		case score == bestScore:
+			tied = true
+		}
+	}
+	if tied {
+		return ""
+	}
+	return best
+}
+
+// shapeScore returns the share of the fields of old and new, by name and
+// kind, that the two have in common. Field numbers are left out, so that
+// a move that also renumbers fields is still found and reported.
+func shapeScore(old, new protoreflect.MessageDescriptor) float64 {
+	total := max(old.Fields().Len(), new.Fields().Len())
+	if total == 0 {
+		return 1
+	}
+	same := 0
+	for i := 0; i < old.Fields().Len(); i++ {
+		of := old.Fields().Get(i)
+		if nf := new.Fields().ByName(of.Name()); nf != nil && nf.Kind() == of.Kind() && nf.IsList() == of.IsList() {
+			same++
+		}
+	}
+	return float64(same) / float64(total)
+}
+
+// movedMessage describes the move of message old to new for MESSAGE_MOVED.
+func (c *checker) movedMessage(old, new protoreflect.MessageDescriptor) string {
+	to := c.target(old)
+	var renumbered []string
+	for i := 0; i < old.Fields().Len(); i++ {
+		of := old.Fields().Get(i)
+		if nf := new.Fields().ByName(of.Name()); nf != nil && nf.Number() != of.Number() {
+			renumbered = append(renumbered, string(of.Name()))
+		}
+	}
+	where := fmt.Sprintf("moved to %s, matched by its fields", to)
+	if old.Name() != new.Name() {
+		where = fmt.Sprintf("moved and renamed to %s, matched by its fields", to)
+	}
+	if len(renumbered) > 0 {
+		return fmt.Sprintf("%s; fields %s changed numbers, which breaks payloads, and Any values name the type and no longer resolve",
+			where, strings.Join(renumbered, ", "))
+	}
+	return where + "; its field numbers are unchanged, so binary and JSON payloads are unaffected, but Any values name the type and no longer resolve"
+}
@@ -0,0 +1,96 @@
+package compat
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// oneofs checks how fields moved into, out of and between oneofs. The
+// wire format has no notion of oneofs: a reader just keeps the last member
+// it sees and clears the others, so the risk is in payloads setting fields
+// that one version treats as exclusive and the other does not.
+func (c *checker) oneofs(name string, old, new protoreflect.MessageDescriptor) {
+	// Fields that were independent and now share a oneof.
+	joined := make(map[protoreflect.Name][]string)
+	var joinedOrder []protoreflect.Name
+
+	for i := 0; i < old.Fields().Len(); i++ {
+		of := old.Fields().Get(i)
+		path := name + "." + string(of.Name())
+		oo := realOneof(of)
+		nf := new.Fields().ByNumber(of.Number())
+		if nf == nil {
+			if oo != nil {
+				c.addFor(Backward, OneofMemberRemoved, Warning, path,
+					"removed from oneof %s; new readers of payloads setting it see %s as unset", oo.Name(), oo.Name())
+			}
+			continue
+		}
+		no := realOneof(nf)
+		switch {
+		case oo == nil && no == nil:
+		case oo == nil:
+			if joined[no.Name()] == nil {
+				joinedOrder = append(joinedOrder, no.Name())
+			}
+			joined[no.Name()] = append(joined[no.Name()], string(of.Name()))
+		case no == nil:
+			c.addFor(Forward, OneofMembershipChanged, Warning, path,
+				"moved out of oneof %s; old readers still keep only one of it and its former siblings, and generated accessors change",
+				oo.Name())
+		case oo.Name() != no.Name() && !sameMembers(oo, no):
+			c.add(OneofMembershipChanged, Warning, path,
+				"moved from oneof %s to %s; each version clears different fields when it is set", oo.Name(), no.Name())
+		}
+	}
+
+	for _, oneof := range joinedOrder {
+		fields := joined[oneof]
+		if len(fields) == 1 {
+			c.addFor(Backward, OneofMembershipChanged, Warning, name+"."+fields[0],
+				"moved into oneof %s; new readers drop it when a payload also sets another member, and generated accessors change",
+				oneof)
+			continue
+		}
+		c.addFor(Backward, OneofMembershipChanged, Breaking, name+"."+string(oneof),
+			"existing fields %s moved into one oneof; old payloads setting several of them lose all but the last in new readers",
+			strings.Join(fields, ", "))
+	}
+
+	for i := 0; i < new.Fields().Len(); i++ {
+		nf := new.Fields().Get(i)
+		no := realOneof(nf)
+		if no == nil || old.Fields().ByNumber(nf.Number()) != nil {
+			continue
+		}
+		if old.Oneofs().ByName(no.Name()) != nil {
+			c.addFor(Forward, OneofMemberAdded, Info, name+"."+string(nf.Name()),
+				"added to oneof %s; old readers of payloads setting it see %s as unset", no.Name(), no.Name())
+		}
+	}
+}
+
+// realOneof returns the oneof fd belongs to, ignoring the synthetic ones
+// of proto3 optional fields.
+func realOneof(fd protoreflect.FieldDescriptor) protoreflect.OneofDescriptor {
+	if od := fd.ContainingOneof(); od != nil && !od.IsSynthetic() {
+		return od
+	}
+	return nil
+}
+
+// sameMembers reports whether two oneofs hold the same field numbers, as a
+// merely renamed oneof does.
+func sameMembers(a, b protoreflect.OneofDescriptor) bool {
+	return memberNumbers(a) == memberNumbers(b)
+}
+
+func memberNumbers(od protoreflect.OneofDescriptor) string {
+	var b strings.Builder
+	for i := 0; i < od.Fields().Len(); i++ {
+		fmt.Fprintf(&b, "%d,", od.Fields().Get(i).Number())
+	}
+	return b.String()
+}
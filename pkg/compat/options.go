@@ -0,0 +1,135 @@
+package compat
+
+import (
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// API rules concern the contract between clients and servers, their
+// services and the expectations attached to fields, rather than the
+// payloads themselves.
+const API Category = "api"
+
+// fieldOptions checks field options that change what clients may expect
+// of a field without changing its encoding: deprecation and the
+// google.api.field_behavior annotations of AIP-203. Changes to json_name
+// are checked by jsonNames, as they change protojson payloads.
+func (c *checker) fieldOptions(name string, old, new protoreflect.MessageDescriptor) {
+	for i := 0; i < old.Fields().Len(); i++ {
+		of := old.Fields().Get(i)
+		nf := new.Fields().ByNumber(of.Number())
+		if nf == nil {
+			continue
+		}
+		path := name + "." + string(of.Name())
+		switch od, nd := deprecated(of), deprecated(nf); {
+		case !od && nd:
+			c.add(FieldDeprecated, Info, path,
+				"field %d is now deprecated; generated code flags its uses, and it is a candidate for removal", of.Number())
+		case od && !nd:
+			c.add(FieldUndeprecated, Info, path, "field %d is no longer deprecated", of.Number())
+		}
+		ob, nb := fieldBehaviors(of), fieldBehaviors(nf)
+		for _, b := range behaviorOrder {
+			switch {
+			case !ob[b] && nb[b]:
+				c.add(FieldBehaviorChanged, behaviors[b].sev[0], path,
+					"field %d is now %s; %s", of.Number(), behaviors[b].name, behaviors[b].added)
+			case ob[b] && !nb[b]:
+				c.add(FieldBehaviorChanged, behaviors[b].sev[1], path,
+					"field %d is no longer %s; %s", of.Number(), behaviors[b].name, behaviors[b].removed)
+			}
+		}
+	}
+}
+
+// deprecated reports whether fd sets the deprecated option.
+func deprecated(fd protoreflect.FieldDescriptor) bool {
+	opts, _ := fd.Options().(*descriptorpb.FieldOptions)
+	return opts.GetDeprecated()
+}
+
+// fieldBehaviorNumber is the number of the google.api.field_behavior
+// extension of FieldOptions.
+const fieldBehaviorNumber = 1052
+
+// fieldBehaviors returns the google.api.field_behavior values of fd. The
+// extension is read from the encoded options, as schemas seldom come with
+// the googleapis types linked in, leaving it an unknown field.
+func fieldBehaviors(fd protoreflect.FieldDescriptor) map[int32]bool {
+	opts, _ := fd.Options().(*descriptorpb.FieldOptions)
+	if opts == nil {
+		return nil
+	}
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(opts)
+	if err != nil {
+		return nil
+	}
+	out := make(map[int32]bool)
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			break
+		}
+		b = b[n:]
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			break
+		}
+		value := b[:n]
+		b = b[n:]
+		if num != fieldBehaviorNumber {
+			continue
+		}
+		if typ == protowire.BytesType {
+			value, _ = protowire.ConsumeBytes(value) // packed
+		}
+		for len(value) > 0 {
+			v, m := protowire.ConsumeVarint(value)
+			if m < 0 {
+				break
+			}
+			out[int32(v)] = true
+			value = value[m:]
+		}
+	}
+	return out
+}
+
+// behavior describes a google.api.FieldBehavior value and what adding and
+// removing it means to clients, with the severity of each.
+type behavior struct {
+	name           string
+	sev            [2]Severity // added, removed
+	added, removed string
+}
+
+var behaviors = map[int32]behavior{
+	1: {"OPTIONAL", [2]Severity{Info, Info},
+		"documentation only", "documentation only"},
+	2: {"REQUIRED", [2]Severity{Warning, Warning},
+		"servers may reject requests from clients that leave it unset",
+		"readers that relied on it being set must handle it missing"},
+	3: {"OUTPUT_ONLY", [2]Severity{Warning, Info},
+		"servers ignore or reject values that clients set",
+		"clients may now set it"},
+	4: {"INPUT_ONLY", [2]Severity{Warning, Info},
+		"servers no longer return it in responses",
+		"servers may now return it in responses"},
+	5: {"IMMUTABLE", [2]Severity{Warning, Info},
+		"servers reject updates that change it once set",
+		"clients may now change it after creation"},
+	6: {"UNORDERED_LIST", [2]Severity{Warning, Info},
+		"clients can no longer rely on the order of its elements",
+		"servers now keep the order of its elements"},
+	7: {"NON_EMPTY_DEFAULT", [2]Severity{Info, Info},
+		"servers fill in a default when it is unset",
+		"servers no longer fill in a default when it is unset"},
+	8: {"IDENTIFIER", [2]Severity{Warning, Warning},
+		"it now names the resource, and servers ignore it on create",
+		"it no longer names the resource"},
+}
+
+var behaviorOrder = []int32{1, 2, 3, 4, 5, 6, 7, 8}
@@ -0,0 +1,46 @@
+package compat
+
+import (
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// packed checks repeated scalar fields for changes to their encoding,
+// whether set by the packed option or by the syntax default: proto3 and
+// editions pack unless told otherwise, proto2 does not. Parsers are meant
+// to accept either encoding, but not all do.
+func (c *checker) packed(name string, old, new protoreflect.MessageDescriptor) {
+	for i := 0; i < old.Fields().Len(); i++ {
+		of := old.Fields().Get(i)
+		nf := new.Fields().ByNumber(of.Number())
+		if nf == nil || !of.IsList() || !nf.IsList() || of.Kind() != nf.Kind() || of.IsPacked() == nf.IsPacked() {
+			continue
+		}
+		path := name + "." + string(of.Name())
+		if nf.IsPacked() {
+			c.addFor(Forward, PackedChanged, Warning, path,
+				"field %d is now packed%s; consumers on protobuf releases before 2.3, and minimal parsers that only read the declared encoding, misread new payloads",
+				of.Number(), packingCause(of, nf))
+			continue
+		}
+		c.addFor(Backward, PackedChanged, Info, path,
+			"field %d is no longer packed%s; conforming parsers read both encodings, so old packed payloads still decode",
+			of.Number(), packingCause(of, nf))
+	}
+}
+
+// packingCause explains a packing change caused by a syntax change rather
+// than the packed option.
+func packingCause(of, nf protoreflect.FieldDescriptor) string {
+	os, ns := of.ParentFile().Syntax(), nf.ParentFile().Syntax()
+	if os == ns || explicitlyPacked(of) || explicitlyPacked(nf) {
+		return ""
+	}
+	return " by the " + ns.String() + " default, after the file moved from " + os.String()
+}
+
+// explicitlyPacked reports whether fd sets the packed option.
+func explicitlyPacked(fd protoreflect.FieldDescriptor) bool {
+	opts, _ := fd.Options().(*descriptorpb.FieldOptions)
+	return opts != nil && opts.Packed != nil
+}
@@ -0,0 +1,113 @@
+package compat
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Policy says which readers must keep working when a schema changes, in
+// the terms schema registries use. Which one fits depends on the order in
+// which a team deploys producers and consumers: upgrading consumers first
+// needs BACKWARD, upgrading producers first needs FORWARD, and either
+// order needs FULL.
+type Policy struct {
+	// Affects names the readers that must keep working; findings affecting
+	// only other readers are dropped. NONE checks nothing.
+	Affects Direction
+	// Transitive policies hold a version compatible with every earlier one,
+	// not just the one before it, for payloads that outlive a release.
+	Transitive bool
+}
+
+// Policies by the names schema registries use.
+var (
+	PolicyNone               = Policy{}
+	PolicyBackward           = Policy{Affects: Backward}
+	PolicyForward            = Policy{Affects: Forward}
+	PolicyFull               = Policy{Affects: Both}
+	PolicyBackwardTransitive = Policy{Affects: Backward, Transitive: true}
+	PolicyForwardTransitive  = Policy{Affects: Forward, Transitive: true}
+	PolicyFullTransitive     = Policy{Affects: Both, Transitive: true}
+)
+
+// PolicyNames lists the names ParsePolicy accepts.
+var PolicyNames = []string{
+	"NONE", "BACKWARD", "FORWARD", "FULL",
+	"BACKWARD_TRANSITIVE", "FORWARD_TRANSITIVE", "FULL_TRANSITIVE",
+}
+
+// ParsePolicy parses a policy name such as BACKWARD or FULL_TRANSITIVE,
+// ignoring case.
+func ParsePolicy(name string) (Policy, error) {
+	base, transitive := strings.CutSuffix(strings.ToUpper(name), "_TRANSITIVE")
+	var p Policy
+	switch base {
+	case "NONE":
+		if transitive {
+			return p, fmt.Errorf("unknown policy %q", name)
+		}
+		return PolicyNone, nil
+	case "BACKWARD":
+		p.Affects = Backward
+	case "FORWARD":
+		p.Affects = Forward
+	case "FULL":
+		p.Affects = Both
+	default:
+		return p, fmt.Errorf("unknown policy %q (want %s)", name, strings.Join(PolicyNames, ", "))
+	}
+	p.Transitive = transitive
+	return p, nil
+}
+
+func (p Policy) String() string {
+	var name string
+	switch p.Affects {
+	case 0:
+		return "NONE"
+	case Both:
+		name = "FULL"
+	default:
+		name = strings.ToUpper(p.Affects.String())
+	}
+	if p.Transitive {
+		name += "_TRANSITIVE"
+	}
+	return name
+}
+
+// Against returns the indexes of the versions, oldest first, that the
+// newest of n versions must be compatible with under p.
+func (p Policy) Against(n int) []int {
+	if p.Affects == 0 || n < 2 {
+		return nil
+	}
+	if !p.Transitive {
+		return []int{n - 2}
+	}
+	out := make([]int, n-1)
+	for i := range out {
+		out[i] = i
+	}
+	return out
+}
+
+// Apply returns the findings of r that p cares about.
+func (p Policy) Apply(r *Report) *Report {
+	return r.Affecting(p.Affects)
+}
+
+// CheckHistory checks the newest of versions, given oldest first, against
+// each earlier version p requires it to be compatible with, returning one
+// report per earlier version in the order of Against. renames, which may
+// be nil, is passed to Compare.
+func CheckHistory(versions [][]protoreflect.FileDescriptor, p Policy, renames Renames) []*Report {
+	newest := versions[len(versions)-1]
+	var out []*Report
+	for _, i := range p.Against(len(versions)) {
+		out = append(out, p.Apply(Compare(versions[i], newest, renames)))
+	}
+	return out
+}
@@ -0,0 +1,118 @@
+package compat
+
+import (
+	"slices"
+	"testing"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestParsePolicy(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		want Policy
+	}{
+		{"NONE", PolicyNone},
+		{"BACKWARD", PolicyBackward},
+		{"forward", PolicyForward},
+		{"Full", PolicyFull},
+		{"BACKWARD_TRANSITIVE", PolicyBackwardTransitive},
+		{"forward_transitive", PolicyForwardTransitive},
+		{"FULL_TRANSITIVE", PolicyFullTransitive},
+	} {
+		got, err := ParsePolicy(tt.name)
+		if err != nil || got != tt.want {
+			t.Errorf("ParsePolicy(%q) = %v, %v; want %v", tt.name, got, err, tt.want)
+		}
+	}
+	for _, name := range []string{"", "NONE_TRANSITIVE", "BACKWARDS", "TRANSITIVE"} {
+		if _, err := ParsePolicy(name); err == nil {
+			t.Errorf("ParsePolicy(%q): no error", name)
+		}
+	}
+	for _, name := range PolicyNames {
+		p, err := ParsePolicy(name)
+		if err != nil || p.String() != name {
+			t.Errorf("ParsePolicy(%q).String() = %q, %v", name, p, err)
+		}
+	}
+}
+
+func TestPolicyApply(t *testing.T) {
+	report := &Report{Findings: []Finding{
+		{Rule: RPCRemoved, Affects: Backward},
+		{Rule: RPCAdded, Affects: Forward},
+		{Rule: FieldTypeChanged, Affects: Both},
+	}}
+	for _, tt := range []struct {
+		policy Policy
+		want   []string
+	}{
+		{PolicyNone, nil},
+		{PolicyBackward, []string{RPCRemoved, FieldTypeChanged}},
+		{PolicyForward, []string{RPCAdded, FieldTypeChanged}},
+		{PolicyFull, []string{RPCRemoved, RPCAdded, FieldTypeChanged}},
+		{PolicyBackwardTransitive, []string{RPCRemoved, FieldTypeChanged}},
+		{PolicyForwardTransitive, []string{RPCAdded, FieldTypeChanged}},
+		{PolicyFullTransitive, []string{RPCRemoved, RPCAdded, FieldTypeChanged}},
+	} {
+		var got []string
+		for _, f := range tt.policy.Apply(report).Findings {
+			got = append(got, f.Rule)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%v: rules = %q, want %q", tt.policy, got, tt.want)
+		}
+	}
+}
+
+func TestPolicyAgainst(t *testing.T) {
+	for _, tt := range []struct {
+		policy Policy
+		n      int
+		want   []int
+	}{
+		{PolicyNone, 3, nil},
+		{PolicyBackward, 1, nil},
+		{PolicyBackward, 3, []int{1}},
+		{PolicyFull, 2, []int{0}},
+		{PolicyBackwardTransitive, 3, []int{0, 1}},
+		{PolicyFullTransitive, 4, []int{0, 1, 2}},
+	} {
+		if got := tt.policy.Against(tt.n); !slices.Equal(got, tt.want) {
+			t.Errorf("%v.Against(%d) = %v, want %v", tt.policy, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestCheckHistory(t *testing.T) {
+	// Item goes in version 2; version 3 changes nothing more.
+	versions := [][]protoreflect.FileDescriptor{
+		proto3(t, `message_type { name: "Order" `+orderID+` } message_type { name: "Item" }`),
+		proto3(t, `message_type { name: "Order" `+orderID+` }`),
+		proto3(t, `message_type { name: "Order" `+orderID+` }`),
+	}
+	for _, tt := range []struct {
+		policy Policy
+		want   [][]string // rules of each report
+	}{
+		{PolicyNone, nil},
+		{PolicyBackward, [][]string{nil}},
+		{PolicyBackwardTransitive, [][]string{{MessageRemoved}, nil}},
+		{PolicyForwardTransitive, [][]string{{MessageRemoved}, nil}},
+		{PolicyFullTransitive, [][]string{{MessageRemoved}, nil}},
+	} {
+		reports := CheckHistory(versions, tt.policy, nil)
+		var got [][]string
+		for _, r := range reports {
+			var rules []string
+			for _, f := range r.Findings {
+				rules = append(rules, f.Rule)
+			}
+			got = append(got, rules)
+		}
+		if !slices.EqualFunc(got, tt.want, slices.Equal) {
+			t.Errorf("%v: rules = %q, want %q", tt.policy, got, tt.want)
+		}
+	}
+}
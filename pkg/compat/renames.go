@@ -0,0 +1,104 @@
+package compat
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Renames maps the names of messages and enums renamed on purpose to their
+// names in the new version. Names are given within their package, as
+// "Order", or in full, as "acme.v1.Order"; renaming a message renames the
+// types nested in it too. Package renames need no entry, as types are
+// matched by their name within their package anyway. Renamed services
+// have their methods compared but still break gRPC clients, which call
+// them by their full name.
+type Renames map[string]string
+
+// ReadRenames reads a rename mapping file: one "OLD NEW" pair per line,
+// with blank lines and "#" comments ignored.
+func ReadRenames(path string) (Renames, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r, err := ParseRenames(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return r, nil
+}
+
+// ParseRenames parses the format of ReadRenames.
+func ParseRenames(r io.Reader) (Renames, error) {
+	out := make(Renames)
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		text, _, _ := strings.Cut(sc.Text(), "#")
+		fields := strings.Fields(text)
+		switch len(fields) {
+		case 0:
+			continue
+		case 2:
+		default:
+			return nil, fmt.Errorf("line %d: want OLD NEW", line)
+		}
+		if _, dup := out[fields[0]]; dup {
+			return nil, fmt.Errorf("line %d: %s renamed twice", line, fields[0])
+		}
+		out[fields[0]] = fields[1]
+	}
+	return out, sc.Err()
+}
+
+// target returns the name d has in the new version, as Compare names
+// types: its own unless renamed, directly or through a message it is
+// nested in.
+func (c *checker) target(d protoreflect.Descriptor) string {
+	if _, ok := d.(protoreflect.FileDescriptor); ok {
+		return ""
+	}
+	local := localName(d)
+	if len(c.renames) == 0 {
+		return c.names.name(d)
+	}
+	pkg := strings.TrimSuffix(string(d.FullName()), local)
+	for name := local; name != ""; name = parentName(name) {
+		to, ok := c.renames[name]
+		if !ok {
+			to, ok = c.renames[pkg+name]
+		}
+		if !ok {
+			continue
+		}
+		if n, ok := c.newNames[to]; ok {
+			to = n
+		}
+		return to + strings.TrimPrefix(local, name)
+	}
+	return c.names.name(d)
+}
+
+// renamedItself reports whether d is renamed other than by the rename of
+// a message it is nested in.
+func (c *checker) renamedItself(d protoreflect.Descriptor) bool {
+	implied := c.names.name(d)
+	if parent := c.target(d.Parent()); parent != "" {
+		implied = parent + "." + string(d.Name())
+	}
+	return c.target(d) != implied
+}
+
+// parentName strips the last element of a dotted name.
+func parentName(name string) string {
+	i := strings.LastIndexByte(name, '.')
+	if i < 0 {
+		return ""
+	}
+	return name[:i]
+}
@@ -0,0 +1,35 @@
+package compat
+
+import "google.golang.org/protobuf/reflect/protoreflect"
+
+// required checks proto2 required fields. A reader rejects any payload
+// lacking a field it declares required, so the set of required fields can
+// neither grow nor shrink without breaking one side.
+func (c *checker) required(name string, old, new protoreflect.MessageDescriptor) {
+	for i := 0; i < old.Fields().Len(); i++ {
+		of := old.Fields().Get(i)
+		path := name + "." + string(of.Name())
+		nf := new.Fields().ByNumber(of.Number())
+		or := of.Cardinality() == protoreflect.Required
+		switch {
+		case nf == nil:
+			if or {
+				c.addFor(Forward, RequiredFieldRemoved, Breaking, path,
+					"required field %d removed; old readers reject every payload of the new version", of.Number())
+			}
+		case or && nf.Cardinality() != protoreflect.Required:
+			c.addFor(Forward, RequiredChanged, Breaking, path,
+				"field %d is no longer required; old readers reject new payloads that omit it", of.Number())
+		case !or && nf.Cardinality() == protoreflect.Required:
+			c.addFor(Backward, RequiredChanged, Breaking, path,
+				"field %d became required; new readers reject old payloads that omit it", of.Number())
+		}
+	}
+	for i := 0; i < new.Fields().Len(); i++ {
+		nf := new.Fields().Get(i)
+		if nf.Cardinality() == protoreflect.Required && old.Fields().ByNumber(nf.Number()) == nil {
+			c.addFor(Backward, RequiredFieldAdded, Breaking, name+"."+string(nf.Name()),
+				"required field %d added; new readers reject every payload of the old version", nf.Number())
+		}
+	}
+}
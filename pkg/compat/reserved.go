@@ -0,0 +1,91 @@
+package compat
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// reserved checks that fields removed from a message are reserved in the
+// new version, and that reservations are kept and respected.
+func (c *checker) reserved(name string, old, new protoreflect.MessageDescriptor) {
+	for i := 0; i < old.Fields().Len(); i++ {
+		of := old.Fields().Get(i)
+		if new.Fields().ByNumber(of.Number()) != nil || new.Fields().ByName(of.Name()) != nil {
+			continue
+		}
+		numberOK := new.ReservedRanges().Has(of.Number())
+		nameOK := new.ReservedNames().Has(of.Name())
+		switch {
+		case !numberOK && !nameOK:
+			c.add(FieldNotReserved, Warning, name+"."+string(of.Name()),
+				"removed field %d is not reserved; a later field reusing the number misreads old payloads", of.Number())
+		case !numberOK:
+			c.add(FieldNotReserved, Warning, name+"."+string(of.Name()),
+				"removed field %d reserves its name but not its number; a later field reusing the number misreads old payloads", of.Number())
+		case !nameOK:
+			c.add(FieldNotReserved, Info, name+"."+string(of.Name()),
+				"removed field %d reserves its number but not its name; a later field reusing the name misreads old JSON payloads", of.Number())
+		}
+	}
+
+	for i := 0; i < new.Fields().Len(); i++ {
+		nf := new.Fields().Get(i)
+		path := name + "." + string(nf.Name())
+		if old.ReservedRanges().Has(nf.Number()) {
+			c.add(ReservedReused, Breaking, path,
+				"field %d uses a number the old version reserves; payloads still carrying the retired field are misread as %s",
+				nf.Number(), nf.Name())
+		}
+		if old.ReservedNames().Has(nf.Name()) {
+			c.add(ReservedReused, Warning, path,
+				"field %d uses a name the old version reserves; JSON payloads still carrying the retired field are misread as %s",
+				nf.Number(), nf.Name())
+		}
+	}
+
+	ranges := old.ReservedRanges()
+	for i := 0; i < ranges.Len(); i++ {
+		r := ranges.Get(i)
+		if !rangeReserved(new.ReservedRanges(), r) {
+			c.add(ReservedRemoved, Warning, name, "reserved numbers %s are no longer all reserved", formatRange(r))
+		}
+	}
+	names := old.ReservedNames()
+	for i := 0; i < names.Len(); i++ {
+		if n := names.Get(i); !new.ReservedNames().Has(n) && new.Fields().ByName(n) == nil {
+			c.add(ReservedRemoved, Warning, name, "reserved name %q is no longer reserved", n)
+		}
+	}
+}
+
+// rangeReserved reports whether ranges reserve every number in r, which
+// need not be covered by a single range.
+func rangeReserved(ranges protoreflect.FieldRanges, r [2]protoreflect.FieldNumber) bool {
+	for n := r[0]; n < r[1]; {
+		covered := false
+		for i := 0; i < ranges.Len(); i++ {
+			if g := ranges.Get(i); g[0] <= n && n < g[1] {
+				n, covered = g[1], true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+	return true
+}
+
+// formatRange renders a half-open field number range as declared in a
+// .proto file.
+func formatRange(r [2]protoreflect.FieldNumber) string {
+	switch {
+	case r[1]-r[0] == 1:
+		return fmt.Sprint(r[0])
+	case r[1] > protowire.MaxValidNumber:
+		return fmt.Sprintf("%d to max", r[0])
+	}
+	return fmt.Sprintf("%d to %d", r[0], r[1]-1)
+}
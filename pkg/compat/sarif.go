@@ -0,0 +1,107 @@
+package compat
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// SARIF 2.1.0 log, reduced to what code review and security tools read.
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name    string      `json:"name"`
+	Version string      `json:"version,omitempty"`
+	Rules   []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation *sarifPhysical `json:"physicalLocation,omitempty"`
+	LogicalLocations []sarifLogical `json:"logicalLocations"`
+}
+
+type sarifPhysical struct {
+	ArtifactLocation sarifArtifact `json:"artifactLocation"`
+	Region           sarifRegion   `json:"region"`
+}
+
+type sarifArtifact struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+type sarifLogical struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+}
+
+// sarifLevels maps severities to SARIF result levels.
+var sarifLevels = map[Severity]string{
+	Info:     "note",
+	Warning:  "warning",
+	Breaking: "error",
+}
+
+// WriteSARIF writes r as a SARIF 2.1.0 log produced by the named tool.
+// Findings with a source position point at the .proto file; all of them
+// carry the element path as a logical location.
+func (r *Report) WriteSARIF(w io.Writer, tool, version string) error {
+	run := sarifRun{
+		Tool:    sarifTool{Driver: sarifDriver{Name: tool, Version: version}},
+		Results: []sarifResult{},
+	}
+	for _, id := range Rules {
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: id})
+	}
+	for _, f := range r.Findings {
+		loc := sarifLocation{LogicalLocations: []sarifLogical{{FullyQualifiedName: f.Path}}}
+		if f.File != "" {
+			loc.PhysicalLocation = &sarifPhysical{
+				ArtifactLocation: sarifArtifact{URI: f.File},
+				Region:           sarifRegion{StartLine: f.Line},
+			}
+		}
+		run.Results = append(run.Results, sarifResult{
+			RuleID:    f.Rule,
+			Level:     sarifLevels[f.Severity],
+			Message:   sarifMessage{Text: f.Message},
+			Locations: []sarifLocation{loc},
+		})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs:    []sarifRun{run},
+	})
+}
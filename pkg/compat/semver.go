@@ -0,0 +1,105 @@
+package compat
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Bump is the part of a semantic version a schema release must increment.
+type Bump int
+
+const (
+	// Patch releases change nothing the checker sees, such as comments.
+	Patch Bump = iota
+	// Minor releases make compatible changes, such as added fields.
+	Minor
+	// Major releases break readers.
+	Major
+)
+
+func (b Bump) String() string {
+	switch b {
+	case Patch:
+		return "patch"
+	case Minor:
+		return "minor"
+	}
+	return "major"
+}
+
+// Bump returns the version bump the findings of r call for: major for
+// breaking changes, minor for any other change, patch if there are none.
+func (r *Report) Bump() Bump {
+	switch {
+	case r.AtLeast(Breaking) > 0:
+		return Major
+	case len(r.Findings) > 0:
+		return Minor
+	}
+	return Patch
+}
+
+// Version is a semantic version, without its pre-release and build parts.
+type Version [3]int
+
+// ParseVersion parses a semantic version such as "1.4.2" or "v2.0.0-rc.1".
+func ParseVersion(s string) (Version, error) {
+	var v Version
+	core, _, _ := strings.Cut(strings.TrimPrefix(s, "v"), "+")
+	core, _, _ = strings.Cut(core, "-")
+	parts := strings.Split(core, ".")
+	if len(parts) != 3 {
+		return v, fmt.Errorf("bad version %q: want MAJOR.MINOR.PATCH", s)
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("bad version %q: want MAJOR.MINOR.PATCH", s)
+		}
+		v[i] = n
+	}
+	return v, nil
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v[0], v[1], v[2])
+}
+
+// Next returns the lowest version after v that makes bump b. Below 1.0.0,
+// where anything may change, breaking changes bump the minor version.
+func (v Version) Next(b Bump) Version {
+	if b == Major && v[0] == 0 {
+		b = Minor
+	}
+	switch b {
+	case Major:
+		return Version{v[0] + 1, 0, 0}
+	case Minor:
+		return Version{v[0], v[1] + 1, 0}
+	}
+	return Version{v[0], v[1], v[2] + 1}
+}
+
+// Verify checks that releasing next after current bumps the version
+// enough for changes that call for b, naming the lowest version that
+// would if not.
+func (b Bump) Verify(current, next Version) error {
+	if !current.Less(next) {
+		return fmt.Errorf("version %s does not follow %s", next, current)
+	}
+	if want := current.Next(b); next.Less(want) {
+		return fmt.Errorf("version %s is too small a bump from %s for %s changes; release %s or later", next, current, b, want)
+	}
+	return nil
+}
+
+// Less reports whether v precedes w.
+func (v Version) Less(w Version) bool {
+	for i := range v {
+		if v[i] != w[i] {
+			return v[i] < w[i]
+		}
+	}
+	return false
+}
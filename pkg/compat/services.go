@@ -0,0 +1,88 @@
+package compat
+
+import (
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// services indexes the services of files by the names name gives them.
+func services(files []protoreflect.FileDescriptor, name func(protoreflect.Descriptor) string) map[string]protoreflect.ServiceDescriptor {
+	out := make(map[string]protoreflect.ServiceDescriptor)
+	for _, fd := range files {
+		for i := 0; i < fd.Services().Len(); i++ {
+			out[name(fd.Services().Get(i))] = fd.Services().Get(i)
+		}
+	}
+	return out
+}
+
+// service compares two versions of a service. gRPC routes calls by the
+// full name of the service and the name of the method, so unlike messages
+// a service cannot change package or name without breaking its clients,
+// and its methods are matched by name.
+func (c *checker) service(name string, old, new protoreflect.ServiceDescriptor) {
+	switch {
+	case old.FullName() == new.FullName():
+	case old.Name() == new.Name():
+		c.add(ServiceRenamed, Breaking, name,
+			"moved from package %s to %s; gRPC calls name the service in full, so clients of one version reach servers of the other only if they serve both",
+			old.ParentFile().Package(), new.ParentFile().Package())
+	default:
+		c.add(ServiceRenamed, Breaking, name,
+			"renamed to %s; gRPC calls name the service in full, so clients of one version reach servers of the other only if they serve both",
+			new.FullName())
+	}
+	for i := 0; i < old.Methods().Len(); i++ {
+		om := old.Methods().Get(i)
+		path := name + "." + string(om.Name())
+		nm := new.Methods().ByName(om.Name())
+		if nm == nil {
+			c.addFor(Backward, RPCRemoved, Breaking, path,
+				"RPC removed; new servers answer calls from old clients with UNIMPLEMENTED")
+			continue
+		}
+		if from, to := methodType(om.Input(), om, c.target), methodType(nm.Input(), nm, c.names.name); from != to {
+			c.add(RPCTypeChanged, Breaking, path,
+				"request type changed from %s to %s; servers read requests of the other version as their own type, which only works if the fields match",
+				from, to)
+		}
+		if from, to := methodType(om.Output(), om, c.target), methodType(nm.Output(), nm, c.names.name); from != to {
+			c.add(RPCTypeChanged, Breaking, path,
+				"response type changed from %s to %s; clients read responses of the other version as their own type, which only works if the fields match",
+				from, to)
+		}
+		if om.IsStreamingClient() != nm.IsStreamingClient() || om.IsStreamingServer() != nm.IsStreamingServer() {
+			c.add(RPCStreamingChanged, Breaking, path,
+				"changed from %s to %s; clients and servers of the two versions disagree on how many messages each side sends",
+				streamingMode(om), streamingMode(nm))
+		}
+	}
+	for i := 0; i < new.Methods().Len(); i++ {
+		nm := new.Methods().Get(i)
+		if old.Methods().ByName(nm.Name()) == nil {
+			c.addFor(Forward, RPCAdded, Info, name+"."+string(nm.Name()),
+				"RPC added; old servers answer calls to it with UNIMPLEMENTED")
+		}
+	}
+}
+
+// methodType names the request or response type md of method m as
+// typeNameAs names message types.
+func methodType(md protoreflect.MessageDescriptor, m protoreflect.MethodDescriptor, name func(protoreflect.Descriptor) string) string {
+	if md.ParentFile().Package() == m.ParentFile().Package() {
+		return name(md)
+	}
+	return string(md.FullName())
+}
+
+// streamingMode describes the kind of RPC m is.
+func streamingMode(m protoreflect.MethodDescriptor) string {
+	switch {
+	case m.IsStreamingClient() && m.IsStreamingServer():
+		return "bidirectional streaming"
+	case m.IsStreamingClient():
+		return "client streaming"
+	case m.IsStreamingServer():
+		return "server streaming"
+	}
+	return "unary"
+}
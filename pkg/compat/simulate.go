@@ -0,0 +1,286 @@
+package compat
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Outcome says what became of a field when a payload crossed versions.
+type Outcome string
+
+const (
+	// Survived fields read back as written.
+	Survived Outcome = "survived"
+	// Changed fields were read, but as a different value.
+	Changed Outcome = "changed"
+	// Dropped fields are not declared by the reader, which keeps them only
+	// as unknown fields, if at all.
+	Dropped Outcome = "dropped"
+	// Defaulted fields are declared by the reader but were not written, so
+	// the reader sees its default.
+	Defaulted Outcome = "defaulted"
+)
+
+// FieldResult is what became of one field.
+type FieldResult struct {
+	// Path names the field by its names in the writer's schema, or in the
+	// reader's for fields the writer does not declare, e.g. "started_at.seconds".
+	Path    string  `json:"path"`
+	Number  string  `json:"number"` // as "3.1" for nested fields
+	Outcome Outcome `json:"outcome"`
+	Written string  `json:"written,omitempty"`
+	Read    string  `json:"read,omitempty"`
+}
+
+// Simulation is the outcome of a payload written with one version of a
+// message and read with another.
+type Simulation struct {
+	Writer  string        `json:"writer"`
+	Reader  string        `json:"reader"`
+	Bytes   int           `json:"bytes"`
+	Fields  []FieldResult `json:"fields"`
+	Unknown int           `json:"unknown_bytes"` // retained by the reader
+}
+
+// Count returns the number of fields with outcome o.
+func (s *Simulation) Count(o Outcome) int {
+	n := 0
+	for _, f := range s.Fields {
+		if f.Outcome == o {
+			n++
+		}
+	}
+	return n
+}
+
+// RandomMessage returns a message of type md with random fields set, as
+// FuzzRoundTrip generates them.
+func RandomMessage(md protoreflect.MessageDescriptor, seed uint64) proto.Message {
+	return randomMessage(rand.New(rand.NewPCG(seed, seed)), md, 0)
+}
+
+// Simulate marshals msg, unmarshals the payload as reader and reports what
+// became of each field written, and which fields the reader defaulted
+// because the writer does not declare them.
+func Simulate(msg proto.Message, reader protoreflect.MessageDescriptor) (*Simulation, error) {
+	payload, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	return simulate(msg, dynamicpb.NewMessage(reader), payload, proto.Unmarshal)
+}
+
+// SimulateJSON is Simulate through protojson, whose readers reject unknown
+// keys and enum names unless discardUnknown is set.
+func SimulateJSON(msg proto.Message, reader protoreflect.MessageDescriptor, discardUnknown bool) (*Simulation, error) {
+	payload, err := protojson.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	return simulate(msg, dynamicpb.NewMessage(reader), payload, protojson.UnmarshalOptions{DiscardUnknown: discardUnknown}.Unmarshal)
+}
+
+// Exchange is a message passed from a producer to a consumer built from
+// another version of its schema, in the binary format and as JSON.
+type Exchange struct {
+	Binary []byte
+	JSON   []byte
+	// FromBinary and FromJSON are the message as the consumer read it,
+	// of the consumer's type.
+	FromBinary proto.Message
+	FromJSON   proto.Message
+	// BinaryFields and JSONFields say what became of each field; Diverge
+	// compares them.
+	BinaryFields *Simulation
+	JSONFields   *Simulation
+}
+
+// SimulateExchange marshals producer to the binary format and to JSON,
+// and has consumer read both, discarding JSON keys it does not know as
+// the binary format discards unknown fields. With the type of a generated
+// message as consumer, FromBinary and FromJSON are of that Go type, for
+// tests to check their fields directly.
+func SimulateExchange(producer proto.Message, consumer protoreflect.MessageType) (*Exchange, error) {
+	binary, err := proto.Marshal(producer)
+	if err != nil {
+		return nil, err
+	}
+	json, err := protojson.Marshal(producer)
+	if err != nil {
+		return nil, err
+	}
+	e := &Exchange{Binary: binary, JSON: json}
+	e.FromBinary = consumer.New().Interface()
+	if e.BinaryFields, err = simulate(producer, e.FromBinary, binary, proto.Unmarshal); err != nil {
+		return nil, err
+	}
+	e.FromJSON = consumer.New().Interface()
+	if e.JSONFields, err = simulate(producer, e.FromJSON, json, protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// simulate unmarshals payload, written from msg, into got and compares
+// the two.
+func simulate(msg, got proto.Message, payload []byte, unmarshal func([]byte, proto.Message) error) (*Simulation, error) {
+	reader := got.ProtoReflect().Descriptor()
+	if err := unmarshal(payload, got); err != nil {
+		return nil, fmt.Errorf("%s readers reject the payload: %w", reader.FullName(), err)
+	}
+	s := &Simulation{
+		Writer: string(msg.ProtoReflect().Descriptor().FullName()),
+		Reader: string(reader.FullName()),
+		Bytes:  len(payload),
+	}
+	s.compare(msg.ProtoReflect(), got.ProtoReflect(), "", "")
+	return s, nil
+}
+
+// compare records the outcome of each field of written and read, the same
+// message under two versions, recursing into messages both sides read as
+// messages.
+func (s *Simulation) compare(written, read protoreflect.Message, path, number string) {
+	s.Unknown += len(read.GetUnknown())
+	wmd, rmd := written.Descriptor(), read.Descriptor()
+	for i := 0; i < wmd.Fields().Len(); i++ {
+		wf := wmd.Fields().Get(i)
+		rf := rmd.Fields().ByNumber(wf.Number())
+		r := FieldResult{
+			Path:   path + string(wf.Name()),
+			Number: number + fmt.Sprint(wf.Number()),
+		}
+		switch {
+		case !written.Has(wf):
+			continue
+		case rf == nil:
+			r.Outcome, r.Written = Dropped, valueString(wf, written.Get(wf))
+		case isMessage(wf) && isMessage(rf) && read.Has(rf):
+			s.compare(written.Get(wf).Message(), read.Get(rf).Message(), r.Path+".", r.Number+".")
+			continue
+		default:
+			r.Written = valueString(wf, written.Get(wf))
+			r.Read = "(unset)"
+			if read.Has(rf) {
+				r.Read = valueString(rf, read.Get(rf))
+			}
+			r.Outcome = Survived
+			if r.Written != r.Read {
+				r.Outcome = Changed
+			}
+		}
+		s.Fields = append(s.Fields, r)
+	}
+	for i := 0; i < rmd.Fields().Len(); i++ {
+		rf := rmd.Fields().Get(i)
+		if wmd.Fields().ByNumber(rf.Number()) != nil {
+			continue
+		}
+		r := FieldResult{
+			Path:    path + string(rf.Name()),
+			Number:  number + fmt.Sprint(rf.Number()),
+			Outcome: Defaulted,
+			Read:    defaultString(rf),
+		}
+		switch {
+		case read.Has(rf):
+			// Only protojson fills in a field the writer does not declare,
+			// from a key the writer uses for another field.
+			r.Outcome, r.Written, r.Read = Changed, "(another field)", valueString(rf, read.Get(rf))
+		case isMessage(rf) || rf.IsList() || rf.IsMap():
+			r.Read = "(empty)"
+		}
+		s.Fields = append(s.Fields, r)
+	}
+}
+
+// isMessage reports whether fd holds a single message.
+func isMessage(fd protoreflect.FieldDescriptor) bool {
+	return fd.Message() != nil && !fd.IsList() && !fd.IsMap()
+}
+
+// valueString renders a field value by field number, as Render does, so
+// that values read under another version compare equal if they are.
+func valueString(fd protoreflect.FieldDescriptor, v protoreflect.Value) string {
+	var lines []string
+	switch {
+	case fd.IsList():
+		for i := 0; i < v.List().Len(); i++ {
+			renderValue(fd, v.List().Get(i), fmt.Sprintf("[%d]", i), &lines)
+		}
+	case fd.IsMap():
+		var keys []protoreflect.MapKey
+		v.Map().Range(func(k protoreflect.MapKey, _ protoreflect.Value) bool {
+			keys = append(keys, k)
+			return true
+		})
+		sortKeys(keys)
+		for _, k := range keys {
+			renderValue(fd.MapValue(), v.Map().Get(k), "["+keyString(k)+"]", &lines)
+		}
+	default:
+		renderValue(fd, v, "", &lines)
+		if len(lines) == 1 && strings.HasPrefix(lines[0], ": ") {
+			return lines[0][2:]
+		}
+	}
+	return "{" + strings.Join(lines, ", ") + "}"
+}
+
+// Divergence is a field that fares differently through protojson than
+// through the binary format.
+type Divergence struct {
+	Path   string `json:"path"`
+	Number string `json:"number"`
+	Binary string `json:"binary"` // the outcome, and the value read if any
+	JSON   string `json:"json"`
+}
+
+// Diverge compares simulations of the same message through the binary
+// format and through protojson.
+func Diverge(binary, json *Simulation) []Divergence {
+	outcomes := func(s *Simulation) map[string]FieldResult {
+		m := make(map[string]FieldResult)
+		for _, f := range s.Fields {
+			m[f.Number] = f
+		}
+		return m
+	}
+	bin, js := outcomes(binary), outcomes(json)
+	var out []Divergence
+	add := func(f FieldResult) {
+		b, j := bin[f.Number], js[f.Number]
+		if b.Outcome == j.Outcome && b.Read == j.Read {
+			return
+		}
+		out = append(out, Divergence{Path: f.Path, Number: f.Number, Binary: describe(b), JSON: describe(j)})
+	}
+	for _, f := range binary.Fields {
+		add(f)
+	}
+	for _, f := range json.Fields {
+		if _, ok := bin[f.Number]; !ok {
+			add(f)
+		}
+	}
+	return out
+}
+
+// describe renders an outcome for a Divergence.
+func describe(f FieldResult) string {
+	switch {
+	case f.Outcome == "":
+		return "not seen"
+	case f.Outcome == Dropped:
+		return "dropped"
+	case f.Read == "":
+		return string(f.Outcome)
+	}
+	return fmt.Sprintf("%s as %s", f.Outcome, f.Read)
+}
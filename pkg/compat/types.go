@@ -0,0 +1,182 @@
+package compat
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// varintRange describes the values a varint-encoded kind can hold.
+type varintRange struct {
+	signed bool
+	bits   int
+}
+
+// varintKinds are the kinds sharing the plain varint encoding. Negative
+// int32 and enum values are sign-extended to 64 bits on the wire, so they
+// read back intact as int64.
+var varintKinds = map[protoreflect.Kind]varintRange{
+	protoreflect.BoolKind:   {false, 1},
+	protoreflect.Int32Kind:  {true, 32},
+	protoreflect.EnumKind:   {true, 32},
+	protoreflect.Uint32Kind: {false, 32},
+	protoreflect.Int64Kind:  {true, 64},
+	protoreflect.Uint64Kind: {false, 64},
+}
+
+// Severity words used in type change explanations.
+var classNames = [...]string{Info: "safe", Warning: "lossy", Breaking: "breaking"}
+
+// typeChange classifies a change of a field's type from of to nf by the
+// worse of the two reading directions, which it returns along with the
+// directions affected.
+func typeChange(of, nf protoreflect.FieldDescriptor) (Severity, Direction, string) {
+	back, bwhy := readAs(of, nf)
+	fwd, fwhy := readAs(nf, of)
+	sev, d, why := back, Backward, bwhy
+	switch {
+	case fwd > back:
+		sev, d, why = fwd, Forward, fwhy
+	case fwd == back:
+		d = Both
+	}
+	msg := fmt.Sprintf("type of field %d changed from %s to %s: %s", of.Number(), typeName(of), typeName(nf), classNames[sev])
+	if why != "" {
+		msg += ", " + why
+	}
+	return sev, d, msg
+}
+
+// readAs classifies what a reader declaring field r makes of values
+// written as field w, explaining any loss.
+func readAs(w, r protoreflect.FieldDescriptor) (Severity, string) {
+	if w.IsMap() && r.IsMap() {
+		ks, kwhy := readAs(w.MapKey(), r.MapKey())
+		vs, vwhy := readAs(w.MapValue(), r.MapValue())
+		if vs > ks {
+			return vs, vwhy
+		}
+		return ks, kwhy
+	}
+	if w.IsMap() != r.IsMap() {
+		return Breaking, "map and non-map fields hold different entries"
+	}
+	wt, rt := typeName(w), typeName(r)
+	if wt == rt {
+		return Info, ""
+	}
+	wk, rk := w.Kind(), r.Kind()
+
+	if wv, ok := varintKinds[wk]; ok {
+		rv, ok := varintKinds[rk]
+		switch {
+		case !ok:
+			return Breaking, fmt.Sprintf("%s readers misread %s values", rt, wt) + wireHint(wk, rk)
+		case wk == protoreflect.EnumKind && rk == protoreflect.EnumKind:
+			return Warning, fmt.Sprintf("%s numbers are read as %s values of the same number", wt, rt)
+		case rv.bits > wv.bits && (rv.signed || !wv.signed), rv == wv:
+			return Info, ""
+		case rk == protoreflect.BoolKind:
+			return Warning, fmt.Sprintf("bool readers turn every non-zero %s into true", wt)
+		case wv.signed && !rv.signed:
+			return Warning, fmt.Sprintf("%s readers see negative %s values as large positive numbers", rt, wt)
+		case rv.bits < wv.bits:
+			return Warning, fmt.Sprintf("%s readers truncate %s values to %d bits", rt, wt, rv.bits)
+		default:
+			return Warning, fmt.Sprintf("%s readers see %s values above %d bits as negative", rt, wt, rv.bits-1)
+		}
+	}
+
+	switch {
+	case pair(wk, rk, protoreflect.Sint32Kind, protoreflect.Sint64Kind):
+		if wk == protoreflect.Sint32Kind {
+			return Info, ""
+		}
+		return Warning, "sint32 readers truncate sint64 values to 32 bits"
+	case pair(wk, rk, protoreflect.Fixed32Kind, protoreflect.Sfixed32Kind),
+		pair(wk, rk, protoreflect.Fixed64Kind, protoreflect.Sfixed64Kind):
+		return Warning, fmt.Sprintf("%s readers reinterpret the sign of %s values", rt, wt)
+	case wk == protoreflect.StringKind && rk == protoreflect.BytesKind:
+		return Info, ""
+	case wk == protoreflect.BytesKind && rk == protoreflect.StringKind:
+		return Warning, "proto3 string readers reject the whole message if the bytes are not valid UTF-8"
+	case wk == protoreflect.MessageKind && rk == protoreflect.BytesKind:
+		return Info, ""
+	case wk == protoreflect.BytesKind && rk == protoreflect.MessageKind:
+		return Warning, fmt.Sprintf("%s readers fail on bytes that are not an encoded %s", rt, rt)
+	case wk == protoreflect.MessageKind && rk == protoreflect.MessageKind:
+		return Breaking, "different message types are only compatible if their fields are"
+	}
+	return Breaking, fmt.Sprintf("%s readers misread %s values", rt, wt) + wireHint(wk, rk)
+}
+
+// wireHint explains why two kinds do not mix when their encodings differ.
+func wireHint(a, b protoreflect.Kind) string {
+	wa, wb := wireType(a), wireType(b)
+	switch {
+	case wa != wb:
+		return fmt.Sprintf(" (%s vs %s encoding; the value is kept only as an unknown field)", wa, wb)
+	case strings.HasPrefix(a.String(), "sint") || strings.HasPrefix(b.String(), "sint"):
+		return " (zigzag vs plain varint encoding)"
+	}
+	return ""
+}
+
+func wireType(k protoreflect.Kind) string {
+	switch k {
+	case protoreflect.Sint32Kind, protoreflect.Sint64Kind:
+		return "varint"
+	case protoreflect.Fixed32Kind, protoreflect.Sfixed32Kind, protoreflect.FloatKind:
+		return "i32"
+	case protoreflect.Fixed64Kind, protoreflect.Sfixed64Kind, protoreflect.DoubleKind:
+		return "i64"
+	case protoreflect.StringKind, protoreflect.BytesKind, protoreflect.MessageKind:
+		return "len"
+	case protoreflect.GroupKind:
+		return "group"
+	}
+	if _, ok := varintKinds[k]; ok {
+		return "varint"
+	}
+	return k.String()
+}
+
+// pair reports whether {a, b} is {x, y} in either order.
+func pair[T comparable](a, b, x, y T) bool {
+	return a == x && b == y || a == y && b == x
+}
+
+// intEnum reports a field changing between an integer and an enum, which
+// share the varint encoding but not their meaning: an integer field holds
+// any number, an enum field only those its enum declares, and readers
+// handle the others as unknown values. It reports whether it applied.
+func (c *checker) intEnum(path string, of, nf protoreflect.FieldDescriptor) bool {
+	ko, kn := of.Kind(), nf.Kind()
+	_, oint := varintKinds[ko]
+	_, nint := varintKinds[kn]
+	switch {
+	case ko == kn || ko == protoreflect.BoolKind || kn == protoreflect.BoolKind || !oint || !nint:
+		return false
+	case kn == protoreflect.EnumKind:
+		c.addFor(Backward, IntEnumChanged, Warning, path,
+			"field %d changed from %s to enum %s; old payloads may hold numbers %s does not declare, and %s",
+			of.Number(), ko, typeName(nf), nf.Enum().Name(), unknownEnum(nf.Enum(), "new readers"))
+	case ko == protoreflect.EnumKind:
+		c.addFor(Forward, IntEnumChanged, Warning, path,
+			"field %d changed from enum %s to %s; new producers may write numbers %s does not declare, and %s",
+			of.Number(), typeName(of), kn, of.Enum().Name(), unknownEnum(of.Enum(), "old readers"))
+	default:
+		return false
+	}
+	return true
+}
+
+// unknownEnum explains what readers of ed make of numbers it does not
+// declare.
+func unknownEnum(ed protoreflect.EnumDescriptor, readers string) string {
+	if ed.IsClosed() {
+		return readers + " keep them only as unknown fields, seeing the default instead"
+	}
+	return readers + " get them as unnamed values that code switching over the enum must handle"
+}
@@ -0,0 +1,172 @@
+// Package decode decodes protobuf payloads, given in the binary format or
+// as hex or base64 text, with a message type or without one, for Go
+// programs to embed what protocompat decode does. Every failure, from
+// malformed text to payloads the type cannot read, is returned as an
+// error.
+package decode
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/example/protobuf-compat/internal/payload"
+	"github.com/example/protobuf-compat/internal/schema"
+	"github.com/example/protobuf-compat/internal/wire"
+	"github.com/example/protobuf-compat/pkg/wireanalyze"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// Encoding is how a payload is given.
+type Encoding string
+
+const (
+	// Binary payloads are in the wire format as is.
+	Binary Encoding = ""
+	Hex    Encoding = "hex"
+	Base64 Encoding = "base64"
+	// Base64URL is base64 with the URL-safe alphabet.
+	Base64URL Encoding = "base64url"
+	// Auto reads text made only of hex digits as hex, text containing '-'
+	// or '_' as base64url and other text as base64.
+	Auto Encoding = "auto"
+)
+
+// ErrUnknownFields reports, with Options.Strict, a payload holding fields
+// its type does not declare.
+var ErrUnknownFields = errors.New("unknown fields")
+
+// Options configures Decode. The zero value decodes binary payloads
+// without a type.
+type Options struct {
+	Encoding Encoding
+	// Type is the message to decode payloads as. Without one, only their
+	// wire-format structure is reported.
+	Type protoreflect.MessageType
+	// Strict fails on payloads with fields Type does not declare, with an
+	// error wrapping ErrUnknownFields that lists their numbers.
+	Strict bool
+	// MaxDepth bounds the nesting of messages and groups; if <= 0, the
+	// defaults of wireanalyze and of proto.Unmarshal apply.
+	MaxDepth int
+	// Fields has the wire-format structure of payloads reported with a
+	// Type too; without one it always is.
+	Fields bool
+	// JSON has the message rendered as protojson.
+	JSON bool
+	// Resolver finds the types of google.protobuf.Any values and of
+	// extensions for the JSON of the message, protoregistry.GlobalTypes if
+	// nil.
+	Resolver interface {
+		protoregistry.ExtensionTypeResolver
+		protoregistry.MessageTypeResolver
+	}
+}
+
+// Result is a decoded payload.
+type Result struct {
+	// Payload is the payload in the binary format.
+	Payload []byte
+	// Fields is its wire-format structure, nil with a type unless
+	// Options.Fields is set.
+	Fields []wireanalyze.Field
+	// Message is the payload decoded as Options.Type, nil without a type.
+	Message proto.Message
+	// JSON is the protojson rendering of Message with Options.JSON, unless
+	// rendering failed, as it does on google.protobuf.Any values of types
+	// Options.Resolver does not know. JSONErr then says why; a message
+	// that only fails to render does not fail Decode.
+	JSON    []byte
+	JSONErr error
+	// Unknown lists the fields of Message, and of the messages in it,
+	// that their types do not declare.
+	Unknown []UnknownFields
+}
+
+// UnknownFields are the numbers of the fields a message holds that its
+// type does not declare.
+type UnknownFields struct {
+	// Path names the message by field names from the top, "" for the top
+	// level.
+	Path    string
+	Numbers []protowire.Number
+}
+
+// Decode decodes data as opts says. On failure it returns what could be
+// decoded together with the error: the payload and its fields parsed
+// before a malformed one, or with Strict, the message holding unknown
+// fields. Payloads malformed in the wire format fail with a
+// *wireanalyze.ParseError locating the problem.
+func Decode(data []byte, opts Options) (*Result, error) {
+	r := &Result{Payload: data}
+	if opts.Encoding != Binary {
+		var err error
+		if r.Payload, err = payload.Decode(string(data), payload.Encoding(opts.Encoding)); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Type == nil || opts.Fields {
+		fields, err := wireanalyze.Options{MaxDepth: opts.MaxDepth}.Fields(r.Payload)
+		r.Fields = fields
+		if opts.Type == nil {
+			return r, err
+		}
+	}
+
+	msg := opts.Type.New().Interface()
+	unmarshal := proto.UnmarshalOptions{RecursionLimit: max(opts.MaxDepth, 0)}
+	if err := unmarshal.Unmarshal(r.Payload, msg); err != nil {
+		// proto does not say where; the wire format parse does, if it is
+		// what failed.
+		var pe *wireanalyze.ParseError
+		if _, parseErr := (wire.ParseOptions{MaxDepth: opts.MaxDepth}).Parse(r.Payload); errors.As(parseErr, &pe) {
+			err = pe
+		}
+		return r, fmt.Errorf("decode as %s: %w", opts.Type.Descriptor().FullName(), err)
+	}
+	r.Message = msg
+	if opts.JSON {
+		r.JSON, r.JSONErr = protojson.MarshalOptions{Resolver: opts.Resolver}.Marshal(msg)
+	}
+	r.Unknown = unknownFields(msg.ProtoReflect())
+	if opts.Strict && len(r.Unknown) > 0 {
+		return r, unknownFieldsError(opts.Type.Descriptor().FullName(), r.Unknown)
+	}
+	return r, nil
+}
+
+func unknownFields(m protoreflect.Message) []UnknownFields {
+	var out []UnknownFields
+	for _, b := range schema.Unknown(m) {
+		u := UnknownFields{Path: b.Path}
+		fields, _ := wire.Parse(b.Data)
+		for _, f := range fields {
+			if !slices.Contains(u.Numbers, f.Number) {
+				u.Numbers = append(u.Numbers, f.Number)
+			}
+		}
+		out = append(out, u)
+	}
+	return out
+}
+
+// unknownFieldsError lists unknown, of a message of type name, as in
+// "unknown fields in example.v1.Execution: 9, 10 (top level); 15 (started_at)".
+func unknownFieldsError(name protoreflect.FullName, unknown []UnknownFields) error {
+	var where []string
+	for _, u := range unknown {
+		numbers := make([]string, len(u.Numbers))
+		for i, n := range u.Numbers {
+			numbers[i] = strconv.Itoa(int(n))
+		}
+		where = append(where, fmt.Sprintf("%s (%s)", strings.Join(numbers, ", "), cmp.Or(u.Path, "top level")))
+	}
+	return fmt.Errorf("%w in %s: %s", ErrUnknownFields, name, strings.Join(where, "; "))
+}
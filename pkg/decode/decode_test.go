@@ -0,0 +1,121 @@
+package decode
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/example/protobuf-compat/pkg/wireanalyze"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// timestamp is a google.protobuf.Timestamp of 1 second and 2 nanoseconds.
+const timestamp = "0801" + "1002"
+
+var timestampType = (&timestamppb.Timestamp{}).ProtoReflect().Type()
+
+func TestDecodeEncodings(t *testing.T) {
+	raw, _ := hex.DecodeString(timestamp)
+	for _, tt := range []struct {
+		enc  Encoding
+		text string
+	}{
+		{Binary, string(raw)},
+		{Hex, timestamp},
+		{Base64, base64.StdEncoding.EncodeToString(raw)},
+		{Base64URL, base64.RawURLEncoding.EncodeToString(raw)},
+		{Auto, timestamp},
+		{Auto, base64.StdEncoding.EncodeToString(raw)},
+	} {
+		r, err := Decode([]byte(tt.text), Options{Encoding: tt.enc})
+		if err != nil {
+			t.Errorf("%q %q: %v", tt.enc, tt.text, err)
+			continue
+		}
+		if string(r.Payload) != string(raw) || len(r.Fields) != 2 || r.Message != nil {
+			t.Errorf("%q %q: result = %+v", tt.enc, tt.text, r)
+		}
+	}
+	if _, err := Decode([]byte("not hex"), Options{Encoding: Hex}); err == nil {
+		t.Error("bad hex: no error")
+	}
+}
+
+func TestDecodeType(t *testing.T) {
+	r, err := Decode([]byte(timestamp), Options{Encoding: Hex, Type: timestampType})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(r.Message, &timestamppb.Timestamp{Seconds: 1, Nanos: 2}) {
+		t.Errorf("message = %v", r.Message)
+	}
+	// Only produced on request with a type.
+	if r.Fields != nil || r.JSON != nil {
+		t.Errorf("fields = %v, JSON = %s; want neither", r.Fields, r.JSON)
+	}
+
+	r, err = Decode([]byte(timestamp), Options{Encoding: Hex, Type: timestampType, Fields: true, JSON: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Fields) != 2 || string(r.JSON) != `"1970-01-01T00:00:01.000000002Z"` || r.JSONErr != nil {
+		t.Errorf("fields = %v, JSON = %s, %v", r.Fields, r.JSON, r.JSONErr)
+	}
+}
+
+func TestDecodeStrict(t *testing.T) {
+	// Field 9 is not a Timestamp field.
+	data := timestamp + "4803"
+	r, err := Decode([]byte(data), Options{Encoding: Hex, Type: timestampType})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []UnknownFields{{Path: "", Numbers: []protowire.Number{9}}}
+	if !slices.EqualFunc(r.Unknown, want, func(a, b UnknownFields) bool {
+		return a.Path == b.Path && slices.Equal(a.Numbers, b.Numbers)
+	}) {
+		t.Errorf("unknown = %+v, want %+v", r.Unknown, want)
+	}
+
+	r, err = Decode([]byte(data), Options{Encoding: Hex, Type: timestampType, Strict: true})
+	if !errors.Is(err, ErrUnknownFields) {
+		t.Fatalf("err = %v, want %v", err, ErrUnknownFields)
+	}
+	if r == nil || r.Message == nil {
+		t.Error("no message returned with the error")
+	}
+}
+
+func TestDecodeMalformed(t *testing.T) {
+	for _, opts := range []Options{{Encoding: Hex}, {Encoding: Hex, Type: timestampType}} {
+		r, err := Decode([]byte("0801"+"0a05"), opts)
+		var pe *wireanalyze.ParseError
+		if !errors.As(err, &pe) || !errors.Is(err, wireanalyze.ErrTruncated) || pe.Offset != 2 {
+			t.Errorf("type %v: err = %v, want a *ParseError at offset 2 wrapping %v", opts.Type, err, wireanalyze.ErrTruncated)
+		}
+		if r == nil || len(r.Payload) != 4 {
+			t.Errorf("type %v: result = %+v, want the payload", opts.Type, r)
+		}
+	}
+}
+
+func TestDecodeAnyOfUnknownType(t *testing.T) {
+	anyType := (&anypb.Any{}).ProtoReflect().Type()
+	data, err := proto.Marshal(&anypb.Any{TypeUrl: "type.googleapis.com/foo.Bar", Value: []byte{0x08, 0x01}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The message decodes; only its JSON cannot be rendered.
+	r, err := Decode(data, Options{Type: anyType, JSON: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Message == nil || r.JSON != nil || r.JSONErr == nil {
+		t.Errorf("message = %v, JSON = %s, JSON error = %v; want a message and a JSON error", r.Message, r.JSON, r.JSONErr)
+	}
+}
@@ -0,0 +1,110 @@
+package wireanalyze
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/example/protobuf-compat/internal/wire"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// SkipChildren, returned by a WalkFunc, has Walk skip the fields nested in
+// the field visited, and carry on with the next one.
+var SkipChildren = errors.New("skip children")
+
+// Node is a field as Walk visits it. Its Token gives its number, wire type
+// and byte span: Offset to End, the value from ValueOffset.
+type Node struct {
+	Token
+	// Path lists the field numbers from the top-level message down to the
+	// field, which is last.
+	Path []protowire.Number
+	// Guess is what the field most likely holds: "varint", "fixed32",
+	// "fixed64", "string", "bytes", "message", "group", or the name of a
+	// well-known message type such as "google.protobuf.Timestamp".
+	Guess string
+	// Value is the field's value under Guess: a uint64 for integers, a
+	// string for strings, the bytes for bytes, the JSON of well-known
+	// types, such as "\"2024-05-01T12:00:00Z\"" for a Timestamp, and nil
+	// for other messages and groups.
+	Value any
+}
+
+// PathString returns Path in dotted form, e.g. "5.1".
+func (n Node) PathString() string {
+	return wire.FormatPath(n.Path)
+}
+
+// WalkFunc is called by Walk for every field. It stops the walk by
+// returning an error other than SkipChildren, which Walk then returns.
+type WalkFunc func(n Node) error
+
+// Walk calls fn for every field of data, parents before the fields nested
+// in them, with the default options.
+func Walk(data []byte, fn WalkFunc) error {
+	return Options{}.Walk(data, fn)
+}
+
+// Walk calls fn for every field of data, a bare message, parents before
+// the fields nested in them and in payload order otherwise; for custom
+// redaction or metrics, say. Malformed payloads fail with a *ParseError
+// before fn is called. o.GRPC and o.Redact do not apply.
+func (o Options) Walk(data []byte, fn WalkFunc) error {
+	fields, err := wire.ParseOptions{MaxDepth: o.MaxDepth}.Parse(data)
+	if err != nil {
+		return err
+	}
+	return walk(fields, nil, fn)
+}
+
+func walk(fields []wire.Field, path []protowire.Number, fn WalkFunc) error {
+	for _, f := range fields {
+		n := Node{
+			Token: Token{
+				Number: f.Number, Type: f.Type,
+				Offset: f.Offset, ValueOffset: f.ValueOffset, End: f.End,
+				Value: f.Value, Varint: f.Varint,
+			},
+			Path: append(slices.Clip(path), f.Number),
+		}
+		n.Guess, n.Value = guess(f)
+		err := fn(n)
+		switch {
+		case errors.Is(err, SkipChildren):
+			continue
+		case err != nil:
+			return err
+		}
+		// Text is most likely just text, even if it parses as a message.
+		if len(f.Nested) > 0 && (f.Type == protowire.StartGroupType || !wire.IsText(f.Value)) {
+			if err := walk(f.Nested, n.Path, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// guess returns what f most likely holds, and its value as that.
+func guess(f wire.Field) (string, any) {
+	switch f.Type {
+	case protowire.VarintType:
+		return "varint", f.Varint
+	case protowire.Fixed32Type:
+		return "fixed32", f.Varint
+	case protowire.Fixed64Type:
+		return "fixed64", f.Varint
+	case protowire.StartGroupType:
+		return "group", nil
+	}
+	if wk, ok := wire.DetectWellKnown(f); ok {
+		return wk.Type, wk.Text
+	}
+	switch {
+	case wire.IsText(f.Value):
+		return "string", string(f.Value)
+	case len(f.Nested) > 0:
+		return "message", nil
+	}
+	return "bytes", f.Value
+}
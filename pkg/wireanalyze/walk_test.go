@@ -0,0 +1,88 @@
+package wireanalyze
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestWalk(t *testing.T) {
+	var got []string
+	err := Walk(mustHex(t, order), func(n Node) error {
+		got = append(got, fmt.Sprintf("%s %s %v [%d,%d)", n.PathString(), n.Guess, n.Value, n.Offset, n.End))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"1 string hello [0,7)",
+		"2 varint 150 [7,10)",
+		`3 google.protobuf.Int64Value "1" [10,14)`,
+		"3.1 varint 1 [12,14)",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("visited %q, want %q", got, want)
+	}
+}
+
+func TestWalkSkipChildren(t *testing.T) {
+	var paths []string
+	err := Walk(mustHex(t, order), func(n Node) error {
+		paths = append(paths, n.PathString())
+		if n.Number == 3 {
+			return SkipChildren
+		}
+		return nil
+	})
+	if err != nil || !slices.Equal(paths, []string{"1", "2", "3"}) {
+		t.Errorf("visited %q, %v; want 1, 2 and 3", paths, err)
+	}
+}
+
+func TestWalkStop(t *testing.T) {
+	stop := errors.New("stop")
+	visited := 0
+	err := Walk(mustHex(t, order), func(n Node) error {
+		visited++
+		if n.Number == 2 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || visited != 2 {
+		t.Errorf("err = %v after %d fields, want stop after 2", err, visited)
+	}
+}
+
+func TestWalkMalformed(t *testing.T) {
+	called := false
+	err := Walk(mustHex(t, "0801"+"0f"), func(Node) error {
+		called = true
+		return nil
+	})
+	var pe *ParseError
+	if !errors.As(err, &pe) || called {
+		t.Errorf("err = %v, fn called: %t; want a *ParseError before any call", err, called)
+	}
+}
+
+func TestWalkPathsNotShared(t *testing.T) {
+	// {1: {1: 1, 2: 2}}: siblings must not overwrite each other's paths.
+	var nodes []Node
+	err := Walk(mustHex(t, "0a04"+"0801"+"1002"), func(n Node) error {
+		nodes = append(nodes, n)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, n := range nodes {
+		paths = append(paths, n.PathString())
+	}
+	if !slices.Equal(paths, []string{"1", "1.1", "1.2"}) {
+		t.Errorf("paths = %q", paths)
+	}
+}
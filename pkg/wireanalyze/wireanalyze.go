@@ -0,0 +1,187 @@
+// Package wireanalyze analyzes protobuf wire-format payloads without a
+// schema, for Go programs to embed what protocompat analyze does rather
+// than run it and scrape its output.
+//
+// Analyze splits a payload into fields, nested messages included, and
+// annotates each with what it most likely holds: text, a well-known type
+// such as google.protobuf.Timestamp, or a time. Payloads framed for gRPC
+// are split into their messages first. The results marshal to the JSON of
+// protocompat analyze -format json.
+package wireanalyze
+
+import (
+	"fmt"
+
+	"github.com/example/protobuf-compat/internal/wire"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// DefaultMaxDepth is the default limit on how deeply messages and groups
+// may nest.
+const DefaultMaxDepth = wire.DefaultMaxDepth
+
+// ParseError is a malformed payload: its Offset locates the field, or
+// gRPC frame header, that could not be parsed, and its Err says why,
+// wrapping one of the Err values below if any applies. Other failures are
+// not of malformed input.
+type ParseError = wire.ParseError
+
+// Reasons for payloads to be malformed, for errors.Is.
+var (
+	// ErrTruncated reports input ending inside a field, length prefix or
+	// gRPC frame, or a length running past the end of its message.
+	ErrTruncated = wire.ErrTruncated
+	// ErrInvalidWireType reports a tag with wire type 6 or 7, or an end
+	// group without its start.
+	ErrInvalidWireType = wire.ErrInvalidWireType
+	// ErrInvalidFieldNumber reports a tag with field number 0, or one
+	// above the largest valid number.
+	ErrInvalidFieldNumber = wire.ErrInvalidFieldNumber
+	// ErrVarintOverflow reports a varint longer than ten bytes.
+	ErrVarintOverflow = wire.ErrVarintOverflow
+	// ErrDepthExceeded reports messages or groups nested deeper than
+	// Options.MaxDepth.
+	ErrDepthExceeded = wire.ErrDepthExceeded
+)
+
+// Scanner reads the fields of a payload one at a time, giving the tag,
+// offsets and raw value of each as a Token, for consumers to build their
+// own processing on without the tree Analyze builds. Next reads a field,
+// Token returns it, Nested scans its value as a message, and Err reports
+// the *ParseError that stopped the scanner, if any.
+type Scanner = wire.Scanner
+
+// Token is a field as a Scanner reads it. Value holds its raw value: the
+// varint or fixed-width encoding, the contents of a length-delimited
+// field, or the body of a group; Varint the integer of the first three.
+type Token = wire.Token
+
+// NewScanner returns a scanner of the fields of data.
+func NewScanner(data []byte) *Scanner {
+	return wire.NewScanner(data)
+}
+
+// Options configures Analyze. The zero value gives the defaults.
+type Options struct {
+	// MaxDepth bounds the nesting of messages and groups (DefaultMaxDepth
+	// if <= 0). Analysis fails with ErrDepthExceeded beyond it.
+	MaxDepth int
+	// GRPC has payloads split into gRPC frames even when they do not look
+	// framed, failing if they are not.
+	GRPC bool
+	// Redact replaces the contents of strings and bytes with placeholders
+	// of the same length, for results to share.
+	Redact bool
+}
+
+// Result is the analysis of a payload.
+type Result struct {
+	// Size is the length of the payload in bytes.
+	Size int `json:"size"`
+	// Fields are those of a bare message; Frames those of the messages of
+	// a gRPC-framed payload. Only one is set.
+	Fields []Field `json:"fields,omitempty"`
+	Frames []Frame `json:"frames,omitempty"`
+}
+
+// Frame is a message of a gRPC-framed payload.
+type Frame struct {
+	// Offset is that of the frame header in the payload.
+	Offset int `json:"offset"`
+	// Compressed frames were gzip-compressed, and are analyzed
+	// decompressed.
+	Compressed bool `json:"compressed,omitempty"`
+	// Size is the length of the message, decompressed.
+	Size   int     `json:"size"`
+	Fields []Field `json:"fields"`
+}
+
+// Field is a field occurrence in a payload.
+type Field struct {
+	Number protowire.Number `json:"number"`
+	// Offset is that of the field's tag in the payload, or in the message
+	// of its gRPC frame.
+	Offset int `json:"offset"`
+	// Type is the wire type: varint, i64, len, sgroup or i32.
+	Type string `json:"type"`
+	// Value is the integer of varint and fixed-width fields.
+	Value *uint64 `json:"value,omitempty"`
+	// Text and Hex hold length-delimited values, as text if they look
+	// like it.
+	Text *string `json:"text,omitempty"`
+	Hex  string  `json:"hex,omitempty"`
+	// WellKnown and Time give the likely meaning of the value:
+	// WellKnown the type and canonical rendering of a well-known message,
+	// such as "google.protobuf.Timestamp 2024-05-01T12:00:00Z", and Time
+	// the time an integer seems to be.
+	WellKnown string `json:"well_known,omitempty"`
+	Time      string `json:"time,omitempty"`
+	// Fields are those of a group, or of a length-delimited value that
+	// parses as a message and does not look like text.
+	Fields []Field `json:"fields,omitempty"`
+}
+
+// Analyze analyzes data with the default options.
+func Analyze(data []byte) (*Result, error) {
+	return Options{}.Analyze(data)
+}
+
+// Analyze analyzes data as a bare message, or as gRPC frames if it looks
+// framed or o.GRPC is set. On malformed input it returns what could be
+// analyzed together with the error.
+func (o Options) Analyze(data []byte) (*Result, error) {
+	r := &Result{Size: len(data)}
+	if !o.GRPC && !wire.IsGRPCFramed(data) {
+		fields, err := o.Fields(data)
+		r.Fields = fields
+		return r, err
+	}
+	frames, err := wire.SplitGRPC(data)
+	if err != nil {
+		return r, err
+	}
+	for _, fr := range frames {
+		fields, err := o.Fields(fr.Data)
+		r.Frames = append(r.Frames, Frame{Offset: fr.Offset, Compressed: fr.Compressed, Size: len(fr.Data), Fields: fields})
+		if err != nil {
+			return r, fmt.Errorf("gRPC frame at byte %d: %w", fr.Offset, err)
+		}
+	}
+	return r, nil
+}
+
+// Fields analyzes data as a bare message, never as gRPC frames. On
+// malformed input it returns the fields parsed before the error with it.
+func (o Options) Fields(data []byte) ([]Field, error) {
+	if o.Redact {
+		data = wire.Redact(data)
+	}
+	fields, err := wire.ParseOptions{MaxDepth: o.MaxDepth}.Parse(data)
+	return convert(fields), err
+}
+
+func convert(fields []wire.Field) []Field {
+	out := make([]Field, 0, len(fields))
+	for _, f := range fields {
+		j := Field{Number: f.Number, Offset: f.Offset, Type: wire.TypeName(f.Type)}
+		switch {
+		case f.Type == protowire.VarintType || f.Type == protowire.Fixed32Type || f.Type == protowire.Fixed64Type:
+			j.Value = &f.Varint
+		case f.Type == protowire.BytesType && wire.IsText(f.Value):
+			text := string(f.Value)
+			j.Text = &text
+		case f.Type == protowire.BytesType:
+			j.Hex = fmt.Sprintf("%X", f.Value)
+		}
+		if wk, ok := wire.DetectWellKnown(f); ok {
+			j.WellKnown = wk.Type + " " + wk.Text
+		} else if guess, ok := wire.FieldTime(f); ok {
+			j.Time = guess.Format()
+		}
+		if len(f.Nested) > 0 && (f.Type == protowire.StartGroupType || !wire.IsText(f.Value)) {
+			j.Fields = convert(f.Nested)
+		}
+		out = append(out, j)
+	}
+	return out
+}
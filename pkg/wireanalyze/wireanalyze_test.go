@@ -0,0 +1,120 @@
+package wireanalyze
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// order is a message with a string (1), a varint (2) and a nested message
+// (3) holding a varint: {1: "hello", 2: 150, 3: {1: 1}}.
+const order = "0a0568656c6c6f" + "109601" + "1a020801"
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestAnalyze(t *testing.T) {
+	r, err := Analyze(mustHex(t, order))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = `{"size":14,"fields":[` +
+		`{"number":1,"offset":0,"type":"len","text":"hello"},` +
+		`{"number":2,"offset":7,"type":"varint","value":150},` +
+		`{"number":3,"offset":10,"type":"len","hex":"0801","well_known":"google.protobuf.Int64Value \"1\"","fields":[{"number":1,"offset":12,"type":"varint","value":1}]}]}`
+	if string(got) != want {
+		t.Errorf("Analyze = %s\nwant %s", got, want)
+	}
+}
+
+func TestAnalyzeGRPC(t *testing.T) {
+	msg := mustHex(t, order)
+	framed := append(binary.BigEndian.AppendUint32([]byte{0}, uint32(len(msg))), msg...)
+	framed = append(framed, framed...)
+	r, err := Analyze(framed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Fields != nil || len(r.Frames) != 2 || r.Frames[1].Offset != 5+len(msg) || len(r.Frames[1].Fields) != 3 {
+		t.Errorf("Analyze = %+v, want two frames of three fields", r)
+	}
+	// Forced framing fails on a bare message.
+	if _, err := (Options{GRPC: true}).Analyze(msg); err == nil {
+		t.Error("GRPC on a bare message: no error")
+	}
+}
+
+func TestFieldsRedact(t *testing.T) {
+	fields, err := Options{Redact: true}.Fields(mustHex(t, order))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fields[0].Text == nil || *fields[0].Text == "hello" || len(*fields[0].Text) != len("hello") {
+		t.Errorf("redacted text = %v", fields[0].Text)
+	}
+	if *fields[1].Value != 150 {
+		t.Errorf("varint = %d, want it kept", *fields[1].Value)
+	}
+}
+
+func TestFieldsMalformed(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		hex      string
+		maxDepth int
+		err      error
+	}{
+		{"truncated", "0a05" + "6869", 0, ErrTruncated},
+		{"wire type 7", "0801" + "0f", 0, ErrInvalidWireType},
+		{"field number 0", "0000", 0, ErrInvalidFieldNumber},
+		{"varint overflow", "08ffffffffffffffffffff01", 0, ErrVarintOverflow},
+		{"too deep", "0b0b0c0c", 1, ErrDepthExceeded},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Options{MaxDepth: tt.maxDepth}.Fields(mustHex(t, tt.hex))
+			var pe *ParseError
+			if !errors.As(err, &pe) || !errors.Is(err, tt.err) {
+				t.Errorf("err = %v, want a *ParseError wrapping %v", err, tt.err)
+			}
+		})
+	}
+	// The fields before the error are returned with it.
+	fields, err := Analyze(mustHex(t, "0801"+"0f"))
+	if err == nil || len(fields.Fields) != 1 {
+		t.Errorf("Analyze = %+v, %v; want one field and an error", fields, err)
+	}
+}
+
+func TestScanner(t *testing.T) {
+	s := NewScanner(mustHex(t, order))
+	var numbers []int
+	for s.Next() {
+		tok := s.Token()
+		numbers = append(numbers, int(tok.Number))
+		if tok.Number == 3 {
+			nested := s.Nested()
+			if !nested.Next() || nested.Token().Varint != 1 || nested.Token().Offset != 12 {
+				t.Errorf("nested token = %+v", nested.Token())
+			}
+		}
+	}
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(numbers, []int{1, 2, 3}) {
+		t.Errorf("numbers = %v", numbers)
+	}
+}